@@ -32,12 +32,15 @@ import (
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
 	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/azurequeuestorage"
+	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/jetstream"
 	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/kafka"
 	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/nats"
+	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/rabbitmq"
+	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/redis"
 )
 
 func Start(logger *zap.Logger, routerUrl string) error {
-	fissionClient, _, _, _, err := crd.MakeFissionClient()
+	fissionClient, kubeClient, _, _, err := crd.MakeFissionClient()
 
 	if err != nil {
 		return errors.Wrap(err, "failed to get fission or kubernetes client")
@@ -66,9 +69,10 @@ func Start(logger *zap.Logger, routerUrl string) error {
 		logger,
 		mqType,
 		messageQueue.Config{
-			MQType:  (string)(mqType),
-			Url:     mqUrl,
-			Secrets: secrets,
+			MQType:     (string)(mqType),
+			Url:        mqUrl,
+			Secrets:    secrets,
+			KubeClient: kubeClient,
 		},
 		routerUrl,
 	)
@@ -30,6 +30,10 @@ const (
 	ChecksumTypeSHA256 ChecksumType = "sha256"
 )
 
+const (
+	SignatureTypeEd25519 SignatureType = "ed25519"
+)
+
 const (
 	// ArchiveTypeLiteral means the package contents are specified in the Literal field of
 	// resource itself.
@@ -37,6 +41,10 @@ const (
 
 	// ArchiveTypeUrl means the package contents are at the specified URL.
 	ArchiveTypeUrl ArchiveType = "url"
+
+	// ArchiveTypeOCI means the package contents are stored as a single layer
+	// artifact in an OCI registry, referenced by an oci://registry/repo:tag@digest URL.
+	ArchiveTypeOCI ArchiveType = "oci"
 )
 
 const (
@@ -72,9 +80,74 @@ const (
 )
 
 const (
-	MessageQueueTypeNats  = "nats-streaming"
-	MessageQueueTypeASQ   = "azure-storage-queue"
-	MessageQueueTypeKafka = "kafka"
+	MessageQueueTypeNats         = "nats-streaming"
+	MessageQueueTypeJetStream    = "nats-jetstream"
+	MessageQueueTypeASQ          = "azure-storage-queue"
+	MessageQueueTypeKafka        = "kafka"
+	MessageQueueTypeRedisStreams = "redis-streams"
+	MessageQueueTypeRabbitMQ     = "rabbitmq"
+)
+
+const (
+	// MessageOrderingPartition processes the messages of a partition
+	// one at a time, in order.
+	MessageOrderingPartition MessageOrdering = "partition"
+	// MessageOrderingKey processes the messages of a partition
+	// concurrently, except those with the same key.
+	MessageOrderingKey MessageOrdering = "key"
+)
+
+const (
+	// BatchFormatJSON delivers a batch as a JSON array of messages.
+	BatchFormatJSON BatchFormat = "json"
+	// BatchFormatMultipart delivers a batch as a multipart/mixed body.
+	BatchFormatMultipart BatchFormat = "multipart"
+)
+
+const (
+	// MessageSchemaTypeJSON checks JSON messages against a JSON Schema.
+	MessageSchemaTypeJSON MessageSchemaType = "json"
+	// MessageSchemaTypeAvro checks Avro binary encoded messages.
+	MessageSchemaTypeAvro MessageSchemaType = "avro"
+	// MessageSchemaTypeProtobuf checks Protocol Buffers binary encoded
+	// messages.
+	MessageSchemaTypeProtobuf MessageSchemaType = "protobuf"
+)
+
+const (
+	// CloudEventsModeBinary puts the event attributes in ce- headers,
+	// the body is the event data.
+	CloudEventsModeBinary CloudEventsMode = "binary"
+	// CloudEventsModeStructured sends the event as a JSON document,
+	// with the application/cloudevents+json content type.
+	CloudEventsModeStructured CloudEventsMode = "structured"
+)
+
+const (
+	// ConcurrencyPolicyAllow invokes the function on every tick, even if
+	// the previous invocation is still running.
+	ConcurrencyPolicyAllow ConcurrencyPolicy = "Allow"
+	// ConcurrencyPolicyForbid skips the tick if the previous invocation
+	// is still running.
+	ConcurrencyPolicyForbid ConcurrencyPolicy = "Forbid"
+	// ConcurrencyPolicyReplace cancels the running invocation and
+	// replaces it with the new one.
+	ConcurrencyPolicyReplace ConcurrencyPolicy = "Replace"
+)
+
+// TimeTriggerRunHistoryLimit is the number of runs kept in the status of
+// a time trigger.
+const TimeTriggerRunHistoryLimit = 10
+
+// DefaultTTLSecondsAfterFinished is how long one-shot time triggers are
+// kept after their invocation by default.
+const DefaultTTLSecondsAfterFinished = 3600
+
+// Event types of kubewatch triggers, the types of the watch events.
+const (
+	KubeWatchEventAdded    = "ADDED"
+	KubeWatchEventModified = "MODIFIED"
+	KubeWatchEventDeleted  = "DELETED"
 )
 
 const (
@@ -133,6 +133,11 @@ type (
 		metav1.TypeMeta   `json:",inline"`
 		metav1.ObjectMeta `json:"metadata"`
 		Spec              KubernetesWatchTriggerSpec `json:"spec"`
+
+		// Status is the progress of the kubewatcher through the events of
+		// the watched resources.
+		// +optional
+		Status KubernetesWatchTriggerStatus `json:"status,omitempty"`
 	}
 
 	// KubernetesWatchTriggerList is a list of KubernetesWatchTriggers
@@ -154,6 +159,10 @@ type (
 		metav1.ObjectMeta `json:"metadata"`
 
 		Spec TimeTriggerSpec `json:"spec"`
+
+		// Status is the record of the recent runs of the trigger.
+		// +optional
+		Status TimeTriggerStatus `json:"status,omitempty"`
 	}
 
 	// TimeTriggerList is a list of TimeTriggers.
@@ -170,11 +179,16 @@ type (
 	// +genclient
 	// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 	// +kubebuilder:object:root=true
+	// +kubebuilder:subresource:status
 	MessageQueueTrigger struct {
 		metav1.TypeMeta   `json:",inline"`
 		metav1.ObjectMeta `json:"metadata"`
 
 		Spec MessageQueueTriggerSpec `json:"spec"`
+
+		// Status is the state of the subscription of the trigger.
+		// +optional
+		Status MessageQueueTriggerStatus `json:"status,omitempty"`
 	}
 
 	// MessageQueueTriggerList is a list of MessageQueueTriggers.
@@ -224,7 +238,7 @@ type (
 		Sum  string       `json:"sum,omitempty"`
 	}
 
-	// ArchiveType is either literal or URL, indicating whether
+	// ArchiveType is literal, URL or OCI, indicating whether
 	// the package is specified in the Archive struct or
 	// externally.
 	ArchiveType string
@@ -232,10 +246,11 @@ type (
 	// Archive contains or references a collection of source or
 	// binary files.
 	Archive struct {
-		// Type defines how the package is specified: literal or URL.
+		// Type defines how the package is specified: literal, URL or OCI.
 		// Available value:
 		//  - literal
 		//  - url
+		//  - oci
 		// +optional
 		Type ArchiveType `json:"type,omitempty"`
 
@@ -244,14 +259,45 @@ type (
 		// +optional
 		Literal []byte `json:"literal,omitempty"`
 
-		// URL references a package.
+		// URL references a package. For the oci type it's a
+		// reference of the form oci://registry/repo:tag@digest,
+		// where the manifest digest takes the place of Checksum.
 		// +optional
 		URL string `json:"url,omitempty"`
 
 		// Checksum ensures the integrity of packages
-		// referenced by URL. Ignored for literals.
+		// referenced by URL. Ignored for literals and OCI
+		// references, which are pinned by digest.
 		// +optional
 		Checksum Checksum `json:"checksum,omitempty"`
+
+		// Signature is a detached signature of the archive contents.
+		// It's required if the environment of the package sets
+		// TrustedKeysSecret, and fetcher refuses to load the archive
+		// when the signature is missing or doesn't match.
+		// +optional
+		Signature ArchiveSignature `json:"signature,omitempty"`
+	}
+
+	// SignatureType specifies the algorithm, such as ed25519,
+	// used for an archive signature.
+	SignatureType string
+
+	// ArchiveSignature is a detached signature of an archive. The
+	// signed message is the raw SHA256 digest of the archive contents,
+	// so that the signature covers literal and URL archives alike.
+	// "ed25519" is the only currently supported type. Sig is base64
+	// encoded.
+	ArchiveSignature struct {
+		Type SignatureType `json:"type,omitempty"`
+
+		// KeyID is the name of the trusted key, that is the key
+		// of the public key in the trusted keys secret, used to
+		// verify the signature. If empty, all trusted keys are tried.
+		// +optional
+		KeyID string `json:"keyid,omitempty"`
+
+		Sig string `json:"sig,omitempty"`
 	}
 
 	// EnvironmentReference is a reference to a environment.
@@ -294,6 +340,18 @@ type (
 		// +optional
 		BuildCommand string `json:"buildcmd,omitempty"`
 
+		// (Optional) BuildTimeout is the maximum duration of the build command
+		// in seconds. The build command is killed and the package marked as
+		// failed once it's exceeded. Builds don't time out if it's 0.
+		// +optional
+		BuildTimeout int `json:"buildtimeout,omitempty"`
+
+		// (Optional) BuildPriority orders the builds waiting for the builder of
+		// the environment. Builds with a higher priority start first, builds
+		// with the same priority in the order they were requested.
+		// +optional
+		BuildPriority int `json:"buildpriority,omitempty"`
+
 		// In the future, we can have a debug build here too
 	}
 
@@ -478,6 +536,14 @@ type (
 	// FunctionReferenceType refers to type of Function
 	FunctionReferenceType string
 
+	// CloudEventsMode is the HTTP content mode of the CloudEvents v1.0
+	// sent by triggers to functions.
+	CloudEventsMode string
+
+	// ConcurrencyPolicy is how a time trigger handles a tick while the
+	// previous invocation is still running.
+	ConcurrencyPolicy string
+
 	// FunctionReference refers to a function
 	FunctionReference struct {
 		// Type indicates whether this function reference is by name or selector. For now,
@@ -572,6 +638,45 @@ type (
 
 		// PodSpec will store the spec of the pod that will be applied to the pod created for the builder
 		PodSpec *apiv1.PodSpec `json:"podspec,omitempty"`
+
+		// (Optional) DependencyCache enables a dependency cache volume on
+		// the builder pod. Builds are given a cache directory keyed on the
+		// hash of the lockfiles in the source package, so that unchanged
+		// dependencies are not reinstalled on every build.
+		// +optional
+		DependencyCache *DependencyCache `json:"dependencycache,omitempty"`
+
+		// (Optional) Concurrency is the number of package builds running at
+		// the same time on the builder of this environment, further builds
+		// wait in a queue. Defaults to the build concurrency of the builder
+		// manager.
+		// +optional
+		Concurrency int `json:"concurrency,omitempty"`
+
+		// (Optional) SigningKeySecret is the name of a Secret, in the same
+		// namespace as the environment, holding the PEM encoded ed25519
+		// private key the builder manager signs built deployment archives
+		// with. The Secret has a single entry, whose data key is used as
+		// the key ID. Required if the environment sets TrustedKeysSecret,
+		// which then has to trust the key under the same ID.
+		// +optional
+		SigningKeySecret string `json:"signingkeysecret,omitempty"`
+	}
+
+	// DependencyCache configures the dependency cache volume of a builder.
+	DependencyCache struct {
+		// (Optional) ClaimName is the name of a PersistentVolumeClaim in the
+		// builder namespace to keep the cache on, so that it survives builder
+		// pod restarts and environment updates. An emptyDir volume is used
+		// if it's empty.
+		// +optional
+		ClaimName string `json:"claimname,omitempty"`
+
+		// (Optional) MaxEntries is the number of lockfile hashes kept in the
+		// cache. The least recently used entries are removed first.
+		// Defaults to 5.
+		// +optional
+		MaxEntries int `json:"maxentries,omitempty"`
 	}
 
 	// EnvironmentSpec contains with builder, runtime and some other related environment settings.
@@ -637,6 +742,15 @@ type (
 		// private registry.
 		// +optional
 		ImagePullSecret string `json:"imagepullsecret"`
+
+		// TrustedKeysSecret is the name of a Secret, in the same namespace
+		// as the environment, holding the PEM encoded ed25519 public keys
+		// trusted to sign packages of this environment. The data key of
+		// each entry is used as the key ID.
+		// If set, fetcher refuses to load any source or deployment archive
+		// that is unsigned or not signed by one of the trusted keys.
+		// +optional
+		TrustedKeysSecret string `json:"trustedkeyssecret,omitempty"`
 	}
 	// AllowedFunctionsPerContainer defaults to 'single'. Related to Fission Workflows
 	AllowedFunctionsPerContainer string
@@ -720,16 +834,66 @@ type (
 	KubernetesWatchTriggerSpec struct {
 		Namespace string `json:"namespace"`
 
-		// Type of resource to watch (Pod, Service, etc.)
+		// Type of resource to watch, a resource or a kind optionally
+		// qualified by version and group, like kubectl takes them: Pod,
+		// services, deployments.apps, functions.v1.fission.io, etc.
 		Type string `json:"type"`
 
-		// Resource labels
+		// Resource labels, only the resources with all of them are watched.
 		// +optional
 		LabelSelector map[string]string `json:"labelselector"`
 
+		// (Optional) FieldSelector restricts the watched resources by their
+		// fields, like "status.phase=Running,spec.nodeName!=node-1".
+		// +optional
+		FieldSelector string `json:"fieldselector,omitempty"`
+
 		// The reference to a function for kubewatcher to invoke with
 		// when receiving events.
 		FunctionReference FunctionReference `json:"functionref"`
+
+		// (Optional) CloudEvents sends events as CloudEvents v1.0, in the
+		// "binary" or "structured" HTTP content mode, along with the
+		// X-Kubernetes headers.
+		// +optional
+		CloudEvents CloudEventsMode `json:"cloudEvents,omitempty"`
+
+		// (Optional) EventTypes are the types of the events to invoke the
+		// function with: ADDED, MODIFIED or DELETED. All of them if empty.
+		// +optional
+		EventTypes []string `json:"eventTypes,omitempty"`
+
+		// (Optional) IgnoreStatusChanges skips the MODIFIED events changing
+		// only the status or the metadata maintained by Kubernetes, like the
+		// resource version, of the object.
+		// +optional
+		IgnoreStatusChanges bool `json:"ignoreStatusChanges,omitempty"`
+
+		// (Optional) DebounceSeconds coalesces the events of each object over
+		// a window: the function is invoked once with the net event at its
+		// end, e.g. a single ADDED event for an object added and modified.
+		// +optional
+		DebounceSeconds int32 `json:"debounceSeconds,omitempty"`
+
+		// (Optional) IncludeOldObject sends MODIFIED events as
+		// {"object": new, "oldObject": old} rather than the new object only.
+		// +optional
+		IncludeOldObject bool `json:"includeOldObject,omitempty"`
+	}
+
+	// KubernetesWatchTriggerStatus is the progress of the kubewatcher
+	// through the events of the resources of a watch, which it resumes from
+	// after restarts.
+	KubernetesWatchTriggerStatus struct {
+		// (Optional) LastResourceVersion is the resource version of the
+		// last processed event.
+		// +optional
+		LastResourceVersion string `json:"lastResourceVersion,omitempty"`
+
+		// (Optional) LastEventTime is the time the last event was
+		// processed.
+		// +optional
+		LastEventTime *metav1.Time `json:"lastEventTime,omitempty"`
 	}
 
 	// MessageQueueType refers to Type of message queue
@@ -762,6 +926,40 @@ type (
 		// +optional
 		MaxRetries int `json:"maxRetries"`
 
+		// (Optional) RetryPolicy controls the delay between retries and
+		// which failures are retried. Failures are retried immediately if
+		// not set.
+		// +optional
+		RetryPolicy *MessageQueueRetryPolicy `json:"retryPolicy,omitempty"`
+
+		// (Optional) Concurrency controls how the messages of a partition
+		// are processed, it's only supported by Kafka. Partitions are
+		// processed concurrently, and the messages of a partition one at
+		// a time in order if not set.
+		// +optional
+		Concurrency *MessageQueueConcurrency `json:"concurrency,omitempty"`
+
+		// (Optional) Batch delivers messages to the function in batches,
+		// it's supported by Kafka, NATS streaming and Azure storage queue.
+		// Messages are delivered one at a time if not set.
+		// +optional
+		Batch *MessageQueueBatch `json:"batch,omitempty"`
+
+		// (Optional) Schema checks messages against a schema before
+		// invoking the function, and optionally decodes them to JSON.
+		// Messages that don't match the schema are handled like failed
+		// invocations, without invoking the function. It's not supported
+		// by keda triggers.
+		// +optional
+		Schema *MessageSchema `json:"schema,omitempty"`
+
+		// (Optional) CloudEvents delivers messages as CloudEvents v1.0, in
+		// the "binary" or "structured" HTTP content mode, along with the
+		// X-Fission-MQTrigger headers. The messages of a batch are
+		// CloudEvents each. It's not supported by keda triggers.
+		// +optional
+		CloudEvents CloudEventsMode `json:"cloudEvents,omitempty"`
+
 		// Content type of payload
 		// +optional
 		ContentType string `json:"contentType"`
@@ -805,14 +1003,296 @@ type (
 		PodSpec *apiv1.PodSpec `json:"podspec,omitempty"`
 	}
 
+	// MessageOrdering is the order kept while processing the messages
+	// of a partition.
+	MessageOrdering string
+
+	// MessageQueueConcurrency controls how many messages of a partition
+	// of a message queue trigger are processed at once.
+	MessageQueueConcurrency struct {
+		// (Optional) Ordering is either "partition", the messages of a
+		// partition are processed one at a time in order, or "key", up to
+		// KeyWorkers messages of a partition are processed at once and the
+		// messages with the same key in order. Defaults to "partition".
+		// +optional
+		Ordering MessageOrdering `json:"ordering,omitempty"`
+
+		// (Optional) KeyWorkers is the number of messages of a partition
+		// processed at once with "key" ordering. Defaults to 10.
+		// +optional
+		KeyWorkers int `json:"keyWorkers,omitempty"`
+	}
+
+	// BatchFormat is the format of the request body delivering a batch
+	// of messages.
+	BatchFormat string
+
+	// MessageQueueBatch controls how messages are batched. The function
+	// can report that some messages of a batch failed by responding with
+	// 207 Multi-Status and a JSON body like {"failed": [0, 2]}, listing
+	// the indexes of the failed messages in the batch. Only those are
+	// retried then.
+	MessageQueueBatch struct {
+		// MaxSize is the maximum number of messages of a batch.
+		MaxSize int `json:"maxSize"`
+
+		// (Optional) MaxWait is the time in milliseconds to wait for more
+		// messages before delivering a batch that isn't full. The messages
+		// available at once are delivered if not set.
+		// +optional
+		MaxWait int `json:"maxWait,omitempty"`
+
+		// (Optional) Format is either "json", a JSON array of the messages
+		// and their headers, or "multipart", a multipart/mixed body with a
+		// part per message. Defaults to "json".
+		// +optional
+		Format BatchFormat `json:"format,omitempty"`
+	}
+
+	// MessageSchemaType is the type of the schema of the messages of a
+	// message queue trigger.
+	MessageSchemaType string
+
+	// MessageSchema is the schema of the messages of a message queue
+	// trigger, read from a ConfigMap or a schema registry. Schemas are
+	// read when the trigger is subscribed, updating the ConfigMap takes
+	// effect once the trigger is updated.
+	MessageSchema struct {
+		// Type is either "json", messages are JSON documents checked
+		// against a JSON Schema, "avro", messages are Avro binary encoded,
+		// or "protobuf", messages are Protocol Buffers binary encoded.
+		Type MessageSchemaType `json:"type"`
+
+		// (Optional) ConfigMap is the ConfigMap key holding the schema,
+		// in the namespace of the trigger. Protobuf schemas are a
+		// FileDescriptorSet, as written by protoc --include_imports
+		// --descriptor_set_out. Either ConfigMap or Registry must be set.
+		// +optional
+		ConfigMap *MessageSchemaConfigMap `json:"configMap,omitempty"`
+
+		// (Optional) Registry is a Confluent compatible schema registry
+		// holding the schema, it's not supported for protobuf schemas.
+		// +optional
+		Registry *MessageSchemaRegistry `json:"registry,omitempty"`
+
+		// (Optional) MessageType is the full name of the protobuf message
+		// type of the messages, required for protobuf schemas.
+		// +optional
+		MessageType string `json:"messageType,omitempty"`
+
+		// (Optional) Decode converts avro and protobuf messages to JSON
+		// before delivering them to the function, in the JSON encoding
+		// of the Avro specification or the protobuf JSON mapping.
+		// +optional
+		Decode bool `json:"decode,omitempty"`
+	}
+
+	// MessageSchemaConfigMap is the ConfigMap key holding a schema.
+	MessageSchemaConfigMap struct {
+		// Name is the name of the ConfigMap.
+		Name string `json:"name"`
+
+		// Key is the key of the schema in the data or binary data of the
+		// ConfigMap.
+		Key string `json:"key"`
+	}
+
+	// MessageSchemaRegistry is a Confluent compatible schema registry.
+	// Messages in the registry wire format, starting with a zero byte
+	// and the schema ID, are checked against the schema with that ID.
+	// Other messages are checked against the latest schema of Subject.
+	MessageSchemaRegistry struct {
+		// URL is the URL of the registry, credentials can be part of it.
+		URL string `json:"url"`
+
+		// (Optional) Subject is the subject whose latest schema checks
+		// messages not in the registry wire format. Such messages are
+		// invalid if not set.
+		// +optional
+		Subject string `json:"subject,omitempty"`
+	}
+
+	// MessageQueueRetryPolicy controls how failed function invocations
+	// of a message queue trigger are retried, up to MaxRetries times.
+	MessageQueueRetryPolicy struct {
+		// (Optional) InitialBackoff is the delay in milliseconds before
+		// the first retry, it's doubled for each following retry.
+		// +optional
+		InitialBackoff int `json:"initialBackoff,omitempty"`
+
+		// (Optional) MaxBackoff caps the delay in milliseconds between
+		// two retries.
+		// +optional
+		MaxBackoff int `json:"maxBackoff,omitempty"`
+
+		// (Optional) RetryableStatusCodes are the function response status
+		// codes that are retried. Any failure is retried if not set, and
+		// requests that didn't get a response are always retried.
+		// +optional
+		RetryableStatusCodes []int `json:"retryableStatusCodes,omitempty"`
+
+		// (Optional) MaxAge is the time in seconds since the first attempt
+		// after which a message isn't retried anymore.
+		// +optional
+		MaxAge int `json:"maxAge,omitempty"`
+	}
+
+	// MessageQueueTriggerStatus is the state of the subscription of a
+	// message queue trigger, reported by the trigger manager or, for keda
+	// triggers, by the scaler manager.
+	MessageQueueTriggerStatus struct {
+		// (Optional) Subscribed is true once the trigger is subscribed to
+		// its topic, or once its keda scaled object is created.
+		// +optional
+		Subscribed bool `json:"subscribed,omitempty"`
+
+		// (Optional) LastMessageAt is the time the trigger last handled a
+		// message.
+		// +optional
+		LastMessageAt *metav1.Time `json:"lastMessageAt,omitempty"`
+
+		// (Optional) ConsumerLag is the number of messages of the topic
+		// not consumed by the trigger yet, for the message queues that
+		// report it.
+		// +optional
+		ConsumerLag *int64 `json:"consumerLag,omitempty"`
+
+		// (Optional) LastError is the last error subscribing the trigger
+		// or handling a message.
+		// +optional
+		LastError string `json:"lastError,omitempty"`
+
+		// (Optional) LastErrorAt is the time of LastError.
+		// +optional
+		LastErrorAt *metav1.Time `json:"lastErrorAt,omitempty"`
+	}
+
 	// TimeTriggerSpec invokes the specific function at a time or
 	// times specified by a cron string.
 	TimeTriggerSpec struct {
-		// Cron schedule
-		Cron string `json:"cron"`
+		// (Optional) Cron schedule, required unless At is set.
+		// +optional
+		Cron string `json:"cron,omitempty"`
+
+		// (Optional) At makes the trigger a one-shot schedule: the function
+		// is invoked once at this time instead of on the ticks of Cron.
+		// The timer deletes the trigger TTLSecondsAfterFinished after the
+		// invocation.
+		// +optional
+		At *metav1.Time `json:"at,omitempty"`
+
+		// (Optional) TTLSecondsAfterFinished is how long a one-shot trigger
+		// is kept after its invocation, so that its result can be read
+		// from its status. Defaults to DefaultTTLSecondsAfterFinished, 0
+		// deletes the trigger right away.
+		// +optional
+		TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
 
 		// The reference to function
 		FunctionReference `json:"functionref"`
+
+		// (Optional) CloudEvents sends invocations as CloudEvents v1.0, in
+		// the "binary" or "structured" HTTP content mode, along with the
+		// X-Fission-Timer-Name header.
+		// +optional
+		CloudEvents CloudEventsMode `json:"cloudEvents,omitempty"`
+
+		// (Optional) TimeZone is the IANA name of the time zone of the
+		// cron schedule, e.g. "Europe/Paris". Defaults to the time zone of
+		// the timer.
+		// +optional
+		TimeZone string `json:"timeZone,omitempty"`
+
+		// (Optional) StartTime is when the trigger starts firing, ticks
+		// before it are skipped.
+		// +optional
+		StartTime *metav1.Time `json:"startTime,omitempty"`
+
+		// (Optional) EndTime is when the trigger stops firing, ticks after
+		// it are skipped.
+		// +optional
+		EndTime *metav1.Time `json:"endTime,omitempty"`
+
+		// (Optional) JitterSeconds delays each invocation by a random
+		// duration of up to this number of seconds, to spread the load of
+		// triggers sharing a schedule.
+		// +optional
+		JitterSeconds int32 `json:"jitterSeconds,omitempty"`
+
+		// (Optional) ConcurrencyPolicy is how a tick is handled while the
+		// previous invocation is still running: Allow (default) invokes the
+		// function anyway, Forbid skips the tick and Replace cancels the
+		// running invocation.
+		// +optional
+		ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+		// (Optional) StartingDeadlineSeconds makes the timer catch up on
+		// the ticks missed while it wasn't running: once started, it
+		// invokes the function for the last missed tick if it's less than
+		// this number of seconds old. Missed ticks are skipped if unset.
+		// One-shot triggers are always run late unless it's set.
+		// +optional
+		StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+		// (Optional) Method is the HTTP method of the invocations, POST by
+		// default.
+		// +optional
+		Method string `json:"method,omitempty"`
+
+		// (Optional) Body is the body of the invocations, so that a
+		// function can serve several triggers with different parameters.
+		// +optional
+		Body string `json:"body,omitempty"`
+
+		// (Optional) ContentType is the content type of Body.
+		// +optional
+		ContentType string `json:"contentType,omitempty"`
+
+		// (Optional) Headers are extra headers of the invocations, they
+		// don't override the X-Fission-Timer-Name and CloudEvents headers.
+		// +optional
+		Headers map[string]string `json:"headers,omitempty"`
+	}
+
+	// TimeTriggerStatus is the record of the recent runs of a time
+	// trigger, reported by the timer.
+	TimeTriggerStatus struct {
+		// (Optional) LastScheduleTime is the time of the tick of the last
+		// run.
+		// +optional
+		LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+		// (Optional) LastSuccessfulTime is the time of the tick of the
+		// last successful run.
+		// +optional
+		LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+		// (Optional) Runs are the most recent runs, most recent first, at
+		// most TimeTriggerRunHistoryLimit of them.
+		// +optional
+		Runs []TimeTriggerRun `json:"runs,omitempty"`
+	}
+
+	// TimeTriggerRun is a run of a time trigger.
+	TimeTriggerRun struct {
+		// ScheduleTime is the time of the tick of the run.
+		ScheduleTime metav1.Time `json:"scheduleTime"`
+
+		// (Optional) StatusCode is the status code of the response of the
+		// function, unset if the function couldn't be reached.
+		// +optional
+		StatusCode int `json:"statusCode,omitempty"`
+
+		// DurationMilliseconds is the duration of the invocation of the
+		// function.
+		DurationMilliseconds int64 `json:"durationMilliseconds"`
+
+		// CompletionTime is when the invocation completed.
+		CompletionTime metav1.Time `json:"completionTime"`
+
+		// (Optional) Error is the error invoking the function.
+		// +optional
+		Error string `json:"error,omitempty"`
 	}
 	// FailureType refers to the type of failure
 	FailureType string
@@ -856,6 +1336,11 @@ type (
 	}
 )
 
+// IsSigned checks if the archive carries a signature
+func (a Archive) IsSigned() bool {
+	return len(a.Signature.Sig) > 0
+}
+
 //IsEmpty checks if the archive byte and litreal are of length 0
 func (a Archive) IsEmpty() bool {
 	return len(a.Literal) == 0 && len(a.URL) == 0
@@ -17,18 +17,26 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/robfig/cron"
+	"golang.org/x/net/http/httpguts"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/fission/fission/pkg/mqtrigger/validator"
+	"github.com/fission/fission/pkg/utils/oci"
 )
 
 const (
@@ -178,12 +186,37 @@ func (checksum Checksum) Validate() error {
 	return result.ErrorOrNil()
 }
 
+func (signature ArchiveSignature) Validate() error {
+	result := &multierror.Error{}
+
+	switch signature.Type {
+	case SignatureTypeEd25519: // no op
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "ArchiveSignature.Type", signature.Type, "not a valid signature type"))
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(signature.Sig); err != nil || len(signature.Sig) == 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "ArchiveSignature.Sig", signature.Sig, "must be base64 encoded"))
+	}
+
+	return result.ErrorOrNil()
+}
+
 func (archive Archive) Validate() error {
 	result := &multierror.Error{}
 
 	if len(archive.Type) > 0 {
 		switch archive.Type {
 		case ArchiveTypeLiteral, ArchiveTypeUrl: // no op
+		case ArchiveTypeOCI:
+			// the digest is what guarantees the integrity of the
+			// archive, so unpinned references are not accepted.
+			ref, err := oci.ParseReference(archive.URL)
+			if err != nil {
+				result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Archive.URL", archive.URL, err.Error()))
+			} else if len(ref.Digest) == 0 {
+				result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Archive.URL", archive.URL, "must be pinned to a digest, e.g. oci://registry/repo:tag@sha256:<digest>"))
+			}
 		default:
 			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "Archive.Type", archive.Type, "not a valid archive type"))
 		}
@@ -193,6 +226,10 @@ func (archive Archive) Validate() error {
 		result = multierror.Append(result, archive.Checksum.Validate())
 	}
 
+	if archive.Signature != (ArchiveSignature{}) {
+		result = multierror.Append(result, archive.Signature.Validate())
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -225,6 +262,10 @@ func (spec PackageSpec) Validate() error {
 		}
 	}
 
+	if spec.BuildTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "PackageSpec.BuildTimeout", spec.BuildTimeout, "must not be negative"))
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -349,6 +390,20 @@ func (ref FunctionReference) Validate() error {
 		result = multierror.Append(result, ValidateKubeName("FunctionReference.Name", ref.Name))
 	}
 
+	if ref.Type == FunctionReferenceTypeFunctionWeights {
+		sum := 0
+		for name, weight := range ref.FunctionWeights {
+			result = multierror.Append(result, ValidateKubeName("FunctionReference.FunctionWeights", name))
+			if weight < 0 {
+				result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FunctionReference.FunctionWeights", weight, "function weight must not be negative"))
+			}
+			sum += weight
+		}
+		if sum <= 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "FunctionReference.FunctionWeights", ref.FunctionWeights, "function weights must sum up to more than 0"))
+		}
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -367,8 +422,31 @@ func (runtime Runtime) Validate() error {
 }
 
 func (builder Builder) Validate() error {
-	// do nothing for now
-	return nil
+	result := &multierror.Error{}
+
+	if builder.DependencyCache != nil {
+		result = multierror.Append(result, builder.DependencyCache.Validate())
+	}
+	if builder.Concurrency < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Builder.Concurrency", builder.Concurrency, "must not be negative"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (cache DependencyCache) Validate() error {
+	result := &multierror.Error{}
+
+	if len(cache.ClaimName) > 0 {
+		if e := validation.IsDNS1123Subdomain(cache.ClaimName); len(e) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "DependencyCache.ClaimName", cache.ClaimName, e...))
+		}
+	}
+	if cache.MaxEntries < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "DependencyCache.MaxEntries", cache.MaxEntries, "must not be negative"))
+	}
+
+	return result.ErrorOrNil()
 }
 
 func (spec EnvironmentSpec) Validate() error {
@@ -384,6 +462,11 @@ func (spec EnvironmentSpec) Validate() error {
 		result = multierror.Append(result, spec.Builder.Validate())
 	}
 
+	// built archives are only loaded if signed, by the builder manager
+	if len(spec.TrustedKeysSecret) > 0 && len(spec.Builder.Image) > 0 && len(spec.Builder.SigningKeySecret) == 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.Builder.SigningKeySecret", spec.Builder.SigningKeySecret, "required to sign built packages of an environment with TrustedKeysSecret"))
+	}
+
 	if len(spec.AllowedFunctionsPerContainer) > 0 {
 		switch spec.AllowedFunctionsPerContainer {
 		case AllowedFunctionsPerContainerSingle, AllowedFunctionsPerContainerInfinite: // no op
@@ -491,16 +574,36 @@ func (config IngressConfig) Validate() error {
 func (spec KubernetesWatchTriggerSpec) Validate() error {
 	result := &multierror.Error{}
 
-	switch strings.ToUpper(spec.Type) {
-	case "POD", "SERVICE", "REPLICATIONCONTROLLER", "JOB":
-	default:
-		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "KubernetesWatchTriggerSpec.Type", spec.Type, "not a valid supported type"))
+	if len(spec.Type) == 0 || strings.ContainsAny(spec.Type, " /") {
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "KubernetesWatchTriggerSpec.Type", spec.Type, "not a valid resource or kind"))
 	}
 
 	result = multierror.Append(result,
 		ValidateKubeName("KubernetesWatchTriggerSpec.Namespace", spec.Namespace),
-		ValidateKubeLabel("KubernetesWatchTriggerSpec.LabelSelector", spec.LabelSelector),
-		spec.FunctionReference.Validate())
+		spec.FunctionReference.Validate(),
+		spec.CloudEvents.Validate("KubernetesWatchTriggerSpec.CloudEvents"))
+
+	for _, eventType := range spec.EventTypes {
+		switch eventType {
+		case KubeWatchEventAdded, KubeWatchEventModified, KubeWatchEventDeleted:
+		default:
+			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "KubernetesWatchTriggerSpec.EventTypes", eventType, "not a supported event type"))
+		}
+	}
+
+	if spec.DebounceSeconds < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "KubernetesWatchTriggerSpec.DebounceSeconds", spec.DebounceSeconds, "must not be negative"))
+	}
+
+	if _, err := labels.ValidatedSelectorFromSet(spec.LabelSelector); err != nil {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "KubernetesWatchTriggerSpec.LabelSelector", spec.LabelSelector, err.Error()))
+	}
+
+	if len(spec.FieldSelector) > 0 {
+		if _, err := fields.ParseSelector(spec.FieldSelector); err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "KubernetesWatchTriggerSpec.FieldSelector", spec.FieldSelector, err.Error()))
+		}
+	}
 
 	return result.ErrorOrNil()
 }
@@ -509,6 +612,9 @@ func (spec MessageQueueTriggerSpec) Validate() error {
 	result := &multierror.Error{}
 
 	result = multierror.Append(result, spec.FunctionReference.Validate())
+	if spec.FunctionReference.Type == FunctionReferenceTypeFunctionWeights && spec.MqtKind == "keda" {
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageQueueTriggerSpec.FunctionReference.Type", spec.FunctionReference.Type, "function weights are not supported by keda triggers"))
+	}
 
 	if !validator.IsValidMessageQueue((string)(spec.MessageQueueType), spec.MqtKind) {
 		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageQueueTriggerSpec.MessageQueueType", spec.MessageQueueType, "not a supported message queue type"))
@@ -522,22 +628,223 @@ func (spec MessageQueueTriggerSpec) Validate() error {
 		}
 	}
 
+	if spec.RetryPolicy != nil {
+		result = multierror.Append(result, spec.RetryPolicy.Validate())
+	}
+
+	if spec.Concurrency != nil {
+		if spec.MessageQueueType != MessageQueueTypeKafka {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueTriggerSpec.Concurrency", spec.MessageQueueType, "concurrency is only supported by kafka"))
+		}
+		result = multierror.Append(result, spec.Concurrency.Validate())
+	}
+
+	if spec.Batch != nil {
+		switch spec.MessageQueueType {
+		case MessageQueueTypeKafka, MessageQueueTypeNats, MessageQueueTypeASQ:
+		default:
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueTriggerSpec.Batch", spec.MessageQueueType, "batches are only supported by kafka, nats-streaming and azure-storage-queue"))
+		}
+		result = multierror.Append(result, spec.Batch.Validate())
+	}
+
+	if spec.Schema != nil {
+		if spec.MqtKind == "keda" {
+			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageQueueTriggerSpec.Schema", spec.MqtKind, "schemas are not supported by keda triggers"))
+		}
+		if spec.Schema.Decode && len(spec.ContentType) > 0 && spec.ContentType != "application/json" {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueTriggerSpec.ContentType", spec.ContentType, "decoded messages are JSON, content type must be application/json"))
+		}
+		result = multierror.Append(result, spec.Schema.Validate())
+	}
+
+	if len(spec.CloudEvents) > 0 && spec.MqtKind == "keda" {
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageQueueTriggerSpec.CloudEvents", spec.MqtKind, "cloud events are not supported by keda triggers"))
+	}
+	result = multierror.Append(result, spec.CloudEvents.Validate("MessageQueueTriggerSpec.CloudEvents"))
+
+	return result.ErrorOrNil()
+}
+
+func (schema MessageSchema) Validate() error {
+	result := &multierror.Error{}
+
+	switch schema.Type {
+	case MessageSchemaTypeJSON:
+		if schema.Decode {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageSchema.Decode", schema.Decode, "json messages can't be decoded"))
+		}
+	case MessageSchemaTypeAvro:
+	case MessageSchemaTypeProtobuf:
+		if schema.Registry != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageSchema.Registry", schema.Registry.URL, "protobuf schemas can only be read from a ConfigMap"))
+		}
+		if len(schema.MessageType) == 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageSchema.MessageType", schema.MessageType, "message type is required for protobuf schemas"))
+		}
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageSchema.Type", schema.Type, "not a supported schema type"))
+	}
+
+	if (schema.ConfigMap == nil) == (schema.Registry == nil) {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageSchema", schema.Type, "either a ConfigMap or a registry must be set"))
+	}
+	if schema.ConfigMap != nil {
+		if e := validation.IsDNS1123Subdomain(schema.ConfigMap.Name); len(e) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageSchemaConfigMap.Name", schema.ConfigMap.Name, e...))
+		}
+		if len(schema.ConfigMap.Key) == 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageSchemaConfigMap.Key", schema.ConfigMap.Key, "key is required"))
+		}
+	}
+	if schema.Registry != nil {
+		u, err := url.Parse(schema.Registry.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageSchemaRegistry.URL", schema.Registry.URL, "not a valid http or https URL"))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (batch MessageQueueBatch) Validate() error {
+	result := &multierror.Error{}
+
+	if batch.MaxSize < 1 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueBatch.MaxSize", batch.MaxSize, "max size must be at least 1"))
+	}
+	if batch.MaxWait < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueBatch.MaxWait", batch.MaxWait, "max wait must not be negative"))
+	}
+	switch batch.Format {
+	case "", BatchFormatJSON, BatchFormatMultipart:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageQueueBatch.Format", batch.Format, "not a supported batch format"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (concurrency MessageQueueConcurrency) Validate() error {
+	result := &multierror.Error{}
+
+	switch concurrency.Ordering {
+	case "", MessageOrderingPartition, MessageOrderingKey:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "MessageQueueConcurrency.Ordering", concurrency.Ordering, "not a supported ordering"))
+	}
+	if concurrency.KeyWorkers < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueConcurrency.KeyWorkers", concurrency.KeyWorkers, "key workers must not be negative"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (policy MessageQueueRetryPolicy) Validate() error {
+	result := &multierror.Error{}
+
+	if policy.InitialBackoff < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueRetryPolicy.InitialBackoff", policy.InitialBackoff, "initial backoff must not be negative"))
+	}
+	if policy.MaxBackoff < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueRetryPolicy.MaxBackoff", policy.MaxBackoff, "max backoff must not be negative"))
+	} else if policy.MaxBackoff > 0 && policy.MaxBackoff < policy.InitialBackoff {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueRetryPolicy.MaxBackoff", policy.MaxBackoff, "max backoff must not be less than the initial backoff"))
+	}
+	if policy.MaxAge < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueRetryPolicy.MaxAge", policy.MaxAge, "max age must not be negative"))
+	}
+	for _, code := range policy.RetryableStatusCodes {
+		if code < 100 || code > 599 || code == http.StatusOK {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueRetryPolicy.RetryableStatusCodes", code, "not a failure status code"))
+		}
+	}
+
 	return result.ErrorOrNil()
 }
 
 func (spec TimeTriggerSpec) Validate() error {
 	result := &multierror.Error{}
 
-	err := IsValidCronSpec(spec.Cron)
-	if err != nil {
-		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.Cron", spec.Cron, "not a valid cron spec"))
+	var err error
+	switch {
+	case spec.At != nil && len(spec.Cron) > 0:
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.At", spec.At, "can't be set along with a cron spec"))
+	case spec.At == nil:
+		err = IsValidCronSpec(spec.Cron)
+		if err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.Cron", spec.Cron, "not a valid cron spec"))
+		}
 	}
 
-	result = multierror.Append(result, spec.FunctionReference.Validate())
+	if spec.TTLSecondsAfterFinished != nil && *spec.TTLSecondsAfterFinished < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.TTLSecondsAfterFinished", *spec.TTLSecondsAfterFinished, "must not be negative"))
+	}
+
+	result = multierror.Append(result,
+		spec.FunctionReference.Validate(),
+		spec.CloudEvents.Validate("TimeTriggerSpec.CloudEvents"))
+
+	if len(spec.TimeZone) > 0 {
+		_, err = time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.TimeZone", spec.TimeZone, "not a valid IANA time zone"))
+		}
+	}
+
+	if spec.StartTime != nil && spec.EndTime != nil && !spec.EndTime.After(spec.StartTime.Time) {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.EndTime", spec.EndTime, "must be after the start time"))
+	}
+
+	if spec.JitterSeconds < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.JitterSeconds", spec.JitterSeconds, "must not be negative"))
+	}
+
+	if spec.StartingDeadlineSeconds != nil && *spec.StartingDeadlineSeconds <= 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.StartingDeadlineSeconds", *spec.StartingDeadlineSeconds, "must be positive"))
+	}
+
+	switch spec.ConcurrencyPolicy {
+	case "", ConcurrencyPolicyAllow, ConcurrencyPolicyForbid, ConcurrencyPolicyReplace:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "TimeTriggerSpec.ConcurrencyPolicy", spec.ConcurrencyPolicy, "not a supported concurrency policy, must be Allow, Forbid or Replace"))
+	}
+
+	switch spec.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "TimeTriggerSpec.Method", spec.Method, "not a valid HTTP method"))
+	}
+
+	if len(spec.ContentType) > 0 {
+		_, _, err = mime.ParseMediaType(spec.ContentType)
+		if err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.ContentType", spec.ContentType, "not a valid content type"))
+		}
+	}
+
+	for name, value := range spec.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.Headers", name, "not a valid header name"))
+		} else if !httpguts.ValidHeaderFieldValue(value) {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.Headers", value, fmt.Sprintf("not a valid value of header %v", name)))
+		}
+	}
 
 	return result.ErrorOrNil()
 }
 
+// Validate checks the CloudEvents mode of the trigger spec field, an
+// empty mode disables CloudEvents.
+func (mode CloudEventsMode) Validate(field string) error {
+	switch mode {
+	case "", CloudEventsModeBinary, CloudEventsModeStructured:
+		return nil
+	}
+	return MakeValidationErr(ErrorUnsupportedType, field, mode, "not a supported CloudEvents mode, must be binary or structured")
+}
+
 func validateMetadata(field string, m metav1.ObjectMeta) error {
 	return ValidateKubeReference(field, m.Name, m.Namespace)
 }
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubernetesWatchTriggerSpecValidate(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		labelSelector map[string]string
+		fieldSelector string
+		// invalidField is the field reported invalid, if any
+		invalidField string
+	}{
+		{
+			name:          "valid selectors",
+			labelSelector: map[string]string{"app": "web", "example.com/tier": "front"},
+			fieldSelector: "status.phase=Running,metadata.name!=db",
+		},
+		{
+			name: "no selectors",
+		},
+		{
+			name:          "invalid label key",
+			labelSelector: map[string]string{"app name": "web"},
+			invalidField:  "KubernetesWatchTriggerSpec.LabelSelector",
+		},
+		{
+			name:          "invalid label value",
+			labelSelector: map[string]string{"app": "web front"},
+			invalidField:  "KubernetesWatchTriggerSpec.LabelSelector",
+		},
+		{
+			name:          "malformed field selector",
+			fieldSelector: "status.phase",
+			invalidField:  "KubernetesWatchTriggerSpec.FieldSelector",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			spec := KubernetesWatchTriggerSpec{
+				Namespace:         "default",
+				Type:              "pod",
+				FunctionReference: FunctionReference{Type: FunctionReferenceTypeFunctionName, Name: "hello"},
+				LabelSelector:     test.labelSelector,
+				FieldSelector:     test.fieldSelector,
+			}
+			err := spec.Validate()
+			if len(test.invalidField) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.invalidField+": Invalid value")
+		})
+	}
+}
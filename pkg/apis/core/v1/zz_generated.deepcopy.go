@@ -34,6 +34,7 @@ func (in *Archive) DeepCopyInto(out *Archive) {
 		copy(*out, *in)
 	}
 	out.Checksum = in.Checksum
+	out.Signature = in.Signature
 	return
 }
 
@@ -47,6 +48,22 @@ func (in *Archive) DeepCopy() *Archive {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchiveSignature) DeepCopyInto(out *ArchiveSignature) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchiveSignature.
+func (in *ArchiveSignature) DeepCopy() *ArchiveSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchiveSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Builder) DeepCopyInto(out *Builder) {
 	*out = *in
@@ -60,6 +77,11 @@ func (in *Builder) DeepCopyInto(out *Builder) {
 		*out = new(corev1.PodSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DependencyCache != nil {
+		in, out := &in.DependencyCache, &out.DependencyCache
+		*out = new(DependencyCache)
+		**out = **in
+	}
 	return
 }
 
@@ -198,6 +220,22 @@ func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DependencyCache) DeepCopyInto(out *DependencyCache) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DependencyCache.
+func (in *DependencyCache) DeepCopy() *DependencyCache {
+	if in == nil {
+		return nil
+	}
+	out := new(DependencyCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Environment) DeepCopyInto(out *Environment) {
 	*out = *in
@@ -583,6 +621,7 @@ func (in *KubernetesWatchTrigger) DeepCopyInto(out *KubernetesWatchTrigger) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -648,6 +687,11 @@ func (in *KubernetesWatchTriggerSpec) DeepCopyInto(out *KubernetesWatchTriggerSp
 		}
 	}
 	in.FunctionReference.DeepCopyInto(&out.FunctionReference)
+	if in.EventTypes != nil {
+		in, out := &in.EventTypes, &out.EventTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -661,12 +705,86 @@ func (in *KubernetesWatchTriggerSpec) DeepCopy() *KubernetesWatchTriggerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesWatchTriggerStatus) DeepCopyInto(out *KubernetesWatchTriggerStatus) {
+	*out = *in
+	if in.LastEventTime != nil {
+		in, out := &in.LastEventTime, &out.LastEventTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesWatchTriggerStatus.
+func (in *KubernetesWatchTriggerStatus) DeepCopy() *KubernetesWatchTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesWatchTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageQueueBatch) DeepCopyInto(out *MessageQueueBatch) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageQueueBatch.
+func (in *MessageQueueBatch) DeepCopy() *MessageQueueBatch {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageQueueBatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageQueueConcurrency) DeepCopyInto(out *MessageQueueConcurrency) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageQueueConcurrency.
+func (in *MessageQueueConcurrency) DeepCopy() *MessageQueueConcurrency {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageQueueConcurrency)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageQueueRetryPolicy) DeepCopyInto(out *MessageQueueRetryPolicy) {
+	*out = *in
+	if in.RetryableStatusCodes != nil {
+		in, out := &in.RetryableStatusCodes, &out.RetryableStatusCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageQueueRetryPolicy.
+func (in *MessageQueueRetryPolicy) DeepCopy() *MessageQueueRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageQueueRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MessageQueueTrigger) DeepCopyInto(out *MessageQueueTrigger) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -725,6 +843,26 @@ func (in *MessageQueueTriggerList) DeepCopyObject() runtime.Object {
 func (in *MessageQueueTriggerSpec) DeepCopyInto(out *MessageQueueTriggerSpec) {
 	*out = *in
 	in.FunctionReference.DeepCopyInto(&out.FunctionReference)
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(MessageQueueRetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = new(MessageQueueConcurrency)
+		**out = **in
+	}
+	if in.Batch != nil {
+		in, out := &in.Batch, &out.Batch
+		*out = new(MessageQueueBatch)
+		**out = **in
+	}
+	if in.Schema != nil {
+		in, out := &in.Schema, &out.Schema
+		*out = new(MessageSchema)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PollingInterval != nil {
 		in, out := &in.PollingInterval, &out.PollingInterval
 		*out = new(int32)
@@ -770,6 +908,93 @@ func (in *MessageQueueTriggerSpec) DeepCopy() *MessageQueueTriggerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageQueueTriggerStatus) DeepCopyInto(out *MessageQueueTriggerStatus) {
+	*out = *in
+	if in.LastMessageAt != nil {
+		in, out := &in.LastMessageAt, &out.LastMessageAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ConsumerLag != nil {
+		in, out := &in.ConsumerLag, &out.ConsumerLag
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LastErrorAt != nil {
+		in, out := &in.LastErrorAt, &out.LastErrorAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageQueueTriggerStatus.
+func (in *MessageQueueTriggerStatus) DeepCopy() *MessageQueueTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageQueueTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageSchema) DeepCopyInto(out *MessageSchema) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(MessageSchemaConfigMap)
+		**out = **in
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(MessageSchemaRegistry)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageSchema.
+func (in *MessageSchema) DeepCopy() *MessageSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageSchemaConfigMap) DeepCopyInto(out *MessageSchemaConfigMap) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageSchemaConfigMap.
+func (in *MessageSchemaConfigMap) DeepCopy() *MessageSchemaConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageSchemaConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MessageSchemaRegistry) DeepCopyInto(out *MessageSchemaRegistry) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MessageSchemaRegistry.
+func (in *MessageSchemaRegistry) DeepCopy() *MessageSchemaRegistry {
+	if in == nil {
+		return nil
+	}
+	out := new(MessageSchemaRegistry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Package) DeepCopyInto(out *Package) {
 	*out = *in
@@ -931,6 +1156,7 @@ func (in *TimeTrigger) DeepCopyInto(out *TimeTrigger) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -985,10 +1211,57 @@ func (in *TimeTriggerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeTriggerRun) DeepCopyInto(out *TimeTriggerRun) {
+	*out = *in
+	in.ScheduleTime.DeepCopyInto(&out.ScheduleTime)
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeTriggerRun.
+func (in *TimeTriggerRun) DeepCopy() *TimeTriggerRun {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeTriggerRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TimeTriggerSpec) DeepCopyInto(out *TimeTriggerSpec) {
 	*out = *in
+	if in.At != nil {
+		in, out := &in.At, &out.At
+		*out = (*in).DeepCopy()
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int64)
+		**out = **in
+	}
 	in.FunctionReference.DeepCopyInto(&out.FunctionReference)
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -1002,6 +1275,37 @@ func (in *TimeTriggerSpec) DeepCopy() *TimeTriggerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeTriggerStatus) DeepCopyInto(out *TimeTriggerStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Runs != nil {
+		in, out := &in.Runs, &out.Runs
+		*out = make([]TimeTriggerRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeTriggerStatus.
+func (in *TimeTriggerStatus) DeepCopy() *TimeTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ValidationError) DeepCopyInto(out *ValidationError) {
 	*out = *in
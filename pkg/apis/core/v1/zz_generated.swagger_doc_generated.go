@@ -26,23 +26,36 @@ package v1
 // Those methods can be generated by using hack/update-swagger-docs.sh
 // AUTO-GENERATED FUNCTIONS START HERE
 var map_Archive = map[string]string{
-	"":         "Archive contains or references a collection of source or binary files.",
-	"type":     "Type defines how the package is specified: literal or URL. Available value:\n - literal\n - url",
-	"literal":  "Literal contents of the package. Can be used for encoding packages below TODO (256KB?) size.",
-	"url":      "URL references a package.",
-	"checksum": "Checksum ensures the integrity of packages referenced by URL. Ignored for literals.",
+	"":          "Archive contains or references a collection of source or binary files.",
+	"type":      "Type defines how the package is specified: literal, URL or OCI. Available value:\n - literal\n - url\n - oci",
+	"literal":   "Literal contents of the package. Can be used for encoding packages below TODO (256KB?) size.",
+	"url":       "URL references a package. For the oci type it's a reference of the form oci://registry/repo:tag@digest, where the manifest digest takes the place of Checksum.",
+	"checksum":  "Checksum ensures the integrity of packages referenced by URL. Ignored for literals and OCI references, which are pinned by digest.",
+	"signature": "Signature is a detached signature of the archive contents. It's required if the environment of the package sets TrustedKeysSecret, and fetcher refuses to load the archive when the signature is missing or doesn't match.",
 }
 
 func (Archive) SwaggerDoc() map[string]string {
 	return map_Archive
 }
 
+var map_ArchiveSignature = map[string]string{
+	"":      "ArchiveSignature is a detached signature of an archive. The signed message is the raw SHA256 digest of the archive contents, so that the signature covers literal and URL archives alike. \"ed25519\" is the only currently supported type. Sig is base64 encoded.",
+	"keyid": "KeyID is the name of the trusted key, that is the key of the public key in the trusted keys secret, used to verify the signature. If empty, all trusted keys are tried.",
+}
+
+func (ArchiveSignature) SwaggerDoc() map[string]string {
+	return map_ArchiveSignature
+}
+
 var map_Builder = map[string]string{
-	"":          "Builder is the setting for environment builder.",
-	"image":     "Image for containing the language compilation environment.",
-	"command":   "(Optional) Default build command to run for this build environment.",
-	"container": "(Optional) Container allows the modification of the deployed builder container using the Kubernetes Container spec. Fission overrides the following fields: - Name - Image; set to the Builder.Image - Command; set to the Builder.Command - TerminationMessagePath - ImagePullPolicy - ReadinessProbe",
-	"podspec":   "PodSpec will store the spec of the pod that will be applied to the pod created for the builder",
+	"":                 "Builder is the setting for environment builder.",
+	"image":            "Image for containing the language compilation environment.",
+	"command":          "(Optional) Default build command to run for this build environment.",
+	"container":        "(Optional) Container allows the modification of the deployed builder container using the Kubernetes Container spec. Fission overrides the following fields: - Name - Image; set to the Builder.Image - Command; set to the Builder.Command - TerminationMessagePath - ImagePullPolicy - ReadinessProbe",
+	"podspec":          "PodSpec will store the spec of the pod that will be applied to the pod created for the builder",
+	"dependencycache":  "(Optional) DependencyCache enables a dependency cache volume on the builder pod. Builds are given a cache directory keyed on the hash of the lockfiles in the source package, so that unchanged dependencies are not reinstalled on every build.",
+	"concurrency":      "(Optional) Concurrency is the number of package builds running at the same time on the builder of this environment, further builds wait in a queue. Defaults to the build concurrency of the builder manager.",
+	"signingkeysecret": "(Optional) SigningKeySecret is the name of a Secret, in the same namespace as the environment, holding the PEM encoded ed25519 private key the builder manager signs built deployment archives with. The Secret has a single entry, whose data key is used as the key ID. Required if the environment sets TrustedKeysSecret, which then has to trust the key under the same ID.",
 }
 
 func (Builder) SwaggerDoc() map[string]string {
@@ -103,6 +116,16 @@ func (ConfigMapReference) SwaggerDoc() map[string]string {
 	return map_ConfigMapReference
 }
 
+var map_DependencyCache = map[string]string{
+	"":           "DependencyCache configures the dependency cache volume of a builder.",
+	"claimname":  "(Optional) ClaimName is the name of a PersistentVolumeClaim in the builder namespace to keep the cache on, so that it survives builder pod restarts and environment updates. An emptyDir volume is used if it's empty.",
+	"maxentries": "(Optional) MaxEntries is the number of lockfile hashes kept in the cache. The least recently used entries are removed first. Defaults to 5.",
+}
+
+func (DependencyCache) SwaggerDoc() map[string]string {
+	return map_DependencyCache
+}
+
 var map_Environment = map[string]string{
 	"": "Environment is environment for building and running user functions.",
 }
@@ -139,6 +162,7 @@ var map_EnvironmentSpec = map[string]string{
 	"terminationGracePeriod":       "The grace time for pod to perform connection draining before termination. The unit is in seconds. (Optional) defaults to 360 seconds",
 	"keeparchive":                  "KeepArchive is used by fetcher to determine if the extracted archive or unarchived file should be placed, which is then used by specialize handler. (This is mainly for the JVM environment because .jar is one kind of zip archive.)",
 	"imagepullsecret":              "ImagePullSecret is the secret for Kubernetes to pull an image from a private registry.",
+	"trustedkeyssecret":            "TrustedKeysSecret is the name of a Secret, in the same namespace as the environment, holding the PEM encoded ed25519 public keys trusted to sign packages of this environment. The data key of each entry is used as the key ID. If set, fetcher refuses to load any source or deployment archive that is unsigned or not signed by one of the trusted keys.",
 }
 
 func (EnvironmentSpec) SwaggerDoc() map[string]string {
@@ -271,7 +295,8 @@ func (InvokeStrategy) SwaggerDoc() map[string]string {
 }
 
 var map_KubernetesWatchTrigger = map[string]string{
-	"": "KubernetesWatchTrigger watches kubernetes resource events and invokes functions.",
+	"":       "KubernetesWatchTrigger watches kubernetes resource events and invokes functions.",
+	"status": "Status is the progress of the kubewatcher through the events of the watched resources.",
 }
 
 func (KubernetesWatchTrigger) SwaggerDoc() map[string]string {
@@ -287,18 +312,68 @@ func (KubernetesWatchTriggerList) SwaggerDoc() map[string]string {
 }
 
 var map_KubernetesWatchTriggerSpec = map[string]string{
-	"":              "KubernetesWatchTriggerSpec defines spec of KuberenetesWatchTrigger",
-	"type":          "Type of resource to watch (Pod, Service, etc.)",
-	"labelselector": "Resource labels",
-	"functionref":   "The reference to a function for kubewatcher to invoke with when receiving events.",
+	"":                    "KubernetesWatchTriggerSpec defines spec of KuberenetesWatchTrigger",
+	"type":                "Type of resource to watch, a resource or a kind optionally qualified by version and group, like kubectl takes them: Pod, services, deployments.apps, functions.v1.fission.io, etc.",
+	"labelselector":       "Resource labels, only the resources with all of them are watched.",
+	"fieldselector":       "(Optional) FieldSelector restricts the watched resources by their fields, like \"status.phase=Running,spec.nodeName!=node-1\".",
+	"functionref":         "The reference to a function for kubewatcher to invoke with when receiving events.",
+	"cloudEvents":         "(Optional) CloudEvents sends events as CloudEvents v1.0, in the \"binary\" or \"structured\" HTTP content mode, along with the X-Kubernetes headers.",
+	"eventTypes":          "(Optional) EventTypes are the types of the events to invoke the function with: ADDED, MODIFIED or DELETED. All of them if empty.",
+	"ignoreStatusChanges": "(Optional) IgnoreStatusChanges skips the MODIFIED events changing only the status or the metadata maintained by Kubernetes, like the resource version, of the object.",
+	"debounceSeconds":     "(Optional) DebounceSeconds coalesces the events of each object over a window: the function is invoked once with the net event at its end, e.g. a single ADDED event for an object added and modified.",
+	"includeOldObject":    "(Optional) IncludeOldObject sends MODIFIED events as {\"object\": new, \"oldObject\": old} rather than the new object only.",
 }
 
 func (KubernetesWatchTriggerSpec) SwaggerDoc() map[string]string {
 	return map_KubernetesWatchTriggerSpec
 }
 
+var map_KubernetesWatchTriggerStatus = map[string]string{
+	"":                    "KubernetesWatchTriggerStatus is the progress of the kubewatcher through the events of the resources of a watch, which it resumes from after restarts.",
+	"lastResourceVersion": "(Optional) LastResourceVersion is the resource version of the last processed event.",
+	"lastEventTime":       "(Optional) LastEventTime is the time the last event was processed.",
+}
+
+func (KubernetesWatchTriggerStatus) SwaggerDoc() map[string]string {
+	return map_KubernetesWatchTriggerStatus
+}
+
+var map_MessageQueueBatch = map[string]string{
+	"":        "MessageQueueBatch controls how messages are batched. The function can report that some messages of a batch failed by responding with 207 Multi-Status and a JSON body like {\"failed\": [0, 2]}, listing the indexes of the failed messages in the batch. Only those are retried then.",
+	"maxSize": "MaxSize is the maximum number of messages of a batch.",
+	"maxWait": "(Optional) MaxWait is the time in milliseconds to wait for more messages before delivering a batch that isn't full. The messages available at once are delivered if not set.",
+	"format":  "(Optional) Format is either \"json\", a JSON array of the messages and their headers, or \"multipart\", a multipart/mixed body with a part per message. Defaults to \"json\".",
+}
+
+func (MessageQueueBatch) SwaggerDoc() map[string]string {
+	return map_MessageQueueBatch
+}
+
+var map_MessageQueueConcurrency = map[string]string{
+	"":           "MessageQueueConcurrency controls how many messages of a partition of a message queue trigger are processed at once.",
+	"ordering":   "(Optional) Ordering is either \"partition\", the messages of a partition are processed one at a time in order, or \"key\", up to KeyWorkers messages of a partition are processed at once and the messages with the same key in order. Defaults to \"partition\".",
+	"keyWorkers": "(Optional) KeyWorkers is the number of messages of a partition processed at once with \"key\" ordering. Defaults to 10.",
+}
+
+func (MessageQueueConcurrency) SwaggerDoc() map[string]string {
+	return map_MessageQueueConcurrency
+}
+
+var map_MessageQueueRetryPolicy = map[string]string{
+	"":                     "MessageQueueRetryPolicy controls how failed function invocations of a message queue trigger are retried, up to MaxRetries times.",
+	"initialBackoff":       "(Optional) InitialBackoff is the delay in milliseconds before the first retry, it's doubled for each following retry.",
+	"maxBackoff":           "(Optional) MaxBackoff caps the delay in milliseconds between two retries.",
+	"retryableStatusCodes": "(Optional) RetryableStatusCodes are the function response status codes that are retried. Any failure is retried if not set, and requests that didn't get a response are always retried.",
+	"maxAge":               "(Optional) MaxAge is the time in seconds since the first attempt after which a message isn't retried anymore.",
+}
+
+func (MessageQueueRetryPolicy) SwaggerDoc() map[string]string {
+	return map_MessageQueueRetryPolicy
+}
+
 var map_MessageQueueTrigger = map[string]string{
-	"": "MessageQueueTrigger invokes functions when messages arrive to certain topic that trigger subscribes to.",
+	"":       "MessageQueueTrigger invokes functions when messages arrive to certain topic that trigger subscribes to.",
+	"status": "Status is the state of the subscription of the trigger.",
 }
 
 func (MessageQueueTrigger) SwaggerDoc() map[string]string {
@@ -321,6 +396,11 @@ var map_MessageQueueTriggerSpec = map[string]string{
 	"respTopic":        "Topic for message queue trigger to sent response from function.",
 	"errorTopic":       "Topic to collect error response sent from function",
 	"maxRetries":       "Maximum times for message queue trigger to retry",
+	"retryPolicy":      "(Optional) RetryPolicy controls the delay between retries and which failures are retried. Failures are retried immediately if not set.",
+	"concurrency":      "(Optional) Concurrency controls how the messages of a partition are processed, it's only supported by Kafka. Partitions are processed concurrently, and the messages of a partition one at a time in order if not set.",
+	"batch":            "(Optional) Batch delivers messages to the function in batches, it's supported by Kafka, NATS streaming and Azure storage queue. Messages are delivered one at a time if not set.",
+	"schema":           "(Optional) Schema checks messages against a schema before invoking the function, and optionally decodes them to JSON. Messages that don't match the schema are handled like failed invocations, without invoking the function. It's not supported by keda triggers.",
+	"cloudEvents":      "(Optional) CloudEvents delivers messages as CloudEvents v1.0, in the \"binary\" or \"structured\" HTTP content mode, along with the X-Fission-MQTrigger headers. The messages of a batch are CloudEvents each. It's not supported by keda triggers.",
 	"contentType":      "Content type of payload",
 	"pollingInterval":  "The period to check each trigger source on every ScaledObject, and scale the deployment up or down accordingly",
 	"cooldownPeriod":   "The period to wait after the last trigger reported active before scaling the deployment back to 0",
@@ -336,6 +416,52 @@ func (MessageQueueTriggerSpec) SwaggerDoc() map[string]string {
 	return map_MessageQueueTriggerSpec
 }
 
+var map_MessageQueueTriggerStatus = map[string]string{
+	"":              "MessageQueueTriggerStatus is the state of the subscription of a message queue trigger, reported by the trigger manager or, for keda triggers, by the scaler manager.",
+	"subscribed":    "(Optional) Subscribed is true once the trigger is subscribed to its topic, or once its keda scaled object is created.",
+	"lastMessageAt": "(Optional) LastMessageAt is the time the trigger last handled a message.",
+	"consumerLag":   "(Optional) ConsumerLag is the number of messages of the topic not consumed by the trigger yet, for the message queues that report it.",
+	"lastError":     "(Optional) LastError is the last error subscribing the trigger or handling a message.",
+	"lastErrorAt":   "(Optional) LastErrorAt is the time of LastError.",
+}
+
+func (MessageQueueTriggerStatus) SwaggerDoc() map[string]string {
+	return map_MessageQueueTriggerStatus
+}
+
+var map_MessageSchema = map[string]string{
+	"":            "MessageSchema is the schema of the messages of a message queue trigger, read from a ConfigMap or a schema registry. Schemas are read when the trigger is subscribed, updating the ConfigMap takes effect once the trigger is updated.",
+	"type":        "Type is either \"json\", messages are JSON documents checked against a JSON Schema, \"avro\", messages are Avro binary encoded, or \"protobuf\", messages are Protocol Buffers binary encoded.",
+	"configMap":   "(Optional) ConfigMap is the ConfigMap key holding the schema, in the namespace of the trigger. Protobuf schemas are a FileDescriptorSet, as written by protoc --include_imports --descriptor_set_out. Either ConfigMap or Registry must be set.",
+	"registry":    "(Optional) Registry is a Confluent compatible schema registry holding the schema, it's not supported for protobuf schemas.",
+	"messageType": "(Optional) MessageType is the full name of the protobuf message type of the messages, required for protobuf schemas.",
+	"decode":      "(Optional) Decode converts avro and protobuf messages to JSON before delivering them to the function, in the JSON encoding of the Avro specification or the protobuf JSON mapping.",
+}
+
+func (MessageSchema) SwaggerDoc() map[string]string {
+	return map_MessageSchema
+}
+
+var map_MessageSchemaConfigMap = map[string]string{
+	"":     "MessageSchemaConfigMap is the ConfigMap key holding a schema.",
+	"name": "Name is the name of the ConfigMap.",
+	"key":  "Key is the key of the schema in the data or binary data of the ConfigMap.",
+}
+
+func (MessageSchemaConfigMap) SwaggerDoc() map[string]string {
+	return map_MessageSchemaConfigMap
+}
+
+var map_MessageSchemaRegistry = map[string]string{
+	"":        "MessageSchemaRegistry is a Confluent compatible schema registry. Messages in the registry wire format, starting with a zero byte and the schema ID, are checked against the schema with that ID. Other messages are checked against the latest schema of Subject.",
+	"url":     "URL is the URL of the registry, credentials can be part of it.",
+	"subject": "(Optional) Subject is the subject whose latest schema checks messages not in the registry wire format. Such messages are invalid if not set.",
+}
+
+func (MessageSchemaRegistry) SwaggerDoc() map[string]string {
+	return map_MessageSchemaRegistry
+}
+
 var map_Package = map[string]string{
 	"":       "Package Think of these as function-level images.",
 	"status": "Status indicates the build status of package.",
@@ -363,11 +489,13 @@ func (PackageRef) SwaggerDoc() map[string]string {
 }
 
 var map_PackageSpec = map[string]string{
-	"":            "PackageSpec includes source/deploy archives and the reference of environment to build the package.",
-	"environment": "Environment is a reference to the environment for building source archive.",
-	"source":      "Source is the archive contains source code and dependencies file. If the package status is in PENDING state, builder manager will then notify builder to compile source and save the result as deployable archive.",
-	"deployment":  "Deployment is the deployable archive that environment runtime used to run user function.",
-	"buildcmd":    "BuildCommand is a custom build command that builder used to build the source archive.",
+	"":              "PackageSpec includes source/deploy archives and the reference of environment to build the package.",
+	"environment":   "Environment is a reference to the environment for building source archive.",
+	"source":        "Source is the archive contains source code and dependencies file. If the package status is in PENDING state, builder manager will then notify builder to compile source and save the result as deployable archive.",
+	"deployment":    "Deployment is the deployable archive that environment runtime used to run user function.",
+	"buildcmd":      "BuildCommand is a custom build command that builder used to build the source archive.",
+	"buildtimeout":  "(Optional) BuildTimeout is the maximum duration of the build command in seconds. The build command is killed and the package marked as failed once it's exceeded. Builds don't time out if it's 0.",
+	"buildpriority": "(Optional) BuildPriority orders the builds waiting for the builder of the environment. Builds with a higher priority start first, builds with the same priority in the order they were requested.",
 }
 
 func (PackageSpec) SwaggerDoc() map[string]string {
@@ -405,7 +533,8 @@ func (SecretReference) SwaggerDoc() map[string]string {
 }
 
 var map_TimeTrigger = map[string]string{
-	"": "TimeTrigger invokes functions based on given cron schedule.",
+	"":       "TimeTrigger invokes functions based on given cron schedule.",
+	"status": "Status is the record of the recent runs of the trigger.",
 }
 
 func (TimeTrigger) SwaggerDoc() map[string]string {
@@ -420,14 +549,51 @@ func (TimeTriggerList) SwaggerDoc() map[string]string {
 	return map_TimeTriggerList
 }
 
+var map_TimeTriggerRun = map[string]string{
+	"":                     "TimeTriggerRun is a run of a time trigger.",
+	"scheduleTime":         "ScheduleTime is the time of the tick of the run.",
+	"statusCode":           "(Optional) StatusCode is the status code of the response of the function, unset if the function couldn't be reached.",
+	"durationMilliseconds": "DurationMilliseconds is the duration of the invocation of the function.",
+	"completionTime":       "CompletionTime is when the invocation completed.",
+	"error":                "(Optional) Error is the error invoking the function.",
+}
+
+func (TimeTriggerRun) SwaggerDoc() map[string]string {
+	return map_TimeTriggerRun
+}
+
 var map_TimeTriggerSpec = map[string]string{
-	"":            "TimeTriggerSpec invokes the specific function at a time or times specified by a cron string.",
-	"cron":        "Cron schedule",
-	"functionref": "The reference to function",
+	"":                        "TimeTriggerSpec invokes the specific function at a time or times specified by a cron string.",
+	"cron":                    "(Optional) Cron schedule, required unless At is set.",
+	"at":                      "(Optional) At makes the trigger a one-shot schedule: the function is invoked once at this time instead of on the ticks of Cron. The timer deletes the trigger TTLSecondsAfterFinished after the invocation.",
+	"ttlSecondsAfterFinished": "(Optional) TTLSecondsAfterFinished is how long a one-shot trigger is kept after its invocation, so that its result can be read from its status. Defaults to DefaultTTLSecondsAfterFinished, 0 deletes the trigger right away.",
+	"functionref":             "The reference to function",
+	"cloudEvents":             "(Optional) CloudEvents sends invocations as CloudEvents v1.0, in the \"binary\" or \"structured\" HTTP content mode, along with the X-Fission-Timer-Name header.",
+	"timeZone":                "(Optional) TimeZone is the IANA name of the time zone of the cron schedule, e.g. \"Europe/Paris\". Defaults to the time zone of the timer.",
+	"startTime":               "(Optional) StartTime is when the trigger starts firing, ticks before it are skipped.",
+	"endTime":                 "(Optional) EndTime is when the trigger stops firing, ticks after it are skipped.",
+	"jitterSeconds":           "(Optional) JitterSeconds delays each invocation by a random duration of up to this number of seconds, to spread the load of triggers sharing a schedule.",
+	"concurrencyPolicy":       "(Optional) ConcurrencyPolicy is how a tick is handled while the previous invocation is still running: Allow (default) invokes the function anyway, Forbid skips the tick and Replace cancels the running invocation.",
+	"startingDeadlineSeconds": "(Optional) StartingDeadlineSeconds makes the timer catch up on the ticks missed while it wasn't running: once started, it invokes the function for the last missed tick if it's less than this number of seconds old. Missed ticks are skipped if unset. One-shot triggers are always run late unless it's set.",
+	"method":                  "(Optional) Method is the HTTP method of the invocations, POST by default.",
+	"body":                    "(Optional) Body is the body of the invocations, so that a function can serve several triggers with different parameters.",
+	"contentType":             "(Optional) ContentType is the content type of Body.",
+	"headers":                 "(Optional) Headers are extra headers of the invocations, they don't override the X-Fission-Timer-Name and CloudEvents headers.",
 }
 
 func (TimeTriggerSpec) SwaggerDoc() map[string]string {
 	return map_TimeTriggerSpec
 }
 
+var map_TimeTriggerStatus = map[string]string{
+	"":                   "TimeTriggerStatus is the record of the recent runs of a time trigger, reported by the timer.",
+	"lastScheduleTime":   "(Optional) LastScheduleTime is the time of the tick of the last run.",
+	"lastSuccessfulTime": "(Optional) LastSuccessfulTime is the time of the tick of the last successful run.",
+	"runs":               "(Optional) Runs are the most recent runs, most recent first, at most TimeTriggerRunHistoryLimit of them.",
+}
+
+func (TimeTriggerStatus) SwaggerDoc() map[string]string {
+	return map_TimeTriggerStatus
+}
+
 // AUTO-GENERATED FUNCTIONS END HERE
@@ -18,6 +18,7 @@ package builder
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,7 +28,9 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/dchest/uniuri"
@@ -41,6 +44,12 @@ const (
 	// supported environment variables
 	envSrcPkg    = "SRC_PKG"
 	envDeployPkg = "DEPLOY_PKG"
+
+	// dependency cache environment variables, only set if the
+	// dependency cache is enabled and the source has lockfiles
+	envCacheDir = "BUILD_CACHE_DIR"
+	envCacheKey = "BUILD_CACHE_KEY"
+	envCacheHit = "BUILD_CACHE_HIT"
 )
 
 type (
@@ -48,28 +57,56 @@ type (
 		SrcPkgFilename string `json:"srcPkgFilename"`
 		// Command for builder to run with.
 		// A build command consists of commands, parameters and environment variables.
-		// For now, two environment variables are supported:
+		// The following environment variables are supported:
 		// 1. SRC_PKG: path to source package directory
 		// 2. DEPLOY_PKG: path to deployment package directory
+		// 3. BUILD_CACHE_DIR: path to the dependency cache directory
+		//    for the lockfiles of the source package
+		// 4. BUILD_CACHE_KEY: hash of the lockfiles
+		// 5. BUILD_CACHE_HIT: "true" if an earlier build with the same
+		//    lockfiles succeeded using the cache directory
 		BuildCommand string `json:"command"`
+		// Timeout is the maximum duration of the build command in seconds,
+		// no timeout if it's 0.
+		Timeout int `json:"timeout,omitempty"`
 	}
 
 	PackageBuildResponse struct {
 		ArtifactFilename string `json:"artifactFilename"`
 		BuildLogs        string `json:"buildLogs"`
+		// CacheKey is the dependency cache key used by the build,
+		// empty if the cache is disabled or there are no lockfiles.
+		CacheKey string `json:"cacheKey,omitempty"`
+		CacheHit bool   `json:"cacheHit,omitempty"`
 	}
 
 	Builder struct {
 		logger           *zap.Logger
 		sharedVolumePath string
+		cache            *dependencyCache
+		logs             *buildLogs
 	}
 )
 
 func MakeBuilder(logger *zap.Logger, sharedVolumePath string) *Builder {
-	return &Builder{
+	builder := &Builder{
 		logger:           logger.Named("builder"),
 		sharedVolumePath: sharedVolumePath,
+		logs:             makeBuildLogs(),
+	}
+
+	// the dependency cache volume is mounted by buildermgr if
+	// the environment enables it
+	cacheDir := os.Getenv("BUILDER_CACHE_DIR")
+	if len(cacheDir) > 0 {
+		maxEntries, err := strconv.Atoi(os.Getenv("BUILDER_CACHE_MAX_ENTRIES"))
+		if err != nil {
+			maxEntries = defaultCacheMaxEntries
+		}
+		builder.cache = makeDependencyCache(cacheDir, maxEntries)
+		builder.logger.Info("dependency cache enabled", zap.String("directory", cacheDir), zap.Int("max_entries", maxEntries))
 	}
+	return builder
 }
 
 func (builder *Builder) VersionHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +124,7 @@ func (builder *Builder) Handler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		e := "method not allowed"
 		builder.logger.Error(e, zap.String("http_method", r.Method))
-		builder.reply(w, "", fmt.Sprintf("%s: %s", e, r.Method), http.StatusMethodNotAllowed)
+		builder.reply(w, PackageBuildResponse{BuildLogs: fmt.Sprintf("%s: %s", e, r.Method)}, http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -102,7 +139,7 @@ func (builder *Builder) Handler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		e := "error reading request body"
 		builder.logger.Error(e, zap.Error(err))
-		builder.reply(w, "", fmt.Sprintf("%s: %s", e, err.Error()), http.StatusInternalServerError)
+		builder.reply(w, PackageBuildResponse{BuildLogs: fmt.Sprintf("%s: %s", e, err.Error())}, http.StatusInternalServerError)
 		return
 	}
 	var req PackageBuildRequest
@@ -110,7 +147,7 @@ func (builder *Builder) Handler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		e := "error parsing json body"
 		builder.logger.Error(e, zap.Error(err))
-		builder.reply(w, "", fmt.Sprintf("%s: %s", e, err.Error()), http.StatusBadRequest)
+		builder.reply(w, PackageBuildResponse{BuildLogs: fmt.Sprintf("%s: %s", e, err.Error())}, http.StatusBadRequest)
 		return
 	}
 	builder.logger.Info("builder received request", zap.Any("request", req))
@@ -124,26 +161,125 @@ func (builder *Builder) Handler(w http.ResponseWriter, r *http.Request) {
 		// use default build command
 		buildCmd = "/build"
 	}
-	buildLogs, err := builder.build(buildCmd, srcPkgPath, deployPkgPath)
+	resp := PackageBuildResponse{ArtifactFilename: deployPkgFilename}
+
+	var cacheEnv []string
+	cacheEntry := builder.cacheEntry(srcPkgPath)
+	if cacheEntry != nil {
+		defer builder.cache.release(cacheEntry)
+		resp.CacheKey = cacheEntry.key
+		resp.CacheHit = cacheEntry.hit
+		cacheEnv = cacheEntry.env()
+	}
+
+	// the build is killed if the request is cancelled, e.g. when the
+	// package build is cancelled through buildermgr
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if req.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(r.Context())
+	}
+	defer cancel()
+
+	log := builder.logs.start(req.SrcPkgFilename, cancel)
+	defer builder.logs.finish(req.SrcPkgFilename)
+
+	buildLogs, err := builder.build(ctx, buildCmd, srcPkgPath, deployPkgPath, cacheEnv, log)
+	resp.BuildLogs = buildLogs
 	if err != nil {
 		e := "error building source package"
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			err = errors.Errorf("build timed out after %v", time.Duration(req.Timeout)*time.Second)
+		case context.Canceled:
+			err = errors.New("build cancelled")
+		}
 		builder.logger.Error(e, zap.Error(err))
 
 		// append error at the end of build logs
-		buildLogs += fmt.Sprintf("%s: %s\n", e, err.Error())
-		builder.reply(w, deployPkgFilename, buildLogs, http.StatusInternalServerError)
+		resp.BuildLogs += fmt.Sprintf("%s: %s\n", e, err.Error())
+		builder.reply(w, resp, http.StatusInternalServerError)
+		return
+	}
+
+	if cacheEntry != nil {
+		err = builder.cache.complete(cacheEntry)
+		if err != nil {
+			// the build itself succeeded, a broken cache only makes later builds slower
+			builder.logger.Error("error updating dependency cache", zap.Error(err))
+		}
+	}
+
+	builder.reply(w, resp, http.StatusOK)
+}
+
+// LogsHandler streams the output of a running build, identified by the
+// source package filename of its build request, until the build finishes.
+func (builder *Builder) LogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "only GET is supported on this endpoint", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	log := builder.logs.get(id)
+	if log == nil {
+		http.Error(w, fmt.Sprintf("no running build for %q", id), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	err := log.follow(r.Context(), w)
+	if err != nil {
+		builder.logger.Info("stopped streaming build logs", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// CancelHandler kills the running build identified by the source package
+// filename of its build request. The build request fails with the
+// build logs produced so far.
+func (builder *Builder) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "only POST is supported on this endpoint", http.StatusMethodNotAllowed)
 		return
 	}
 
-	builder.reply(w, deployPkgFilename, buildLogs, http.StatusOK)
+	id := r.URL.Query().Get("id")
+	log := builder.logs.get(id)
+	if log == nil {
+		http.Error(w, fmt.Sprintf("no running build for %q", id), http.StatusNotFound)
+		return
+	}
+	builder.logger.Info("cancelling build", zap.String("id", id))
+	log.cancel()
+	w.WriteHeader(http.StatusOK)
 }
 
-func (builder *Builder) reply(w http.ResponseWriter, pkgFilename string, buildLogs string, statusCode int) {
-	resp := PackageBuildResponse{
-		ArtifactFilename: pkgFilename,
-		BuildLogs:        buildLogs,
+// cacheEntry returns the dependency cache entry for the source package,
+// or nil if the cache is disabled or unusable for this build.
+func (builder *Builder) cacheEntry(srcPkgPath string) *cacheEntry {
+	if builder.cache == nil {
+		return nil
+	}
+	srcDir := srcPkgPath
+	if fi, err := os.Stat(srcPkgPath); err == nil && !fi.IsDir() {
+		srcDir = path.Dir(srcPkgPath)
+	}
+	entry, err := builder.cache.entry(srcDir)
+	if err != nil {
+		builder.logger.Error("error preparing dependency cache, building without it", zap.Error(err))
+		return nil
 	}
+	if entry != nil {
+		builder.logger.Info("using dependency cache", zap.String("key", entry.key), zap.Bool("hit", entry.hit))
+	}
+	return entry
+}
 
+func (builder *Builder) reply(w http.ResponseWriter, resp PackageBuildResponse, statusCode int) {
 	rBody, err := json.Marshal(resp)
 	if err != nil {
 		e := errors.Wrap(err, "error encoding response body")
@@ -164,8 +300,11 @@ func (builder *Builder) reply(w http.ResponseWriter, pkgFilename string, buildLo
 	}
 }
 
-func (builder *Builder) build(command string, srcPkgPath string, deployPkgPath string) (string, error) {
+func (builder *Builder) build(ctx context.Context, command string, srcPkgPath string, deployPkgPath string, extraEnv []string, log *buildLog) (string, error) {
 	cmd := exec.Command(command)
+	// run the build in its own process group, so that the processes
+	// started by the build command are killed along with it.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	fi, err := os.Stat(srcPkgPath)
 	if err != nil {
@@ -182,6 +321,7 @@ func (builder *Builder) build(command string, srcPkgPath string, deployPkgPath s
 		fmt.Sprintf("%v=%v", envSrcPkg, srcPkgPath),
 		fmt.Sprintf("%v=%v", envDeployPkg, deployPkgPath),
 	)
+	cmd.Env = append(cmd.Env, extraEnv...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -208,10 +348,25 @@ func (builder *Builder) build(command string, srcPkgPath string, deployPkgPath s
 		return "", errors.Wrap(err, "error starting cmd")
 	}
 
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// a negative pid signals the whole process group
+			err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			if err != nil {
+				builder.logger.Error("error killing build command", zap.Error(err))
+			}
+		case <-exited:
+		}
+	}()
+
 	// Runtime logs
 	for scanner.Scan() {
 		output := scanner.Text()
 		fmt.Println(output)
+		log.append(output)
 		buildLogs += fmt.Sprintf("%v\n", output)
 	}
 
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// slowBuild writes a build command that never finishes on its own and
+// leaves a background process holding its output open.
+func slowBuild(t *testing.T, dir string) string {
+	t.Helper()
+	err := os.Mkdir(filepath.Join(dir, "src-pkg"), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := filepath.Join(dir, "build.sh")
+	err = ioutil.WriteFile(command, []byte("#!/bin/sh\necho started\nsleep 60 &\nsleep 60\n"), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return command
+}
+
+func runBuild(t *testing.T, builder *Builder, req PackageBuildRequest) (PackageBuildResponse, int) {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	builder.Handler(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	var resp PackageBuildResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, w.Code
+}
+
+func TestBuildTimeout(t *testing.T) {
+	dir := t.TempDir()
+	builder := MakeBuilder(zap.NewNop(), dir)
+
+	start := time.Now()
+	resp, code := runBuild(t, builder, PackageBuildRequest{
+		SrcPkgFilename: "src-pkg",
+		BuildCommand:   slowBuild(t, dir),
+		Timeout:        1,
+	})
+	if code != http.StatusInternalServerError {
+		t.Errorf("build status = %v, want %v", code, http.StatusInternalServerError)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("build took %v, the build command wasn't killed", elapsed)
+	}
+	if !strings.Contains(resp.BuildLogs, "started\n") || !strings.Contains(resp.BuildLogs, "build timed out after 1s") {
+		t.Errorf("build logs = %q, want the output and the timeout", resp.BuildLogs)
+	}
+}
+
+func TestCancelHandler(t *testing.T) {
+	dir := t.TempDir()
+	builder := MakeBuilder(zap.NewNop(), dir)
+	command := slowBuild(t, dir)
+
+	w := httptest.NewRecorder()
+	builder.CancelHandler(w, httptest.NewRequest(http.MethodPost, "/cancel?id=src-pkg", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("CancelHandler() status without build = %v, want %v", w.Code, http.StatusNotFound)
+	}
+
+	go func() {
+		for builder.logs.get("src-pkg") == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		w := httptest.NewRecorder()
+		builder.CancelHandler(w, httptest.NewRequest(http.MethodPost, "/cancel?id=src-pkg", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("CancelHandler() status = %v, want %v", w.Code, http.StatusOK)
+		}
+	}()
+
+	resp, code := runBuild(t, builder, PackageBuildRequest{
+		SrcPkgFilename: "src-pkg",
+		BuildCommand:   command,
+	})
+	if code != http.StatusInternalServerError {
+		t.Errorf("build status = %v, want %v", code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(resp.BuildLogs, "build cancelled") {
+		t.Errorf("build logs = %q, want the cancellation", resp.BuildLogs)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+type (
+	// buildLog keeps the output of a running build so that it can be
+	// followed while the build is in progress.
+	buildLog struct {
+		sync.Mutex
+		lines []string
+		done  bool
+		// changed is closed and replaced whenever lines are
+		// appended or the build finishes.
+		changed chan struct{}
+		// cancel kills the build.
+		cancel context.CancelFunc
+	}
+
+	// buildLogs are the logs of the running builds, keyed on
+	// the source package filename of the build request.
+	buildLogs struct {
+		sync.Mutex
+		logs map[string]*buildLog
+	}
+)
+
+func makeBuildLogs() *buildLogs {
+	return &buildLogs{
+		logs: make(map[string]*buildLog),
+	}
+}
+
+func (bl *buildLogs) start(id string, cancel context.CancelFunc) *buildLog {
+	bl.Lock()
+	defer bl.Unlock()
+	log := &buildLog{changed: make(chan struct{}), cancel: cancel}
+	bl.logs[id] = log
+	return log
+}
+
+func (bl *buildLogs) finish(id string) {
+	bl.Lock()
+	log, ok := bl.logs[id]
+	delete(bl.logs, id)
+	bl.Unlock()
+	if ok {
+		log.finish()
+	}
+}
+
+func (bl *buildLogs) get(id string) *buildLog {
+	bl.Lock()
+	defer bl.Unlock()
+	return bl.logs[id]
+}
+
+func (log *buildLog) append(line string) {
+	log.Lock()
+	defer log.Unlock()
+	log.lines = append(log.lines, line)
+	close(log.changed)
+	log.changed = make(chan struct{})
+}
+
+func (log *buildLog) finish() {
+	log.Lock()
+	defer log.Unlock()
+	if log.done {
+		return
+	}
+	log.done = true
+	close(log.changed)
+}
+
+// follow writes the build output to w from the start, and keeps writing
+// new lines until the build finishes or ctx is done.
+func (log *buildLog) follow(ctx context.Context, w io.Writer) error {
+	flusher, _ := w.(http.Flusher)
+	next := 0
+	for {
+		log.Lock()
+		lines := log.lines[next:]
+		done := log.done
+		changed := log.changed
+		log.Unlock()
+
+		for _, line := range lines {
+			_, err := io.WriteString(w, line+"\n")
+			if err != nil {
+				return err
+			}
+		}
+		next += len(lines)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestLogsHandler(t *testing.T) {
+	builder := MakeBuilder(zap.NewNop(), t.TempDir())
+	log := builder.logs.start("src-pkg", func() {})
+	log.append("installing dependencies")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		log.append("done")
+		builder.logs.finish("src-pkg")
+	}()
+
+	w := httptest.NewRecorder()
+	builder.LogsHandler(w, httptest.NewRequest(http.MethodGet, "/logs?id=src-pkg", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("LogsHandler() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if want := "installing dependencies\ndone\n"; w.Body.String() != want {
+		t.Errorf("LogsHandler() body = %q, want %q", w.Body.String(), want)
+	}
+
+	w = httptest.NewRecorder()
+	builder.LogsHandler(w, httptest.NewRequest(http.MethodGet, "/logs?id=src-pkg", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("LogsHandler() status of finished build = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
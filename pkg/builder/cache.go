@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCacheMaxEntries = 5
+
+	// cacheCompleteMarker is written to a cache entry once a build using it
+	// succeeded, so that entries left behind by failed builds are not
+	// reported as hits.
+	cacheCompleteMarker = ".fission-cache-complete"
+)
+
+// lockfiles are the dependency lockfiles and manifests the cache key
+// is computed from.
+var lockfiles = []string{
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"npm-shrinkwrap.json",
+	"requirements.txt",
+	"Pipfile.lock",
+	"poetry.lock",
+	"go.sum",
+	"Gemfile.lock",
+	"composer.lock",
+	"pom.xml",
+	"build.gradle",
+	"Cargo.lock",
+	"packages.lock.json",
+}
+
+// toolCacheEnvs are the cache locations of common package managers,
+// relative to the cache entry. They are only set if the builder image
+// doesn't set them already.
+var toolCacheEnvs = map[string]string{
+	"npm_config_cache":   "npm",
+	"YARN_CACHE_FOLDER":  "yarn",
+	"PIP_CACHE_DIR":      "pip",
+	"GOMODCACHE":         "gomod",
+	"COMPOSER_CACHE_DIR": "composer",
+}
+
+type (
+	// dependencyCache keeps a directory per lockfile hash on the
+	// dependency cache volume of the builder pod.
+	dependencyCache struct {
+		dir        string
+		maxEntries int
+
+		lock sync.Mutex
+		// inUse counts the running builds using each entry by key,
+		// they are not evicted meanwhile
+		inUse map[string]int
+	}
+
+	// cacheEntry is the cache directory of a single build.
+	cacheEntry struct {
+		key string
+		dir string
+		hit bool
+	}
+)
+
+func makeDependencyCache(dir string, maxEntries int) *dependencyCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+	return &dependencyCache{
+		dir:        dir,
+		maxEntries: maxEntries,
+		inUse:      make(map[string]int),
+	}
+}
+
+// cacheKey returns the hash of the lockfiles in srcDir, or an empty string
+// if there are none.
+func cacheKey(srcDir string) (string, error) {
+	hasher := sha256.New()
+	found := false
+	for _, name := range lockfiles {
+		data, err := ioutil.ReadFile(filepath.Join(srcDir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", errors.Wrapf(err, "error reading lockfile %v", name)
+		}
+		found = true
+		hasher.Write([]byte(name))
+		hasher.Write([]byte{0})
+		hasher.Write(data)
+		hasher.Write([]byte{0})
+	}
+	if !found {
+		return "", nil
+	}
+	// a shortened hash keeps paths readable and is plenty to tell
+	// the few entries kept in the cache apart.
+	return hex.EncodeToString(hasher.Sum(nil))[:16], nil
+}
+
+// entry returns the cache entry for the lockfiles in srcDir, creating its
+// directory if needed. It returns nil if there are no lockfiles. The
+// entry is in use until it's released.
+func (cache *dependencyCache) entry(srcDir string) (*cacheEntry, error) {
+	key, err := cacheKey(srcDir)
+	if err != nil || len(key) == 0 {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry := &cacheEntry{
+		key: key,
+		dir: filepath.Join(cache.dir, key),
+	}
+	if _, err := os.Stat(filepath.Join(entry.dir, cacheCompleteMarker)); err == nil {
+		entry.hit = true
+	}
+
+	err = os.MkdirAll(entry.dir, os.ModeDir|0750)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating dependency cache directory")
+	}
+	// the modification time of the entry directory tracks its last use
+	now := time.Now()
+	err = os.Chtimes(entry.dir, now, now)
+	if err != nil {
+		return nil, errors.Wrap(err, "error updating dependency cache directory")
+	}
+	cache.inUse[key]++
+	return entry, nil
+}
+
+// release records that a build is done with an entry.
+func (cache *dependencyCache) release(entry *cacheEntry) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.inUse[entry.key]--
+	if cache.inUse[entry.key] <= 0 {
+		delete(cache.inUse, entry.key)
+	}
+}
+
+// env returns the environment variables telling the build command
+// where the cache is.
+func (entry *cacheEntry) env() []string {
+	env := []string{
+		envCacheDir + "=" + entry.dir,
+		envCacheKey + "=" + entry.key,
+	}
+	if entry.hit {
+		env = append(env, envCacheHit+"=true")
+	} else {
+		env = append(env, envCacheHit+"=false")
+	}
+	for name, dir := range toolCacheEnvs {
+		if _, ok := os.LookupEnv(name); !ok {
+			env = append(env, name+"="+filepath.Join(entry.dir, dir))
+		}
+	}
+	return env
+}
+
+// complete marks the entry as usable by later builds and removes the
+// least recently used entries beyond the configured maximum. Entries in
+// use by other builds are kept until a later build completes.
+func (cache *dependencyCache) complete(entry *cacheEntry) error {
+	err := ioutil.WriteFile(filepath.Join(entry.dir, cacheCompleteMarker), nil, 0640)
+	if err != nil {
+		return errors.Wrap(err, "error marking dependency cache entry complete")
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	infos, err := ioutil.ReadDir(cache.dir)
+	if err != nil {
+		return errors.Wrap(err, "error listing dependency cache entries")
+	}
+	entries := make([]os.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() && info.Name() != entry.key {
+			entries = append(entries, info)
+		}
+	}
+	// the current entry is always kept
+	if len(entries) < cache.maxEntries {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().After(entries[j].ModTime())
+	})
+	for _, info := range entries[cache.maxEntries-1:] {
+		if cache.inUse[info.Name()] > 0 {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(cache.dir, info.Name()))
+		if err != nil {
+			return errors.Wrapf(err, "error removing dependency cache entry %v", info.Name())
+		}
+	}
+	return nil
+}
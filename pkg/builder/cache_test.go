@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDependencyCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "builder-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := makeDependencyCache(filepath.Join(dir, "cache"), 2)
+	src := filepath.Join(dir, "src")
+	err = os.Mkdir(src, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no lockfiles, no cache
+	entry, err := cache.entry(src)
+	if err != nil || entry != nil {
+		t.Fatalf("entry() = %v, %v, want no entry without lockfiles", entry, err)
+	}
+
+	build := func(lockfile string, wantHit bool) *cacheEntry {
+		t.Helper()
+		err := ioutil.WriteFile(filepath.Join(src, "package-lock.json"), []byte(lockfile), 0640)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := cache.entry(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.hit != wantHit {
+			t.Fatalf("entry(%q).hit = %v, want %v", lockfile, entry.hit, wantHit)
+		}
+		err = cache.complete(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache.release(entry)
+		// keep modification times apart for the eviction order
+		time.Sleep(10 * time.Millisecond)
+		return entry
+	}
+
+	first := build("v1", false)
+	build("v1", true)
+	build("v2", false)
+	build("v3", false)
+
+	// only the two most recently used entries are kept
+	if _, err := os.Stat(first.dir); !os.IsNotExist(err) {
+		t.Errorf("least recently used entry %v was not removed", first.key)
+	}
+	build("v2", true)
+	build("v1", false)
+
+	// entries in use by running builds are not removed
+	err = ioutil.WriteFile(filepath.Join(src, "package-lock.json"), []byte("v2"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := cache.entry(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	build("v3", false)
+	build("v4", false)
+	if _, err := os.Stat(running.dir); err != nil {
+		t.Errorf("entry %v in use was removed: %v", running.key, err)
+	}
+	cache.release(running)
+	build("v5", false)
+	if _, err := os.Stat(running.dir); !os.IsNotExist(err) {
+		t.Errorf("released entry %v was not removed", running.key)
+	}
+}
@@ -18,9 +18,12 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -45,7 +48,10 @@ func MakeClient(logger *zap.Logger, builderUrl string) *Client {
 	}
 }
 
-func (c *Client) Build(req *builder.PackageBuildRequest) (*builder.PackageBuildResponse, error) {
+// Build sends a build request to the builder and waits for the build to
+// finish. The build is killed if ctx is done. A failed build returns the
+// build response along with the error.
+func (c *Client) Build(ctx context.Context, req *builder.PackageBuildRequest) (*builder.PackageBuildResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "error marshaling json")
@@ -55,16 +61,24 @@ func (c *Client) Build(req *builder.PackageBuildRequest) (*builder.PackageBuildR
 	var resp *http.Response
 
 	for i := 0; i < maxRetries; i++ {
-		resp, err = http.Post(c.url, "application/json", bytes.NewReader(body))
+		var httpReq *http.Request
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating build request")
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp, err = http.DefaultClient.Do(httpReq)
 
+		// only retry if the request didn't reach the builder, a build
+		// that ran and failed would fail again.
 		if err == nil {
-			if resp.StatusCode == 200 {
+			if !unavailable(resp.StatusCode) {
 				break
 			}
 			err = ferror.MakeErrorFromHTTP(resp)
 		}
 
-		if i < maxRetries-1 {
+		if i < maxRetries-1 && ctx.Err() == nil {
 			time.Sleep(50 * time.Duration(2*i) * time.Millisecond)
 			c.logger.Error("error building package, retrying", zap.Error(err))
 			continue
@@ -84,9 +98,56 @@ func (c *Client) Build(req *builder.PackageBuildRequest) (*builder.PackageBuildR
 	pkgBuildResp := builder.PackageBuildResponse{}
 	err = json.Unmarshal(rBody, &pkgBuildResp)
 	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			resp.Body = ioutil.NopCloser(bytes.NewReader(rBody))
+			return nil, ferror.MakeErrorFromHTTP(resp)
+		}
 		c.logger.Error("error parsing resp body", zap.Error(err))
 		return nil, err
 	}
 
+	resp.Body = ioutil.NopCloser(bytes.NewReader(rBody))
 	return &pkgBuildResp, ferror.MakeErrorFromHTTP(resp)
 }
+
+// unavailable returns true for the responses of proxies in front of
+// a builder that is not reachable yet.
+func unavailable(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// Cancel kills the running build of the given source package. The
+// build request fails with the build logs produced so far. Builders
+// that predate build cancellation respond with a not found error.
+func (c *Client) Cancel(ctx context.Context, srcPkgFilename string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/cancel?id="+url.QueryEscape(srcPkgFilename), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error cancelling build")
+	}
+	defer resp.Body.Close()
+	return ferror.MakeErrorFromHTTP(resp)
+}
+
+// FollowLogs returns the output of the running build of the given source
+// package, which keeps streaming until the build finishes. Builders that
+// predate log streaming respond with a not found error.
+func (c *Client) FollowLogs(ctx context.Context, srcPkgFilename string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/logs?id="+url.QueryEscape(srcPkgFilename), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error following build logs")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ferror.MakeErrorFromHTTP(resp)
+	}
+	return resp.Body, nil
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package buildermgr
 
 import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	k8sInformers "k8s.io/client-go/informers"
@@ -47,6 +52,13 @@ func Start(logger *zap.Logger, storageSvcUrl string, envBuilderNamespace string)
 		return errors.Wrap(err, "error making fetcher config")
 	}
 
+	buildConcurrency, err := strconv.Atoi(os.Getenv("BUILD_CONCURRENCY"))
+	if err != nil {
+		buildConcurrency = defaultBuildConcurrency
+	}
+
+	logRelay := makeBuildLogRelay(bmLogger, fissionClient)
+
 	envWatcher := makeEnvironmentWatcher(bmLogger, fissionClient, kubernetesClient, fetcherConfig, envBuilderNamespace)
 	go envWatcher.watchEnvironments()
 
@@ -55,7 +67,26 @@ func Start(logger *zap.Logger, storageSvcUrl string, envBuilderNamespace string)
 	podInformer := k8sInformerFactory.Core().V1().Pods().Informer()
 	pkgInformer := informerFactory.Core().V1().Packages().Informer()
 	pkgWatcher := makePackageWatcher(bmLogger, fissionClient,
-		kubernetesClient, envBuilderNamespace, storageSvcUrl, &podInformer, &pkgInformer)
+		kubernetesClient, envBuilderNamespace, storageSvcUrl, &podInformer, &pkgInformer, logRelay, buildConcurrency)
+
+	go func() {
+		err := serve(bmLogger, 8000, logRelay, pkgWatcher)
+		bmLogger.Error("buildermgr server exited", zap.Error(err))
+	}()
+
 	pkgWatcher.Run()
 	return nil
 }
+
+// serve starts the buildermgr HTTP server.
+func serve(logger *zap.Logger, port int, logRelay *buildLogRelay, pkgWatcher *packageWatcher) error {
+	r := mux.NewRouter()
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+	r.HandleFunc("/v1/packages/{namespace}/{name}/buildlogs", logRelay.BuildLogsHandler).Methods("GET")
+	r.HandleFunc("/v1/packages/{namespace}/{name}/cancel", pkgWatcher.CancelBuildHandler).Methods("POST")
+
+	logger.Info("server started", zap.Int("port", port))
+	return http.ListenAndServe(fmt.Sprintf(":%v", port), r)
+}
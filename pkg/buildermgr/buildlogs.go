@@ -0,0 +1,210 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildermgr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	builderClient "github.com/fission/fission/pkg/builder/client"
+	"github.com/fission/fission/pkg/crd"
+	ferror "github.com/fission/fission/pkg/error"
+)
+
+type (
+	// runningBuild is a build that has been sent to a builder.
+	runningBuild struct {
+		builder        *builderClient.Client
+		srcPkgFilename string
+		done           chan struct{}
+	}
+
+	// buildLogRelay relays the output of running builds from the
+	// builder pods, so that clients can follow builds in progress.
+	buildLogRelay struct {
+		logger        *zap.Logger
+		fissionClient *crd.FissionClient
+		sync.Mutex
+		builds map[string]*runningBuild
+	}
+)
+
+func makeBuildLogRelay(logger *zap.Logger, fissionClient *crd.FissionClient) *buildLogRelay {
+	return &buildLogRelay{
+		logger:        logger.Named("build_log_relay"),
+		fissionClient: fissionClient,
+		builds:        make(map[string]*runningBuild),
+	}
+}
+
+func buildKey(namespace, name string) string {
+	return fmt.Sprintf("%v/%v", namespace, name)
+}
+
+// started registers a build sent to a builder. The returned function
+// must be called once the builder replied.
+func (relay *buildLogRelay) started(pkg *fv1.Package, builder *builderClient.Client, srcPkgFilename string) func() {
+	key := buildKey(pkg.ObjectMeta.Namespace, pkg.ObjectMeta.Name)
+	build := &runningBuild{
+		builder:        builder,
+		srcPkgFilename: srcPkgFilename,
+		done:           make(chan struct{}),
+	}
+
+	relay.Lock()
+	relay.builds[key] = build
+	relay.Unlock()
+
+	return func() {
+		relay.Lock()
+		if relay.builds[key] == build {
+			delete(relay.builds, key)
+		}
+		relay.Unlock()
+		close(build.done)
+	}
+}
+
+func (relay *buildLogRelay) get(namespace, name string) *runningBuild {
+	relay.Lock()
+	defer relay.Unlock()
+	return relay.builds[buildKey(namespace, name)]
+}
+
+// BuildLogsHandler streams the build logs of a package. The output of a
+// running build is relayed from the builder as it's produced; if there's
+// no build in progress, the build log in the package status is written
+// once the package build is no longer pending.
+func (relay *buildLogRelay) BuildLogsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	name := vars["name"]
+
+	flusher, _ := w.(http.Flusher)
+	headerWritten := false
+	writeHeader := func() {
+		if !headerWritten {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			headerWritten = true
+		}
+	}
+
+	for {
+		if build := relay.get(namespace, name); build != nil {
+			logs, err := relay.followLogs(ctx, build)
+			if err == nil {
+				writeHeader()
+				_, err = io.Copy(flushWriter{w, flusher}, logs)
+				logs.Close()
+				if err != nil {
+					relay.logger.Info("stopped relaying build logs", zap.String("package", buildKey(namespace, name)), zap.Error(err))
+				}
+				return
+			}
+			if !ferror.IsNotFound(err) {
+				relay.logger.Error("error following build logs", zap.Error(err), zap.String("package", buildKey(namespace, name)))
+			}
+			// the builder can't stream its output, wait for
+			// the build to finish and write the build log.
+			select {
+			case <-build.done:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		pkg, err := relay.fissionClient.CoreV1().Packages(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if headerWritten {
+				return
+			}
+			if k8serrors.IsNotFound(err) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeHeader()
+		if pkg.Status.BuildStatus != fv1.BuildStatusPending && pkg.Status.BuildStatus != fv1.BuildStatusRunning {
+			_, err = io.WriteString(w, pkg.Status.BuildLog)
+			if err != nil {
+				relay.logger.Info("error writing build log", zap.Error(err))
+			}
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// followLogs opens the log stream of a running build. The build request
+// may still be on its way to the builder, so a not found error is retried
+// briefly.
+func (relay *buildLogRelay) followLogs(ctx context.Context, build *runningBuild) (io.ReadCloser, error) {
+	var err error
+	for i := 0; i < 10; i++ {
+		var logs io.ReadCloser
+		logs, err = build.builder.FollowLogs(ctx, build.srcPkgFilename)
+		if err == nil || !ferror.IsNotFound(err) {
+			return logs, err
+		}
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-build.done:
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+// flushWriter flushes every write, so that log lines reach the
+// client as soon as they are relayed.
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	return n, err
+}
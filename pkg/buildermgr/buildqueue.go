@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildermgr
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+const defaultBuildConcurrency = 4
+
+type (
+	// queuedBuild is a package build waiting for a free slot on the
+	// builder of its environment.
+	queuedBuild struct {
+		priority int
+		seq      uint64
+		ready    chan struct{}
+	}
+
+	// envBuildQueue holds the builds of a single environment.
+	envBuildQueue struct {
+		concurrency int
+		running     int
+		waiting     []*queuedBuild
+	}
+
+	// buildQueue limits the number of builds running at the same time on
+	// the builder of each environment. Waiting builds start in the order
+	// of their priority, and in the order they were queued otherwise.
+	buildQueue struct {
+		sync.Mutex
+		defaultConcurrency int
+		seq                uint64
+		envs               map[string]*envBuildQueue
+	}
+)
+
+func makeBuildQueue(defaultConcurrency int) *buildQueue {
+	if defaultConcurrency <= 0 {
+		defaultConcurrency = defaultBuildConcurrency
+	}
+	return &buildQueue{
+		defaultConcurrency: defaultConcurrency,
+		envs:               make(map[string]*envBuildQueue),
+	}
+}
+
+// acquire waits for a free build slot on the builder of env. The returned
+// function must be called to free the slot once the build finished. An
+// error is returned if ctx is done before the build could start.
+func (q *buildQueue) acquire(ctx context.Context, env *fv1.Environment, priority int) (func(), error) {
+	key := buildKey(env.ObjectMeta.Namespace, env.ObjectMeta.Name)
+
+	q.Lock()
+	eq, ok := q.envs[key]
+	if !ok {
+		eq = &envBuildQueue{}
+		q.envs[key] = eq
+	}
+	// the latest environment spec wins
+	eq.concurrency = q.defaultConcurrency
+	if env.Spec.Builder.Concurrency > 0 {
+		eq.concurrency = env.Spec.Builder.Concurrency
+	}
+
+	q.seq++
+	build := &queuedBuild{
+		priority: priority,
+		seq:      q.seq,
+		ready:    make(chan struct{}),
+	}
+	eq.waiting = append(eq.waiting, build)
+	sort.SliceStable(eq.waiting, func(i, j int) bool {
+		if eq.waiting[i].priority != eq.waiting[j].priority {
+			return eq.waiting[i].priority > eq.waiting[j].priority
+		}
+		return eq.waiting[i].seq < eq.waiting[j].seq
+	})
+	eq.dispatch()
+	q.Unlock()
+
+	release := func() {
+		q.Lock()
+		defer q.Unlock()
+		eq.running--
+		eq.dispatch()
+	}
+
+	select {
+	case <-build.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	for i, b := range eq.waiting {
+		if b == build {
+			eq.waiting = append(eq.waiting[:i], eq.waiting[i+1:]...)
+			return nil, ctx.Err()
+		}
+	}
+	// the build got its slot meanwhile, hand it over
+	eq.running--
+	eq.dispatch()
+	return nil, ctx.Err()
+}
+
+// length returns the number of builds waiting for the builder of env.
+func (q *buildQueue) length(env *fv1.Environment) int {
+	q.Lock()
+	defer q.Unlock()
+	eq, ok := q.envs[buildKey(env.ObjectMeta.Namespace, env.ObjectMeta.Name)]
+	if !ok {
+		return 0
+	}
+	return len(eq.waiting)
+}
+
+// dispatch starts waiting builds while there are free slots. The
+// queue must be locked.
+func (eq *envBuildQueue) dispatch() {
+	for eq.running < eq.concurrency && len(eq.waiting) > 0 {
+		build := eq.waiting[0]
+		eq.waiting = eq.waiting[1:]
+		eq.running++
+		close(build.ready)
+	}
+}
+
+type (
+	// trackedBuild is a package build handled by the package watcher,
+	// from the moment it's queued until its status is updated.
+	trackedBuild struct {
+		cancel    context.CancelFunc
+		cancelled bool
+	}
+
+	// buildTracker keeps the builds in progress so that they can be
+	// cancelled.
+	buildTracker struct {
+		sync.Mutex
+		builds map[string]map[*trackedBuild]struct{}
+	}
+)
+
+func makeBuildTracker() *buildTracker {
+	return &buildTracker{
+		builds: make(map[string]map[*trackedBuild]struct{}),
+	}
+}
+
+// track registers a build of pkg. The returned context is cancelled when
+// the build is cancelled, and the returned function must be called once
+// the build is over.
+func (tracker *buildTracker) track(ctx context.Context, pkg *fv1.Package) (context.Context, *trackedBuild, func()) {
+	key := buildKey(pkg.ObjectMeta.Namespace, pkg.ObjectMeta.Name)
+	ctx, cancel := context.WithCancel(ctx)
+	build := &trackedBuild{cancel: cancel}
+
+	tracker.Lock()
+	if tracker.builds[key] == nil {
+		tracker.builds[key] = make(map[*trackedBuild]struct{})
+	}
+	tracker.builds[key][build] = struct{}{}
+	tracker.Unlock()
+
+	return ctx, build, func() {
+		tracker.Lock()
+		delete(tracker.builds[key], build)
+		if len(tracker.builds[key]) == 0 {
+			delete(tracker.builds, key)
+		}
+		tracker.Unlock()
+		cancel()
+	}
+}
+
+// markCancelled marks the builds of a package as cancelled and returns
+// them, or nil if the package isn't being built.
+func (tracker *buildTracker) markCancelled(namespace, name string) []*trackedBuild {
+	tracker.Lock()
+	defer tracker.Unlock()
+	var builds []*trackedBuild
+	for build := range tracker.builds[buildKey(namespace, name)] {
+		build.cancelled = true
+		builds = append(builds, build)
+	}
+	return builds
+}
+
+// isCancelled returns true if the build was cancelled.
+func (tracker *buildTracker) isCancelled(build *trackedBuild) bool {
+	tracker.Lock()
+	defer tracker.Unlock()
+	return build.cancelled
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildermgr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestBuildQueue(t *testing.T) {
+	env := &fv1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "default"},
+		Spec: fv1.EnvironmentSpec{
+			Builder: fv1.Builder{Concurrency: 1},
+		},
+	}
+	q := makeBuildQueue(0)
+	ctx := context.Background()
+
+	release, err := q.acquire(ctx, env, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan int, 3)
+	queue := func(priority int) {
+		go func() {
+			release, err := q.acquire(ctx, env, priority)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			started <- priority
+			release()
+		}()
+		// keep the queueing order deterministic
+		for q.length(env) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	queue(0)
+	queue(5)
+
+	// a cancelled build leaves the queue without taking a slot
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancelled := make(chan error)
+	go func() {
+		_, err := q.acquire(cancelCtx, env, 10)
+		cancelled <- err
+	}()
+	for q.length(env) != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("acquire() with cancelled context = %v, want %v", err, context.Canceled)
+	}
+
+	select {
+	case p := <-started:
+		t.Fatalf("build with priority %v started while the builder was busy", p)
+	default:
+	}
+
+	release()
+	for _, want := range []int{5, 0} {
+		select {
+		case p := <-started:
+			if p != want {
+				t.Errorf("started build with priority %v, want %v", p, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("build with priority %v didn't start", want)
+		}
+	}
+}
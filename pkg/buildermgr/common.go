@@ -27,6 +27,7 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/builder"
@@ -35,17 +36,23 @@ import (
 	ferror "github.com/fission/fission/pkg/error"
 	"github.com/fission/fission/pkg/fetcher"
 	fetcherClient "github.com/fission/fission/pkg/fetcher/client"
+	"github.com/fission/fission/pkg/utils"
 )
 
+// buildTimeoutGrace is the time given to the builder to kill a build
+// that timed out and reply before buildermgr gives up on the build.
+const buildTimeoutGrace = 30 * time.Second
+
 // buildPackage helps to build source package into deployment package.
 // Following is the steps buildPackage function takes to complete the whole process.
 // 1. Send fetch request to fetcher to fetch source package.
 // 2. Send build request to builder to start a build.
 // 3. Send upload request to fetcher to upload deployment package.
-// 4. Return upload response and build logs.
+// 4. Sign the deployment package if the environment has a signing key.
+// 5. Return deployment archive and build logs.
 // *. Return build logs and error if any one of steps above failed.
-func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.FissionClient, envBuilderNamespace string,
-	storageSvcUrl string, pkg *fv1.Package) (uploadResp *fetcher.ArchiveUploadResponse, buildLogs string, err error) {
+func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.FissionClient, kubernetesClient kubernetes.Interface, logRelay *buildLogRelay, envBuilderNamespace string,
+	storageSvcUrl string, pkg *fv1.Package) (deployment *fv1.Archive, buildLogs string, err error) {
 
 	env, err := fissionClient.CoreV1().Environments(pkg.Spec.Environment.Namespace).Get(ctx, pkg.Spec.Environment.Name, metav1.GetOptions{})
 	if err != nil {
@@ -84,20 +91,36 @@ func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.Fi
 	pkgBuildReq := &builder.PackageBuildRequest{
 		SrcPkgFilename: srcPkgFilename,
 		BuildCommand:   buildCmd,
+		Timeout:        pkg.Spec.BuildTimeout,
+	}
+
+	// the builder enforces the build timeout itself, the deadline of the
+	// request only kicks in if it doesn't, e.g. for older builder images.
+	buildCtx := ctx
+	if pkg.Spec.BuildTimeout > 0 {
+		var cancel context.CancelFunc
+		buildCtx, cancel = context.WithTimeout(ctx, time.Duration(pkg.Spec.BuildTimeout)*time.Second+buildTimeoutGrace)
+		defer cancel()
 	}
 
 	logger.Info("started building with source package", zap.String("source_package", srcPkgFilename))
-	// send build request to builder
-	buildResp, err := builderC.Build(pkgBuildReq)
+	// send build request to builder, the relay streams its output meanwhile
+	buildDone := logRelay.started(pkg, builderC, srcPkgFilename)
+	buildResp, err := builderC.Build(buildCtx, pkgBuildReq)
+	buildDone()
 	if err != nil {
 		e := fmt.Sprintf("Error building deployment package: %v", err)
+		if ctx.Err() == nil && buildCtx.Err() == context.DeadlineExceeded {
+			e = fmt.Sprintf("Error building deployment package: build timed out after %v", time.Duration(pkg.Spec.BuildTimeout)*time.Second)
+		}
 		var buildLogs string
 		if buildResp != nil {
-			buildLogs = buildResp.BuildLogs
+			buildLogs = cacheStatusLog(buildResp) + buildResp.BuildLogs
 		}
 		buildLogs += fmt.Sprintf("%v\n", e)
 		return nil, buildLogs, ferror.MakeError(http.StatusInternalServerError, e)
 	}
+	buildResp.BuildLogs = cacheStatusLog(buildResp) + buildResp.BuildLogs
 
 	logger.Info("build succeed", zap.String("source_package", srcPkgFilename), zap.String("deployment_package", buildResp.ArtifactFilename))
 
@@ -111,19 +134,65 @@ func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.Fi
 
 	logger.Info("started uploading deployment package", zap.String("deployment_package", buildResp.ArtifactFilename))
 	// ask fetcher to upload the deployment package
-	uploadResp, err = fetcherC.Upload(ctx, uploadReq)
+	uploadResp, err := fetcherC.Upload(ctx, uploadReq)
 	if err != nil {
 		e := fmt.Sprintf("Error uploading deployment package: %v", err)
 		buildResp.BuildLogs += fmt.Sprintf("%v\n", e)
 		return nil, buildResp.BuildLogs, ferror.MakeError(http.StatusInternalServerError, e)
 	}
 
-	return uploadResp, buildResp.BuildLogs, nil
+	deployment = &fv1.Archive{
+		Type:     fv1.ArchiveTypeUrl,
+		URL:      uploadResp.ArchiveDownloadUrl,
+		Checksum: uploadResp.Checksum,
+	}
+	if len(env.Spec.Builder.SigningKeySecret) > 0 {
+		err = signDeployment(ctx, kubernetesClient, env, deployment)
+		if err != nil {
+			e := fmt.Sprintf("Error signing deployment package: %v", err)
+			buildResp.BuildLogs += fmt.Sprintf("%v\n", e)
+			return nil, buildResp.BuildLogs, ferror.MakeError(http.StatusInternalServerError, e)
+		}
+	}
+
+	return deployment, buildResp.BuildLogs, nil
+}
+
+// signDeployment signs a built deployment archive with the signing key of
+// its environment, so that fetcher loads it if the environment only trusts
+// signed packages.
+func signDeployment(ctx context.Context, kubernetesClient kubernetes.Interface, env *fv1.Environment, deployment *fv1.Archive) error {
+	secret, err := kubernetesClient.CoreV1().Secrets(env.ObjectMeta.Namespace).Get(ctx, env.Spec.Builder.SigningKeySecret, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error getting signing key secret")
+	}
+	keyID, key, err := utils.ParseSigningKey(secret.Data)
+	if err != nil {
+		return err
+	}
+	sig, err := utils.SignChecksum(key, keyID, &deployment.Checksum)
+	if err != nil {
+		return err
+	}
+	deployment.Signature = *sig
+	return nil
+}
+
+// cacheStatusLog returns the build log line reporting whether the build
+// used the dependency cache of the builder.
+func cacheStatusLog(buildResp *builder.PackageBuildResponse) string {
+	if len(buildResp.CacheKey) == 0 {
+		return ""
+	}
+	if buildResp.CacheHit {
+		return fmt.Sprintf("Dependency cache hit (key %v)\n", buildResp.CacheKey)
+	}
+	return fmt.Sprintf("Dependency cache miss (key %v)\n", buildResp.CacheKey)
 }
 
 func updatePackage(logger *zap.Logger, fissionClient *crd.FissionClient,
 	pkg *fv1.Package, status fv1.BuildStatus, buildLogs string,
-	uploadResp *fetcher.ArchiveUploadResponse) (*fv1.Package, error) {
+	deployment *fv1.Archive) (*fv1.Package, error) {
 
 	pkg.Status = fv1.PackageStatus{
 		BuildStatus:         status,
@@ -131,12 +200,8 @@ func updatePackage(logger *zap.Logger, fissionClient *crd.FissionClient,
 		LastUpdateTimestamp: metav1.Time{Time: time.Now().UTC()},
 	}
 
-	if uploadResp != nil {
-		pkg.Spec.Deployment = fv1.Archive{
-			Type:     fv1.ArchiveTypeUrl,
-			URL:      uploadResp.ArchiveDownloadUrl,
-			Checksum: uploadResp.Checksum,
-		}
+	if deployment != nil {
+		pkg.Spec.Deployment = *deployment
 	}
 
 	// update package spec
@@ -51,6 +51,9 @@ const (
 	LABEL_ENV_RESOURCEVERSION = "envResourceVersion"
 	LABEL_DEPLOYMENT_OWNER    = "owner"
 	BUILDER_MGR               = "buildermgr"
+
+	dependencyCacheVolume    = "dependency-cache"
+	dependencyCacheMountPath = "/dependency-cache"
 )
 
 var (
@@ -468,7 +471,7 @@ func (envw *environmentWatcher) createBuilderDeployment(env *fv1.Environment, ns
 		podAnnotations["sidecar.istio.io/inject"] = "false"
 	}
 
-	container, err := util.MergeContainer(&apiv1.Container{
+	builderContainer := &apiv1.Container{
 		Name:                   "builder",
 		Image:                  env.Spec.Builder.Image,
 		ImagePullPolicy:        envw.builderImagePullPolicy,
@@ -487,7 +490,43 @@ func (envw *environmentWatcher) createBuilderDeployment(env *fv1.Environment, ns
 				},
 			},
 		},
-	}, env.Spec.Builder.Container)
+	}
+
+	var volumes []apiv1.Volume
+	if cache := env.Spec.Builder.DependencyCache; cache != nil {
+		volume := apiv1.Volume{
+			Name: dependencyCacheVolume,
+			VolumeSource: apiv1.VolumeSource{
+				EmptyDir: &apiv1.EmptyDirVolumeSource{},
+			},
+		}
+		if len(cache.ClaimName) > 0 {
+			volume.VolumeSource = apiv1.VolumeSource{
+				PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+					ClaimName: cache.ClaimName,
+				},
+			}
+		}
+		volumes = append(volumes, volume)
+
+		// cache entries are kept in a directory per environment, so that
+		// environments can share a persistent volume claim.
+		builderContainer.VolumeMounts = append(builderContainer.VolumeMounts, apiv1.VolumeMount{
+			Name:      dependencyCacheVolume,
+			MountPath: dependencyCacheMountPath,
+		})
+		builderContainer.Env = append(builderContainer.Env,
+			apiv1.EnvVar{
+				Name:  "BUILDER_CACHE_DIR",
+				Value: fmt.Sprintf("%v/%v-%v", dependencyCacheMountPath, env.ObjectMeta.Namespace, env.ObjectMeta.Name),
+			},
+			apiv1.EnvVar{
+				Name:  "BUILDER_CACHE_MAX_ENTRIES",
+				Value: strconv.Itoa(cache.MaxEntries),
+			})
+	}
+
+	container, err := util.MergeContainer(builderContainer, env.Spec.Builder.Container)
 	if err != nil {
 		return nil, err
 	}
@@ -499,6 +538,7 @@ func (envw *environmentWatcher) createBuilderDeployment(env *fv1.Environment, ns
 		},
 		Spec: apiv1.PodSpec{
 			Containers:         []apiv1.Container{*container},
+			Volumes:            volumes,
 			ServiceAccountName: "fission-builder",
 		},
 	}
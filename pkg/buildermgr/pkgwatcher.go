@@ -19,8 +19,10 @@ package buildermgr
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	apiv1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -44,12 +46,15 @@ type (
 		builderNamespace string
 		storageSvcUrl    string
 		buildCache       *cache.Cache
+		logRelay         *buildLogRelay
+		buildQueue       *buildQueue
+		builds           *buildTracker
 	}
 )
 
 func makePackageWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, k8sClientSet *kubernetes.Clientset,
 	builderNamespace string, storageSvcUrl string, podInformer *k8sCache.SharedIndexInformer,
-	pkgInformer *k8sCache.SharedIndexInformer) *packageWatcher {
+	pkgInformer *k8sCache.SharedIndexInformer, logRelay *buildLogRelay, buildConcurrency int) *packageWatcher {
 	pkgw := &packageWatcher{
 		logger:           logger.Named("package_watcher"),
 		fissionClient:    fissionClient,
@@ -59,6 +64,9 @@ func makePackageWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, k8
 		builderNamespace: builderNamespace,
 		storageSvcUrl:    storageSvcUrl,
 		buildCache:       cache.MakeCache(0, 0),
+		logRelay:         logRelay,
+		buildQueue:       makeBuildQueue(buildConcurrency),
+		builds:           makeBuildTracker(),
 	}
 	return pkgw
 }
@@ -67,12 +75,13 @@ func makePackageWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, k8
 // dispatches buildPackage to build source package into deployment package.
 // Following is the steps build function takes to complete the whole process.
 // 1. Check package status
-// 2. Update package status to running state
-// 3. Check environment builder pod status
-// 4. Call buildPackage to build package
-// 5. Update package resource in package ref of functions that share the same package
-// 6. Update package status to succeed state
-// *. Update package status to failed state,if any one of steps above failed/time out
+// 2. Wait for a free build slot on the environment builder
+// 3. Update package status to running state
+// 4. Check environment builder pod status
+// 5. Call buildPackage to build package
+// 6. Update package resource in package ref of functions that share the same package
+// 7. Update package status to succeed state
+// *. Update package status to failed state,if any one of steps above failed/time out/was cancelled
 func (pkgw *packageWatcher) build(ctx context.Context, srcpkg *fv1.Package) {
 	// Ignore duplicate build requests
 	key := fmt.Sprintf("%v-%v", srcpkg.ObjectMeta.Name, srcpkg.ObjectMeta.ResourceVersion)
@@ -87,28 +96,35 @@ func (pkgw *packageWatcher) build(ctx context.Context, srcpkg *fv1.Package) {
 		}
 	}()
 
-	pkgw.logger.Info("starting build for package", zap.String("package_name", srcpkg.ObjectMeta.Name), zap.String("resource_version", srcpkg.ObjectMeta.ResourceVersion))
+	ctx, build, buildOver := pkgw.builds.track(ctx, srcpkg)
+	defer buildOver()
 
-	pkg, err := updatePackage(pkgw.logger, pkgw.fissionClient, srcpkg, fv1.BuildStatusRunning, "", nil)
+	env, err := pkgw.fissionClient.CoreV1().Environments(srcpkg.Spec.Environment.Namespace).Get(ctx, srcpkg.Spec.Environment.Name, metav1.GetOptions{})
 	if err != nil {
-		pkgw.logger.Error("error setting package pending state", zap.Error(err))
+		e := fmt.Sprintf("environment does not exist: %q", srcpkg.Spec.Environment.Name)
+		if !k8serrors.IsNotFound(err) {
+			e = fmt.Sprintf("error getting environment %q: %v", srcpkg.Spec.Environment.Name, err)
+		}
+		pkgw.logger.Error(e, zap.String("environment", srcpkg.Spec.Environment.Name))
+		pkgw.markFailed(srcpkg, e)
 		return
 	}
 
-	env, err := pkgw.fissionClient.CoreV1().Environments(pkg.Spec.Environment.Namespace).Get(ctx, pkg.Spec.Environment.Name, metav1.GetOptions{})
-	if k8serrors.IsNotFound(err) {
-		e := "environment does not exist"
-		pkgw.logger.Error(e, zap.String("environment", pkg.Spec.Environment.Name))
-		_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg,
-			fv1.BuildStatusFailed, fmt.Sprintf("%s: %q", e, pkg.Spec.Environment.Name), nil)
-		if er != nil {
-			pkgw.logger.Error(
-				"error updating package",
-				zap.String("package_name", pkg.ObjectMeta.Name),
-				zap.String("resource_version", pkg.ObjectMeta.ResourceVersion),
-				zap.Error(er),
-			)
-		}
+	pkgw.logger.Info("queueing build for package", zap.String("package_name", srcpkg.ObjectMeta.Name),
+		zap.String("resource_version", srcpkg.ObjectMeta.ResourceVersion), zap.Int("queue_length", pkgw.buildQueue.length(env)))
+	release, err := pkgw.buildQueue.acquire(ctx, env, srcpkg.Spec.BuildPriority)
+	if err != nil {
+		pkgw.logger.Info("package build cancelled while queued", zap.String("package_name", srcpkg.ObjectMeta.Name))
+		pkgw.markFailed(srcpkg, "Build cancelled\n")
+		return
+	}
+	defer release()
+
+	pkgw.logger.Info("starting build for package", zap.String("package_name", srcpkg.ObjectMeta.Name), zap.String("resource_version", srcpkg.ObjectMeta.ResourceVersion))
+
+	pkg, err := updatePackage(pkgw.logger, pkgw.fissionClient, srcpkg, fv1.BuildStatusRunning, "", nil)
+	if err != nil {
+		pkgw.logger.Error("error setting package pending state", zap.Error(err))
 		return
 	}
 
@@ -119,6 +135,11 @@ func (pkgw *packageWatcher) build(ctx context.Context, srcpkg *fv1.Package) {
 	//}
 	// Do health check for environment builder pod
 	for healthCheckBackOff.NextExists() {
+		if ctx.Err() != nil {
+			pkgw.markFailed(pkg, "Build cancelled\n")
+			return
+		}
+
 		// Informer store is not able to use label to find the pod,
 		// iterate all available environment builders.
 		items := (*pkgw.podInformer).GetStore().List()
@@ -181,9 +202,12 @@ func (pkgw *packageWatcher) build(ctx context.Context, srcpkg *fv1.Package) {
 					zap.String("package", fmt.Sprintf("%s.%s", pkg.ObjectMeta.Name, pkg.ObjectMeta.Namespace)))
 			}
 
-			uploadResp, buildLogs, err := buildPackage(ctx, pkgw.logger, pkgw.fissionClient, builderNs, pkgw.storageSvcUrl, pkg)
+			deployment, buildLogs, err := buildPackage(ctx, pkgw.logger, pkgw.fissionClient, pkgw.k8sClient, pkgw.logRelay, builderNs, pkgw.storageSvcUrl, pkg)
 			if err != nil {
 				pkgw.logger.Error("error building package", zap.Error(err), zap.String("package_name", pkg.ObjectMeta.Name))
+				if pkgw.builds.isCancelled(build) {
+					buildLogs += "Build cancelled\n"
+				}
 				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
 				if er != nil {
 					pkgw.logger.Error(
@@ -243,7 +267,7 @@ func (pkgw *packageWatcher) build(ctx context.Context, srcpkg *fv1.Package) {
 			}
 
 			_, err = updatePackage(pkgw.logger, pkgw.fissionClient, pkg,
-				fv1.BuildStatusSucceeded, buildLogs, uploadResp)
+				fv1.BuildStatusSucceeded, buildLogs, deployment)
 			if err != nil {
 				pkgw.logger.Error("error updating package info", zap.Error(err), zap.String("package_name", pkg.ObjectMeta.Name))
 				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
@@ -279,6 +303,74 @@ func (pkgw *packageWatcher) build(ctx context.Context, srcpkg *fv1.Package) {
 		zap.String("package", fmt.Sprintf("%s.%s", pkg.ObjectMeta.Name, pkg.ObjectMeta.Namespace)))
 }
 
+// CancelBuildHandler cancels the build of a package, whether it's waiting
+// in the build queue or running on the builder, and marks the package as
+// failed.
+func (pkgw *packageWatcher) CancelBuildHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	name := vars["name"]
+
+	builds := pkgw.builds.markCancelled(namespace, name)
+	if len(builds) == 0 {
+		pkg, err := pkgw.fissionClient.CoreV1().Packages(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if pkg.Status.BuildStatus != fv1.BuildStatusPending && pkg.Status.BuildStatus != fv1.BuildStatusRunning {
+			http.Error(w, fmt.Sprintf("package %v is not being built", name), http.StatusBadRequest)
+			return
+		}
+		// the build was lost, e.g. when buildermgr restarted meanwhile
+		_, err = updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, pkg.Status.BuildLog+"Build cancelled\n", nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	pkgw.logger.Info("cancelling package build", zap.String("package", buildKey(namespace, name)))
+
+	// kill the build command first, so that the build logs produced so
+	// far end up in the package status.
+	if running := pkgw.logRelay.get(namespace, name); running != nil {
+		err := running.builder.Cancel(ctx, running.srcPkgFilename)
+		if err == nil {
+			select {
+			case <-running.done:
+			case <-time.After(10 * time.Second):
+			}
+		} else {
+			pkgw.logger.Info("error cancelling build on the builder, abandoning it", zap.Error(err))
+		}
+	}
+	for _, build := range builds {
+		build.cancel()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// markFailed sets the build status of pkg to failed.
+func (pkgw *packageWatcher) markFailed(pkg *fv1.Package, buildLogs string) {
+	_, err := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
+	if err != nil {
+		pkgw.logger.Error(
+			"error updating package",
+			zap.String("package_name", pkg.ObjectMeta.Name),
+			zap.String("resource_version", pkg.ObjectMeta.ResourceVersion),
+			zap.Error(err),
+		)
+	}
+}
+
 func (pkgw *packageWatcher) packageInformerHandler() k8sCache.ResourceEventHandlerFuncs {
 	processPkg := func(pkg *fv1.Package) {
 		var err error
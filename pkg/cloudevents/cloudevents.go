@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudevents encodes the invocations of triggers as CloudEvents
+// v1.0, in the binary or structured HTTP content mode, so that functions
+// can handle the events of any trigger with a CloudEvents SDK.
+package cloudevents
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+const (
+	// SpecVersion is the version of the CloudEvents specification.
+	SpecVersion = "1.0"
+
+	// StructuredContentType is the content type of events sent in the
+	// structured content mode.
+	StructuredContentType = "application/cloudevents+json; charset=UTF-8"
+)
+
+// Event types of the triggers.
+const (
+	// TypeTimeTrigger is the type of the events of time triggers.
+	TypeTimeTrigger = "io.fission.timetrigger.fired"
+	// TypeMessage is the type of the messages of message queue triggers.
+	TypeMessage = "io.fission.mqtrigger.message"
+	// typeKubeWatchPrefix is followed by the lower case watch event
+	// type, e.g. io.fission.kubewatch.added.
+	typeKubeWatchPrefix = "io.fission.kubewatch."
+)
+
+// Event is a CloudEvent. ID, Source and Type are required, Time is set
+// to the current time if zero.
+type Event struct {
+	ID      string
+	Source  string
+	Type    string
+	Subject string
+	Time    time.Time
+
+	// DataContentType is the content type of Data.
+	DataContentType string
+	Data            []byte
+}
+
+// Source returns the source of the events of a trigger, the API path of
+// the trigger, e.g. /apis/fission.io/v1/namespaces/default/timetriggers/daily.
+func Source(resource string, meta metav1.ObjectMeta) string {
+	return fmt.Sprintf("/apis/%v/namespaces/%v/%v/%v",
+		fv1.SchemeGroupVersion.String(), meta.Namespace, resource, meta.Name)
+}
+
+// KubeWatchType returns the type of the events of a watch event type.
+func KubeWatchType(eventType string) string {
+	return typeKubeWatchPrefix + strings.ToLower(eventType)
+}
+
+// Encode returns the body and headers of a request delivering the event
+// in a content mode. The headers include the Content-Type header.
+func Encode(mode fv1.CloudEventsMode, event Event) ([]byte, map[string]string) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	eventTime := event.Time.UTC().Format(time.RFC3339Nano)
+
+	if mode == fv1.CloudEventsModeStructured {
+		doc := map[string]interface{}{
+			"specversion": SpecVersion,
+			"id":          event.ID,
+			"source":      event.Source,
+			"type":        event.Type,
+			"time":        eventTime,
+		}
+		if len(event.Subject) > 0 {
+			doc["subject"] = event.Subject
+		}
+		if len(event.DataContentType) > 0 {
+			doc["datacontenttype"] = event.DataContentType
+		}
+		if len(event.Data) > 0 {
+			switch {
+			case isJSON(event.DataContentType) && json.Valid(event.Data):
+				doc["data"] = json.RawMessage(event.Data)
+			case isText(event.DataContentType) && utf8.Valid(event.Data):
+				doc["data"] = string(event.Data)
+			default:
+				doc["data_base64"] = base64.StdEncoding.EncodeToString(event.Data)
+			}
+		}
+		// the values can't fail to marshal
+		body, _ := json.Marshal(doc)
+		return body, map[string]string{"Content-Type": StructuredContentType}
+	}
+
+	headers := map[string]string{
+		"ce-specversion": SpecVersion,
+		"ce-id":          event.ID,
+		"ce-source":      event.Source,
+		"ce-type":        event.Type,
+		"ce-time":        eventTime,
+	}
+	if len(event.Subject) > 0 {
+		headers["ce-subject"] = event.Subject
+	}
+	if len(event.DataContentType) > 0 {
+		headers["Content-Type"] = event.DataContentType
+	}
+	return event.Data, headers
+}
+
+// isJSON returns true for the JSON media types, including the
+// structured syntax suffix +json. Data without a content type is JSON
+// as per the JSON event format.
+func isJSON(contentType string) bool {
+	if len(contentType) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isText returns true for the text media types.
+func isText(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.HasPrefix(mediaType, "text/")
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+var testTime = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSource(t *testing.T) {
+	meta := metav1.ObjectMeta{Name: "daily", Namespace: "default"}
+	require.Equal(t, "/apis/fission.io/v1/namespaces/default/timetriggers/daily", Source("timetriggers", meta))
+	require.Equal(t, "io.fission.kubewatch.added", KubeWatchType("ADDED"))
+}
+
+func TestEncodeBinary(t *testing.T) {
+	body, headers := Encode(fv1.CloudEventsModeBinary, Event{
+		ID:              "1",
+		Source:          "/source",
+		Type:            TypeMessage,
+		Subject:         "topic",
+		Time:            testTime,
+		DataContentType: "text/plain",
+		Data:            []byte("hello"),
+	})
+	require.Equal(t, "hello", string(body))
+	require.Equal(t, map[string]string{
+		"ce-specversion": SpecVersion,
+		"ce-id":          "1",
+		"ce-source":      "/source",
+		"ce-type":        TypeMessage,
+		"ce-subject":     "topic",
+		"ce-time":        "2021-06-01T12:00:00Z",
+		"Content-Type":   "text/plain",
+	}, headers)
+
+	// events without data have no content type
+	body, headers = Encode(fv1.CloudEventsModeBinary, Event{ID: "1", Source: "/source", Type: TypeTimeTrigger})
+	require.Empty(t, body)
+	require.NotContains(t, headers, "Content-Type")
+	require.NotContains(t, headers, "ce-subject")
+	require.NotEmpty(t, headers["ce-time"])
+}
+
+func TestEncodeStructured(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		contentType string
+		data        []byte
+		key         string
+		expected    interface{}
+	}{
+		{"json", "application/json", []byte(`{"a":1}`), "data", map[string]interface{}{"a": float64(1)}},
+		{"json suffix", "application/vnd.api+json", []byte(`[1]`), "data", []interface{}{float64(1)}},
+		{"no content type", "", []byte(`"a"`), "data", "a"},
+		{"invalid json", "application/json", []byte("{"), "data_base64", "ew=="},
+		{"text", "text/plain; charset=utf-8", []byte("hello"), "data", "hello"},
+		{"binary", "application/octet-stream", []byte{0xff}, "data_base64", "/w=="},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			body, headers := Encode(fv1.CloudEventsModeStructured, Event{
+				ID:              "1",
+				Source:          "/source",
+				Type:            TypeMessage,
+				Time:            testTime,
+				DataContentType: test.contentType,
+				Data:            test.data,
+			})
+			require.Equal(t, map[string]string{"Content-Type": StructuredContentType}, headers)
+
+			var doc map[string]interface{}
+			require.NoError(t, json.Unmarshal(body, &doc))
+			require.Equal(t, SpecVersion, doc["specversion"])
+			require.Equal(t, "1", doc["id"])
+			require.Equal(t, "/source", doc["source"])
+			require.Equal(t, TypeMessage, doc["type"])
+			require.Equal(t, "2021-06-01T12:00:00Z", doc["time"])
+			require.Equal(t, test.expected, doc[test.key])
+			if len(test.contentType) > 0 {
+				require.Equal(t, test.contentType, doc["datacontenttype"])
+			} else {
+				require.NotContains(t, doc, "datacontenttype")
+			}
+		})
+	}
+}
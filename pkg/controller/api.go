@@ -208,6 +208,8 @@ func (api *API) GetHandler() http.Handler {
 	r.HandleFunc("/v2/packages/{package}", api.PackageApiGet).Methods("GET")
 	r.HandleFunc("/v2/packages/{package}", api.PackageApiUpdate).Methods("PUT")
 	r.HandleFunc("/v2/packages/{package}", api.PackageApiDelete).Methods("DELETE")
+	r.HandleFunc("/v2/packages/{package}/buildlogs", api.PackageApiBuildLogs).Methods("GET")
+	r.HandleFunc("/v2/packages/{package}/cancel", api.PackageApiCancelBuild).Methods("POST")
 
 	r.HandleFunc("/v2/functions", api.FunctionApiList).Methods("GET")
 	r.HandleFunc("/v2/functions", api.FunctionApiCreate).Methods("POST")
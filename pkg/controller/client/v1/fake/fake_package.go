@@ -17,6 +17,8 @@ limitations under the License.
 package fake
 
 import (
+	"io"
+
 	v1 "github.com/fission/fission/pkg/controller/client/v1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,3 +53,11 @@ func (c *FakePackage) Delete(m *metav1.ObjectMeta) error {
 func (c *FakePackage) List(pkgNamespace string) ([]fv1.Package, error) {
 	return nil, nil
 }
+
+func (c *FakePackage) BuildLogs(m *metav1.ObjectMeta) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (c *FakePackage) CancelBuild(m *metav1.ObjectMeta) error {
+	return nil
+}
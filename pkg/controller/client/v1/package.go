@@ -19,12 +19,14 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/fission/fission/pkg/controller/client/rest"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	ferror "github.com/fission/fission/pkg/error"
 )
 
 type (
@@ -38,6 +40,8 @@ type (
 		Update(f *fv1.Package) (*metav1.ObjectMeta, error)
 		Delete(m *metav1.ObjectMeta) error
 		List(pkgNamespace string) ([]fv1.Package, error)
+		BuildLogs(m *metav1.ObjectMeta) (io.ReadCloser, error)
+		CancelBuild(m *metav1.ObjectMeta) error
 	}
 
 	Package struct {
@@ -162,3 +166,33 @@ func (c *Package) List(pkgNamespace string) ([]fv1.Package, error) {
 
 	return funcs, nil
 }
+
+// BuildLogs returns the build logs of the package. If a build is in
+// progress, the returned reader follows its output until it finishes.
+func (c *Package) BuildLogs(m *metav1.ObjectMeta) (io.ReadCloser, error) {
+	relativeUrl := fmt.Sprintf("packages/%v/buildlogs", m.Name)
+	relativeUrl += fmt.Sprintf("?namespace=%v", m.Namespace)
+
+	resp, err := c.client.Get(relativeUrl)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, ferror.MakeErrorFromHTTP(resp)
+	}
+	return resp.Body, nil
+}
+
+// CancelBuild cancels the build of the package, which is then marked
+// as failed.
+func (c *Package) CancelBuild(m *metav1.ObjectMeta) error {
+	relativeUrl := fmt.Sprintf("packages/%v/cancel", m.Name)
+	relativeUrl += fmt.Sprintf("?namespace=%v", m.Namespace)
+
+	resp, err := c.client.Create(relativeUrl, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return ferror.MakeErrorFromHTTP(resp)
+}
@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 
 	"github.com/dustin/go-humanize"
 	"github.com/emicklei/go-restful"
 	restfulspec "github.com/emicklei/go-restful-openapi"
 	"github.com/go-openapi/spec"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
@@ -99,6 +102,30 @@ func RegisterPackageRoute(ws *restful.WebService) {
 			Param(ws.QueryParameter("namespace", "Namespace of package").DataType("string").DefaultValue(metav1.NamespaceAll).Required(false)).
 			Produces(restful.MIME_JSON).
 			Returns(http.StatusOK, "Only HTTP status returned", nil))
+
+	ws.Route(
+		ws.GET("/v2/packages/{package}/buildlogs").
+			Doc("Stream build logs of package").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}).
+			Param(ws.PathParameter("package", "Package name").DataType("string").DefaultValue("").Required(true)).
+			Param(ws.QueryParameter("namespace", "Namespace of package").DataType("string").DefaultValue(metav1.NamespaceAll).Required(false)).
+			Produces("text/plain").
+			Returns(http.StatusOK, "Build output, streamed until the running build finishes", nil))
+
+	ws.Route(
+		ws.POST("/v2/packages/{package}/cancel").
+			Doc("Cancel the build of package").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}).
+			Param(ws.PathParameter("package", "Package name").DataType("string").DefaultValue("").Required(true)).
+			Param(ws.QueryParameter("namespace", "Namespace of package").DataType("string").DefaultValue(metav1.NamespaceAll).Required(false)).
+			Produces(restful.MIME_JSON).
+			Returns(http.StatusOK, "Only HTTP status returned", nil))
 }
 
 func (a *API) PackageApiList(w http.ResponseWriter, r *http.Request) {
@@ -253,3 +280,44 @@ func (a *API) PackageApiDelete(w http.ResponseWriter, r *http.Request) {
 
 	a.respondWithSuccess(w, []byte(""))
 }
+
+// PackageApiBuildLogs streams the build logs of a package from buildermgr,
+// following the build if it's in progress.
+func (a *API) PackageApiBuildLogs(w http.ResponseWriter, r *http.Request) {
+	a.proxyPackageBuild(w, r, "buildlogs")
+}
+
+// PackageApiCancelBuild asks buildermgr to cancel the build of a package.
+func (a *API) PackageApiCancelBuild(w http.ResponseWriter, r *http.Request) {
+	a.proxyPackageBuild(w, r, "cancel")
+}
+
+// proxyPackageBuild proxies a request about the build of a package
+// to buildermgr.
+func (a *API) proxyPackageBuild(w http.ResponseWriter, r *http.Request, action string) {
+	vars := mux.Vars(r)
+	name := vars["package"]
+	ns := a.extractQueryParamFromRequest(r, "namespace")
+	if len(ns) == 0 {
+		ns = metav1.NamespaceDefault
+	}
+
+	u, err := url.Parse(a.builderManagerUrl)
+	if err != nil {
+		a.respondWithError(w, errors.Wrapf(err, "error parsing builder manager url %v", a.builderManagerUrl))
+		return
+	}
+	director := func(req *http.Request) {
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.URL.Path = fmt.Sprintf("/v1/packages/%v/%v/%v", ns, name, action)
+		req.URL.RawQuery = ""
+		req.Host = u.Host
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: director,
+		// flush immediately so that clients see build output as it's produced
+		FlushInterval: -1,
+	}
+	proxy.ServeHTTP(w, r)
+}
@@ -135,12 +135,14 @@ func (a *API) TimeTriggerApiCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// validate
-	_, err = cron.Parse(t.Spec.Cron)
-	if err != nil {
-		err = ferror.MakeError(ferror.ErrorInvalidArgument, "TimeTrigger cron spec is not valid")
-		a.respondWithError(w, err)
-		return
+	// validate, one-shot triggers have no cron spec
+	if t.Spec.At == nil {
+		_, err = cron.Parse(t.Spec.Cron)
+		if err != nil {
+			err = ferror.MakeError(ferror.ErrorInvalidArgument, "TimeTrigger cron spec is not valid")
+			a.respondWithError(w, err)
+			return
+		}
 	}
 
 	// check if namespace exists, if not create it.
@@ -212,11 +214,14 @@ func (a *API) TimeTriggerApiUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = cron.Parse(t.Spec.Cron)
-	if err != nil {
-		err = ferror.MakeError(ferror.ErrorInvalidArgument, "TimeTrigger cron spec is not valid")
-		a.respondWithError(w, err)
-		return
+	// one-shot triggers have no cron spec
+	if t.Spec.At == nil {
+		_, err = cron.Parse(t.Spec.Cron)
+		if err != nil {
+			err = ferror.MakeError(ferror.ErrorInvalidArgument, "TimeTrigger cron spec is not valid")
+			a.respondWithError(w, err)
+			return
+		}
 	}
 
 	tnew, err := a.fissionClient.CoreV1().TimeTriggers(t.ObjectMeta.Namespace).Update(context.TODO(), &t, metav1.UpdateOptions{})
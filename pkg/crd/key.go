@@ -38,3 +38,10 @@ func CacheKey(metadata *metav1.ObjectMeta) string {
 func CacheKeyUID(metadata *metav1.ObjectMeta) string {
 	return fmt.Sprintf("%v", metadata.UID)
 }
+
+// CacheKeyGeneration creates a key that uniquely identifies the spec of
+// the object. Unlike the resourceVersion, the generation of objects with
+// a status subresource doesn't change on status updates.
+func CacheKeyGeneration(metadata *metav1.ObjectMeta) string {
+	return fmt.Sprintf("%v_%v", metadata.UID, metadata.Generation)
+}
@@ -87,6 +87,8 @@ func (err Error) HTTPStatus() int {
 		code = http.StatusConflict
 	case ErrorTooManyRequests:
 		code = http.StatusTooManyRequests
+	case ErrorSignatureFail:
+		code = http.StatusForbidden
 	default:
 		code = http.StatusInternalServerError
 	}
@@ -136,6 +138,7 @@ const (
 	ErrorSizeLimitExceeded
 	ErrorRequestTimeout
 	ErrorTooManyRequests
+	ErrorSignatureFail
 )
 
 // must match order and len of the above const
@@ -150,4 +153,6 @@ var errorDescriptions = []string{
 	"Checksum verification failed",
 	"Size limit exceeded",
 	"Request time limit exceeded",
+	"Too many requests",
+	"Signature verification failed",
 }
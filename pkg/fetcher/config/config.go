@@ -291,6 +291,10 @@ func (cfg *Config) addFetcherToPodSpecWithCommand(podSpec *apiv1.PodSpec, mainCo
 				Name:  "OTEL_COLLECTOR_ENDPOINT",
 				Value: os.Getenv("OTEL_COLLECTOR_ENDPOINT"),
 			},
+			{
+				Name:  "OCI_PLAIN_HTTP_REGISTRIES",
+				Value: os.Getenv("OCI_PLAIN_HTTP_REGISTRIES"),
+			},
 		},
 	}
 
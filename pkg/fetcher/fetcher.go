@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mholt/archiver"
@@ -51,6 +52,7 @@ import (
 	"github.com/fission/fission/pkg/info"
 	storageSvcClient "github.com/fission/fission/pkg/storagesvc/client"
 	"github.com/fission/fission/pkg/utils"
+	"github.com/fission/fission/pkg/utils/oci"
 )
 
 type (
@@ -62,6 +64,7 @@ type (
 		fissionClient    *crd.FissionClient
 		kubeClient       *kubernetes.Clientset
 		httpClient       *http.Client
+		ociPlainHTTP     []string
 		Info             PodInfo
 	}
 	PodInfo struct {
@@ -127,7 +130,8 @@ func MakeFetcher(logger *zap.Logger, sharedVolumePath string, sharedSecretPath s
 			Name:      string(name),
 			Namespace: string(namespace),
 		},
-		httpClient: hc,
+		httpClient:   hc,
+		ociPlainHTTP: strings.Split(os.Getenv("OCI_PLAIN_HTTP_REGISTRIES"), ","),
 	}, nil
 }
 
@@ -141,6 +145,104 @@ func verifyChecksum(fileChecksum, checksum *fv1.Checksum) error {
 	return nil
 }
 
+// verifySignature checks the archive written to archivePath against the
+// trusted keys of the package's environment. It's a no-op if the environment
+// doesn't set TrustedKeysSecret. The checksum is calculated from the file
+// itself rather than taken from the package, so a tampered archive can't be
+// passed off with a matching checksum.
+func (fetcher *Fetcher) verifySignature(ctx context.Context, pkg *fv1.Package, archive *fv1.Archive, archivePath string) (int, error) {
+	env, err := fetcher.fissionClient.CoreV1().Environments(pkg.Spec.Environment.Namespace).Get(ctx, pkg.Spec.Environment.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return http.StatusNotFound, errors.Wrap(err, "error getting package environment")
+		}
+		return http.StatusInternalServerError, errors.Wrap(err, "error getting package environment")
+	}
+	if len(env.Spec.TrustedKeysSecret) == 0 {
+		return http.StatusOK, nil
+	}
+
+	if !archive.IsSigned() {
+		return http.StatusForbidden, ferror.MakeError(ferror.ErrorSignatureFail,
+			fmt.Sprintf("environment %s.%s requires signed packages but the archive is not signed", env.ObjectMeta.Name, env.ObjectMeta.Namespace))
+	}
+
+	secret, err := fetcher.kubeClient.CoreV1().Secrets(env.ObjectMeta.Namespace).Get(ctx, env.Spec.TrustedKeysSecret, metav1.GetOptions{})
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return http.StatusNotFound, errors.Wrap(err, "trusted keys secret was not found in kubeapi")
+		}
+		return http.StatusInternalServerError, errors.Wrap(err, "error getting trusted keys secret from kubeapi")
+	}
+	keys, err := utils.ParseTrustedKeys(secret.Data)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	checksum, err := utils.GetFileChecksum(archivePath)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "failed to get checksum")
+	}
+	err = utils.VerifyChecksumSignature(keys, checksum, &archive.Signature)
+	if err != nil {
+		return http.StatusForbidden, ferror.MakeError(ferror.ErrorSignatureFail, err.Error())
+	}
+
+	fetcher.logger.Info("verified archive signature",
+		zap.String("package_name", pkg.ObjectMeta.Name),
+		zap.String("package_namespace", pkg.ObjectMeta.Namespace),
+		zap.String("key_id", archive.Signature.KeyID))
+	return http.StatusOK, nil
+}
+
+// pullOCIArchive pulls an archive referenced by oci://registry/repo:tag@digest
+// to archivePath. Digest pinning takes the place of the archive checksum:
+// the manifest must match the pinned digest and the layer the digest in
+// the manifest. Credentials are taken from the ImagePullSecret of the
+// package's environment, if any.
+func (fetcher *Fetcher) pullOCIArchive(ctx context.Context, pkg *fv1.Package, archive *fv1.Archive, archivePath string) (int, error) {
+	ref, err := oci.ParseReference(archive.URL)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if len(ref.Digest) == 0 {
+		return http.StatusBadRequest, errors.New("OCI reference must be pinned to a digest")
+	}
+
+	var credentials oci.Credentials
+	env, err := fetcher.fissionClient.CoreV1().Environments(pkg.Spec.Environment.Namespace).Get(ctx, pkg.Spec.Environment.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return http.StatusNotFound, errors.Wrap(err, "error getting package environment")
+		}
+		return http.StatusInternalServerError, errors.Wrap(err, "error getting package environment")
+	}
+	if len(env.Spec.ImagePullSecret) > 0 {
+		secret, err := fetcher.kubeClient.CoreV1().Secrets(env.ObjectMeta.Namespace).Get(ctx, env.Spec.ImagePullSecret, metav1.GetOptions{})
+		if err != nil {
+			if k8serr.IsNotFound(err) {
+				return http.StatusNotFound, errors.Wrap(err, "image pull secret was not found in kubeapi")
+			}
+			return http.StatusInternalServerError, errors.Wrap(err, "error getting image pull secret from kubeapi")
+		}
+		credentials, err = oci.CredentialsFromDockerConfig(secret.Data[corev1.DockerConfigJsonKey])
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	client := oci.MakeClient(fetcher.httpClient, credentials, fetcher.ociPlainHTTP...)
+	layer, err := client.Pull(ctx, ref, archivePath)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	fetcher.logger.Info("pulled archive from OCI registry",
+		zap.String("reference", archive.URL),
+		zap.String("layer_digest", layer.Digest))
+	return http.StatusOK, nil
+}
+
 func writeSecretOrConfigMap(dataMap map[string][]byte, dirPath string) error {
 	for key, val := range dataMap {
 		writeFilePath := filepath.Join(dirPath, key)
@@ -310,6 +412,14 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 				fetcher.logger.Error(e, zap.Error(err), zap.String("location", tmpPath))
 				return http.StatusInternalServerError, errors.Wrapf(err, "%s %s", e, tmpPath)
 			}
+		} else if archive.Type == fv1.ArchiveTypeOCI {
+			// pull and verify against the pinned digest
+			code, err := fetcher.pullOCIArchive(ctx, pkg, archive, tmpPath)
+			if err != nil {
+				e := "failed to pull archive from OCI registry"
+				fetcher.logger.Error(e, zap.Error(err), zap.String("reference", archive.URL))
+				return code, errors.Wrapf(err, "%s %s", e, archive.URL)
+			}
 		} else {
 			// download and verify
 			err := utils.DownloadUrl(ctx, fetcher.httpClient, archive.URL, tmpPath)
@@ -335,6 +445,17 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 				}
 			}
 		}
+
+		code, err := fetcher.verifySignature(ctx, pkg, archive, tmpPath)
+		if err != nil {
+			e := "failed to verify archive signature"
+			fetcher.logger.Error(e,
+				zap.Error(err),
+				zap.String("package_name", pkg.ObjectMeta.Name),
+				zap.String("package_namespace", pkg.ObjectMeta.Namespace))
+			os.Remove(tmpPath)
+			return code, errors.Wrap(err, e)
+		}
 	}
 
 	if archiver.Zip.Match(tmpPath) && !req.KeepArchive {
@@ -31,9 +31,9 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(createCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.KwFnName},
-		Optional: []flag.Flag{flag.KwName, flag.KwObjType, flag.KwNamespace, flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
-		// TODO: add label selector flag
-		// flag.KwLabelsFlag
+		Optional: []flag.Flag{flag.KwName, flag.KwObjType, flag.KwNamespace, flag.KwLabels, flag.KwFields, flag.KwCloudEvents,
+			flag.KwEventTypes, flag.KwIgnoreStatus, flag.KwDebounce, flag.KwOldObject,
+			flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
 	})
 
 	deleteCmd := &cobra.Command{
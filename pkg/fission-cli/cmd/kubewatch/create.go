@@ -18,10 +18,12 @@ package kubewatch
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
@@ -60,6 +62,20 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 	namespace := input.String(flagkey.KwNamespace)
 	objType := input.String(flagkey.KwObjType)
 
+	var labelSelector map[string]string
+	if input.IsSet(flagkey.KwLabels) {
+		set, err := labels.ConvertSelectorToLabelsMap(input.String(flagkey.KwLabels))
+		if err != nil {
+			return errors.Wrap(err, "error parsing label selector, it must be of the form a=b,c=d")
+		}
+		labelSelector = set
+	}
+
+	var eventTypes []string
+	for _, eventType := range input.StringSlice(flagkey.KwEventTypes) {
+		eventTypes = append(eventTypes, strings.ToUpper(eventType))
+	}
+
 	if input.Bool(flagkey.SpecSave) {
 		specDir := util.GetSpecDir(input)
 		fr, err := spec.ReadSpecs(specDir)
@@ -88,13 +104,19 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 			Namespace: fnNamespace,
 		},
 		Spec: fv1.KubernetesWatchTriggerSpec{
-			Namespace: namespace,
-			Type:      objType,
-			//LabelSelector: labels,
+			Namespace:     namespace,
+			Type:          objType,
+			LabelSelector: labelSelector,
+			FieldSelector: input.String(flagkey.KwFields),
 			FunctionReference: fv1.FunctionReference{
 				Name: fnName,
 				Type: fv1.FunctionReferenceTypeFunctionName,
 			},
+			CloudEvents:         fv1.CloudEventsMode(input.String(flagkey.KwCloudEvents)),
+			EventTypes:          eventTypes,
+			IgnoreStatusChanges: input.Bool(flagkey.KwIgnoreStatus),
+			DebounceSeconds:     int32(input.Duration(flagkey.KwDebounce).Seconds()),
+			IncludeOldObject:    input.Bool(flagkey.KwOldObject),
 		},
 	}
 
@@ -35,7 +35,11 @@ func Commands() *cobra.Command {
 			flag.MqtErrorTopic, flag.MqtMaxRetries, flag.MqtMsgContentType,
 			flag.NamespaceFunction, flag.SpecSave, flag.SpecDry, flag.MqtPollingInterval,
 			flag.MqtCooldownPeriod, flag.MqtMinReplicaCount, flag.MqtMaxReplicaCount, flag.MqtSecret,
-			flag.MqtMetadata, flag.MqtKind},
+			flag.MqtMetadata, flag.MqtKind, flag.MqtRetryBackoff, flag.MqtRetryMaxBackoff,
+			flag.MqtRetryStatusCode, flag.MqtRetryMaxAge, flag.MqtBatchSize, flag.MqtBatchWait,
+			flag.MqtBatchFormat, flag.MqtSchemaType, flag.MqtSchemaConfigMap, flag.MqtSchemaKey,
+			flag.MqtSchemaRegistry, flag.MqtSchemaSubject, flag.MqtSchemaMsgType, flag.MqtSchemaDecode,
+			flag.MqtCloudEvents},
 	})
 
 	updateCmd := &cobra.Command{
@@ -49,7 +53,11 @@ func Commands() *cobra.Command {
 		Optional: []flag.Flag{flag.MqtFnName, flag.MqtTopic, flag.MqtRespTopic, flag.MqtErrorTopic,
 			flag.MqtMaxRetries, flag.MqtMsgContentType, flag.NamespaceTrigger, flag.MqtPollingInterval,
 			flag.MqtCooldownPeriod, flag.MqtMinReplicaCount, flag.MqtMaxReplicaCount, flag.MqtMetadata,
-			flag.MqtSecret, flag.MqtKind},
+			flag.MqtSecret, flag.MqtKind, flag.MqtRetryBackoff, flag.MqtRetryMaxBackoff,
+			flag.MqtRetryStatusCode, flag.MqtRetryMaxAge, flag.MqtBatchSize, flag.MqtBatchWait,
+			flag.MqtBatchFormat, flag.MqtSchemaType, flag.MqtSchemaConfigMap, flag.MqtSchemaKey,
+			flag.MqtSchemaRegistry, flag.MqtSchemaSubject, flag.MqtSchemaMsgType, flag.MqtSchemaDecode,
+			flag.MqtCloudEvents},
 	})
 
 	deleteCmd := &cobra.Command{
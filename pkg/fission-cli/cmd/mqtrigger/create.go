@@ -121,6 +121,21 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 
 	secret := input.String(flagkey.MqtSecret)
 
+	retryPolicy, _, err := retryPolicyFromFlags(input, nil)
+	if err != nil {
+		return err
+	}
+
+	batch, _, err := batchFromFlags(input, nil)
+	if err != nil {
+		return err
+	}
+
+	msgSchema, _, err := schemaFromFlags(input, nil)
+	if err != nil {
+		return err
+	}
+
 	if input.Bool(flagkey.SpecSave) {
 		specDir := util.GetSpecDir(input)
 		fr, err := spec.ReadSpecs(specDir)
@@ -158,6 +173,10 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 			ResponseTopic:    respTopic,
 			ErrorTopic:       errorTopic,
 			MaxRetries:       maxRetries,
+			RetryPolicy:      retryPolicy,
+			Batch:            batch,
+			Schema:           msgSchema,
+			CloudEvents:      fv1.CloudEventsMode(input.String(flagkey.MqtCloudEvents)),
 			ContentType:      contentType,
 			PollingInterval:  &pollingInterval,
 			CooldownPeriod:   &cooldownPeriod,
@@ -205,3 +224,131 @@ func checkMQTopicAvailability(mqType fv1.MessageQueueType, mqtKind string, topic
 	}
 	return nil
 }
+
+// retryPolicyFromFlags returns policy updated with the retry policy
+// flags set, and whether any was set. policy is left unchanged.
+func retryPolicyFromFlags(input cli.Input, policy *fv1.MessageQueueRetryPolicy) (*fv1.MessageQueueRetryPolicy, bool, error) {
+	var updated fv1.MessageQueueRetryPolicy
+	if policy != nil {
+		policy.DeepCopyInto(&updated)
+	}
+
+	set := false
+	if input.IsSet(flagkey.MqtRetryBackoff) {
+		updated.InitialBackoff = input.Int(flagkey.MqtRetryBackoff)
+		set = true
+	}
+	if input.IsSet(flagkey.MqtRetryMaxBackoff) {
+		updated.MaxBackoff = input.Int(flagkey.MqtRetryMaxBackoff)
+		set = true
+	}
+	if input.IsSet(flagkey.MqtRetryStatusCode) {
+		updated.RetryableStatusCodes = input.IntSlice(flagkey.MqtRetryStatusCode)
+		set = true
+	}
+	if input.IsSet(flagkey.MqtRetryMaxAge) {
+		updated.MaxAge = input.Int(flagkey.MqtRetryMaxAge)
+		set = true
+	}
+	if !set {
+		return policy, false, nil
+	}
+
+	err := updated.Validate()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "invalid retry policy")
+	}
+	return &updated, true, nil
+}
+
+// batchFromFlags returns batch updated with the batch flags set, and
+// whether any was set. batch is left unchanged.
+func batchFromFlags(input cli.Input, batch *fv1.MessageQueueBatch) (*fv1.MessageQueueBatch, bool, error) {
+	var updated fv1.MessageQueueBatch
+	if batch != nil {
+		batch.DeepCopyInto(&updated)
+	}
+
+	set := false
+	if input.IsSet(flagkey.MqtBatchSize) {
+		updated.MaxSize = input.Int(flagkey.MqtBatchSize)
+		set = true
+	}
+	if input.IsSet(flagkey.MqtBatchWait) {
+		updated.MaxWait = input.Int(flagkey.MqtBatchWait)
+		set = true
+	}
+	if input.IsSet(flagkey.MqtBatchFormat) {
+		updated.Format = fv1.BatchFormat(input.String(flagkey.MqtBatchFormat))
+		set = true
+	}
+	if !set {
+		return batch, false, nil
+	}
+
+	err := updated.Validate()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "invalid batch")
+	}
+	return &updated, true, nil
+}
+
+// schemaFromFlags returns schema updated with the schema flags set, and
+// whether any was set. schema is left unchanged. Setting the ConfigMap
+// of a schema read from a registry replaces the registry, and the other
+// way round.
+func schemaFromFlags(input cli.Input, schema *fv1.MessageSchema) (*fv1.MessageSchema, bool, error) {
+	var updated fv1.MessageSchema
+	if schema != nil {
+		schema.DeepCopyInto(&updated)
+	}
+
+	set := false
+	if input.IsSet(flagkey.MqtSchemaType) {
+		updated.Type = fv1.MessageSchemaType(input.String(flagkey.MqtSchemaType))
+		set = true
+	}
+	if input.IsSet(flagkey.MqtSchemaConfigMap) || input.IsSet(flagkey.MqtSchemaKey) {
+		if updated.ConfigMap == nil {
+			updated.ConfigMap = &fv1.MessageSchemaConfigMap{}
+		}
+		if input.IsSet(flagkey.MqtSchemaConfigMap) {
+			updated.ConfigMap.Name = input.String(flagkey.MqtSchemaConfigMap)
+		}
+		if input.IsSet(flagkey.MqtSchemaKey) {
+			updated.ConfigMap.Key = input.String(flagkey.MqtSchemaKey)
+		}
+		updated.Registry = nil
+		set = true
+	}
+	if input.IsSet(flagkey.MqtSchemaRegistry) || input.IsSet(flagkey.MqtSchemaSubject) {
+		if updated.Registry == nil {
+			updated.Registry = &fv1.MessageSchemaRegistry{}
+		}
+		if input.IsSet(flagkey.MqtSchemaRegistry) {
+			updated.Registry.URL = input.String(flagkey.MqtSchemaRegistry)
+		}
+		if input.IsSet(flagkey.MqtSchemaSubject) {
+			updated.Registry.Subject = input.String(flagkey.MqtSchemaSubject)
+		}
+		updated.ConfigMap = nil
+		set = true
+	}
+	if input.IsSet(flagkey.MqtSchemaMsgType) {
+		updated.MessageType = input.String(flagkey.MqtSchemaMsgType)
+		set = true
+	}
+	if input.IsSet(flagkey.MqtSchemaDecode) {
+		updated.Decode = input.Bool(flagkey.MqtSchemaDecode)
+		set = true
+	}
+	if !set {
+		return schema, false, nil
+	}
+
+	err := updated.Validate()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "invalid schema")
+	}
+	return &updated, true, nil
+}
@@ -93,7 +93,10 @@ func (opts *UpdateSubCommand) complete(input cli.Input) error {
 		updated = true
 	}
 	if len(fnName) > 0 {
-		mqt.Spec.FunctionReference.Name = fnName
+		mqt.Spec.FunctionReference = fv1.FunctionReference{
+			Type: fv1.FunctionReferenceTypeFunctionName,
+			Name: fnName,
+		}
 		updated = true
 	}
 	if input.IsSet(flagkey.MqtMsgContentType) {
@@ -130,6 +133,38 @@ func (opts *UpdateSubCommand) complete(input cli.Input) error {
 		updated = true
 	}
 
+	retryPolicy, retryPolicySet, err := retryPolicyFromFlags(input, mqt.Spec.RetryPolicy)
+	if err != nil {
+		return err
+	}
+	if retryPolicySet {
+		mqt.Spec.RetryPolicy = retryPolicy
+		updated = true
+	}
+
+	batch, batchSet, err := batchFromFlags(input, mqt.Spec.Batch)
+	if err != nil {
+		return err
+	}
+	if batchSet {
+		mqt.Spec.Batch = batch
+		updated = true
+	}
+
+	msgSchema, schemaSet, err := schemaFromFlags(input, mqt.Spec.Schema)
+	if err != nil {
+		return err
+	}
+	if schemaSet {
+		mqt.Spec.Schema = msgSchema
+		updated = true
+	}
+
+	if input.IsSet(flagkey.MqtCloudEvents) {
+		mqt.Spec.CloudEvents = fv1.CloudEventsMode(input.String(flagkey.MqtCloudEvents))
+		updated = true
+	}
+
 	if !updated {
 		return errors.New("Nothing changed, see 'help' for more details")
 	}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package _package
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+)
+
+type CancelBuildSubCommand struct {
+	cmd.CommandActioner
+	name      string
+	namespace string
+}
+
+func CancelBuild(input cli.Input) error {
+	return (&CancelBuildSubCommand{}).do(input)
+}
+
+func (opts *CancelBuildSubCommand) do(input cli.Input) error {
+	err := opts.complete(input)
+	if err != nil {
+		return err
+	}
+	return opts.run(input)
+}
+
+func (opts *CancelBuildSubCommand) complete(input cli.Input) error {
+	opts.name = input.String(flagkey.PkgName)
+	opts.namespace = input.String(flagkey.NamespacePackage)
+	return nil
+}
+
+func (opts *CancelBuildSubCommand) run(input cli.Input) error {
+	err := opts.Client().V1().Package().CancelBuild(&metav1.ObjectMeta{
+		Name:      opts.name,
+		Namespace: opts.namespace,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error cancelling build of package %v", opts.name)
+	}
+
+	fmt.Printf("Cancelled build of package %v. Use \"fission pkg rebuild --name %v\" to build it again.\n", opts.name, opts.name)
+
+	return nil
+}
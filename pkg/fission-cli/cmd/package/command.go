@@ -32,7 +32,8 @@ func Commands() *cobra.Command {
 	wrapper.SetFlags(createCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.PkgEnvironment},
 		Optional: []flag.Flag{flag.PkgName, flag.PkgCode, flag.PkgSrcArchive, flag.PkgDeployArchive,
-			flag.PkgSrcChecksum, flag.PkgDeployChecksum, flag.PkgInsecure, flag.PkgBuildCmd,
+			flag.PkgSrcChecksum, flag.PkgDeployChecksum, flag.PkgInsecure, flag.PkgSignKey, flag.PkgSignKeyID, flag.PkgBuildCmd,
+			flag.PkgBuildTimeout, flag.PkgBuildPriority,
 			flag.NamespacePackage, flag.NamespaceEnvironment, flag.SpecSave, flag.SpecDry},
 	})
 
@@ -64,8 +65,8 @@ func Commands() *cobra.Command {
 	wrapper.SetFlags(updateCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.PkgName},
 		Optional: []flag.Flag{flag.PkgEnvironment, flag.PkgCode, flag.PkgSrcArchive, flag.PkgDeployArchive,
-			flag.PkgSrcChecksum, flag.PkgDeployChecksum, flag.PkgInsecure, flag.PkgBuildCmd, flag.PkgForce,
-			flag.NamespacePackage, flag.NamespaceEnvironment},
+			flag.PkgSrcChecksum, flag.PkgDeployChecksum, flag.PkgInsecure, flag.PkgSignKey, flag.PkgSignKeyID, flag.PkgBuildCmd, flag.PkgForce,
+			flag.PkgBuildTimeout, flag.PkgBuildPriority, flag.NamespacePackage, flag.NamespaceEnvironment},
 	})
 
 	deleteCmd := &cobra.Command{
@@ -94,7 +95,7 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(infoCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.PkgName},
-		Optional: []flag.Flag{flag.NamespacePackage},
+		Optional: []flag.Flag{flag.NamespacePackage, flag.PkgFollow},
 	})
 
 	rebuildCmd := &cobra.Command{
@@ -107,13 +108,30 @@ func Commands() *cobra.Command {
 		Optional: []flag.Flag{flag.NamespacePackage},
 	})
 
+	cancelBuildCmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel a pending or running package build",
+		Long:  "Cancel a package build waiting in the build queue or running on the environment builder, and mark the package as failed",
+		RunE:  wrapper.Wrapper(CancelBuild),
+	}
+	wrapper.SetFlags(cancelBuildCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.PkgName},
+		Optional: []flag.Flag{flag.NamespacePackage},
+	})
+
+	buildCmd := &cobra.Command{
+		Use:   "build",
+		Short: "Manage package builds",
+	}
+	buildCmd.AddCommand(cancelBuildCmd)
+
 	command := &cobra.Command{
 		Use:     "package",
 		Aliases: []string{"pkg"},
 		Short:   "Create, update and manage packages",
 	}
 
-	command.AddCommand(createCmd, getSrcCmd, getDeployCmd, updateCmd, deleteCmd, listCmd, infoCmd, rebuildCmd)
+	command.AddCommand(createCmd, getSrcCmd, getDeployCmd, updateCmd, deleteCmd, listCmd, infoCmd, rebuildCmd, buildCmd)
 
 	return command
 }
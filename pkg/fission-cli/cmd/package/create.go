@@ -158,6 +158,8 @@ func CreatePackage(input cli.Input, client client.Interface, pkgName string, pkg
 	if len(buildcmd) > 0 {
 		pkgSpec.BuildCommand = buildcmd
 	}
+	pkgSpec.BuildTimeout = input.Int(flagkey.PkgBuildTimeout)
+	pkgSpec.BuildPriority = input.Int(flagkey.PkgBuildPriority)
 
 	if len(pkgName) == 0 {
 		pkgName = strings.ToLower(uuid.NewV4().String())
@@ -80,15 +80,23 @@ func (opts *GetSubCommand) run(input cli.Input) error {
 		archive = pkg.Spec.Deployment
 	}
 
-	if pkg.Spec.Deployment.Type == fv1.ArchiveTypeLiteral {
+	if archive.Type == fv1.ArchiveTypeLiteral {
 		reader = bytes.NewReader(archive.Literal)
-	} else if pkg.Spec.Deployment.Type == fv1.ArchiveTypeUrl {
+	} else if archive.Type == fv1.ArchiveTypeUrl {
 		readCloser, err := pkgutil.DownloadStoragesvcURL(opts.Client(), archive.URL)
 		if err != nil {
 			return err
 		}
 		defer readCloser.Close()
 		reader = readCloser
+	} else if archive.Type == fv1.ArchiveTypeOCI {
+		file, err := pullOCIArchive(archive.URL)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		reader = file
 	}
 
 	if len(opts.output) > 0 {
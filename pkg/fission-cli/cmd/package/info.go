@@ -17,6 +17,7 @@ limitations under the License.
 package _package
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
@@ -32,6 +33,7 @@ type InfoSubCommand struct {
 	cmd.CommandActioner
 	name      string
 	namespace string
+	follow    bool
 }
 
 func Info(input cli.Input) error {
@@ -49,6 +51,7 @@ func (opts *InfoSubCommand) do(input cli.Input) error {
 func (opts *InfoSubCommand) complete(input cli.Input) error {
 	opts.name = input.String(flagkey.PkgName)
 	opts.namespace = input.String(flagkey.NamespacePackage)
+	opts.follow = input.Bool(flagkey.PkgFollow)
 	return nil
 }
 
@@ -60,6 +63,24 @@ func (opts *InfoSubCommand) run(input cli.Input) error {
 	if err != nil {
 		return errors.Wrapf(err, "error finding package %s", opts.name)
 	}
-	pkgutil.PrintPackageSummary(os.Stdout, pkg)
+
+	if !opts.follow || !pkgutil.IsBuilding(pkg) {
+		pkgutil.PrintPackageSummary(os.Stdout, pkg)
+		return nil
+	}
+
+	fmt.Printf("Name:        %v\n", pkg.ObjectMeta.Name)
+	fmt.Printf("Environment: %v\n", pkg.Spec.Environment.Name)
+	fmt.Printf("Build Logs:\n")
+	err = pkgutil.FollowBuildLogs(os.Stdout, opts.Client(), pkg.ObjectMeta, "")
+	if err != nil {
+		return err
+	}
+
+	pkg, err = opts.Client().V1().Package().Get(&pkg.ObjectMeta)
+	if err != nil {
+		return errors.Wrapf(err, "error finding package %s", opts.name)
+	}
+	fmt.Printf("Status:      %v\n", pkg.Status.BuildStatus)
 	return nil
 }
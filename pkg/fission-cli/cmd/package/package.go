@@ -18,7 +18,9 @@ package _package
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -40,6 +42,7 @@ import (
 	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
 	"github.com/fission/fission/pkg/fission-cli/util"
 	"github.com/fission/fission/pkg/utils"
+	"github.com/fission/fission/pkg/utils/oci"
 )
 
 // CreateArchive returns a fv1.Archive made from an archive .  If specFile, then
@@ -57,13 +60,18 @@ func CreateArchive(client client.Interface, input cli.Input, includeFiles []stri
 			return nil, errors.Wrapf(err, "error getting root directory of spec directory")
 		}
 	}
+	signKey, err := loadSignKey(input)
+	if err != nil {
+		return nil, err
+	}
+
 	errs := utils.MultiErrorWithFormat()
 	fileURL := ""
 
 	// check files existence
 	for _, path := range includeFiles {
-		// ignore http files
-		if utils.IsURL(path) {
+		// ignore http files and OCI references
+		if utils.IsURL(path) || oci.IsReference(path) {
 			if len(includeFiles) > 1 {
 				// It's intentional to disallow the user to provide file and URL at the same time.
 				return nil, errors.New("unable to create an archive that contains both file and URL")
@@ -100,8 +108,15 @@ func CreateArchive(client client.Interface, input cli.Input, includeFiles []stri
 		return nil, errs.ErrorOrNil()
 	}
 
+	if oci.IsReference(fileURL) {
+		return ociArchive(fileURL, checksum, signKey)
+	}
+
 	if len(fileURL) > 0 {
 		if insecure {
+			if signKey != nil {
+				return nil, errors.Errorf("--%v requires a checksum of the archive and can't be used with --%v", flagkey.PkgSignKey, flagkey.PkgInsecure)
+			}
 			return &fv1.Archive{
 				Type: fv1.ArchiveTypeUrl,
 				URL:  fileURL,
@@ -136,14 +151,23 @@ func CreateArchive(client client.Interface, input cli.Input, includeFiles []stri
 			}
 		}
 
-		return &fv1.Archive{
+		archive := &fv1.Archive{
 			Type:     fv1.ArchiveTypeUrl,
 			URL:      fileURL,
 			Checksum: *csum,
-		}, nil
+		}
+		err = signArchive(signKey, archive, csum)
+		if err != nil {
+			return nil, err
+		}
+		return archive, nil
 	}
 
 	if input.Bool(flagkey.SpecSave) || input.Bool(flagkey.SpecDry) {
+		if signKey != nil {
+			return nil, errors.Errorf("--%v is not supported when creating spec files", flagkey.PkgSignKey)
+		}
+
 		// create an ArchiveUploadSpec and reference it from the archive
 		aus := &spectypes.ArchiveUploadSpec{
 			Name:         archiveName("", includeFiles),
@@ -190,7 +214,149 @@ func CreateArchive(client client.Interface, input cli.Input, includeFiles []stri
 	}
 
 	ctx := context.Background()
-	return pkgutil.UploadArchiveFile(ctx, client, archivePath)
+	archive, err := pkgutil.UploadArchiveFile(ctx, client, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if signKey != nil {
+		csum, err := utils.GetFileChecksum(archivePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "calculate checksum for file %v", archivePath)
+		}
+		err = signArchive(signKey, archive, csum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return archive, nil
+}
+
+// ociArchive returns an archive referencing an artifact in an OCI registry.
+// Unpinned references are resolved to the current manifest digest, which
+// replaces the checksum of URL archives.
+func ociArchive(reference string, checksum string, signKey *signingKey) (*fv1.Archive, error) {
+	if len(checksum) > 0 {
+		return nil, errors.New("OCI references are verified by their digest and don't take a checksum")
+	}
+	ref, err := oci.ParseReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := dockerCredentials()
+	if err != nil {
+		return nil, err
+	}
+	digest, layer, err := oci.MakeClient(http.DefaultClient, credentials).Resolve(context.Background(), ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(ref.Digest) == 0 {
+		console.Info(fmt.Sprintf("Pinning %v to digest %v", ref, digest))
+	}
+
+	archive := &fv1.Archive{
+		Type: fv1.ArchiveTypeOCI,
+		URL:  ref.Pinned(digest).String(),
+	}
+	// the layer digest is the sha256 checksum of the archive itself
+	err = signArchive(signKey, archive, &fv1.Checksum{
+		Type: fv1.ChecksumTypeSHA256,
+		Sum:  strings.TrimPrefix(layer.Digest, "sha256:"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// pullOCIArchive pulls the archive referenced by an OCI archive URL to a
+// temporary file.
+func pullOCIArchive(reference string) (*os.File, error) {
+	ref, err := oci.ParseReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := dockerCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := utils.GetTempDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(tmpDir, uuid.NewV4().String())
+	_, err = oci.MakeClient(http.DefaultClient, credentials).Pull(context.Background(), ref, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error pulling archive %v", reference)
+	}
+	return os.Open(path)
+}
+
+// dockerCredentials returns the registry credentials stored in the docker
+// config file, e.g. by "docker login", if there is one.
+func dockerCredentials() (oci.Credentials, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "error reading docker config")
+	}
+	return oci.CredentialsFromDockerConfig(data)
+}
+
+// signingKey is the key archives are signed with, and the ID it's trusted
+// under by environments.
+type signingKey struct {
+	id  string
+	key ed25519.PrivateKey
+}
+
+// loadSignKey reads the ed25519 private key given with --sign-key, and its
+// ID given with --sign-key-id. It returns nil if the key isn't set.
+func loadSignKey(input cli.Input) (*signingKey, error) {
+	keyFile := input.String(flagkey.PkgSignKey)
+	if len(keyFile) == 0 {
+		if len(input.String(flagkey.PkgSignKeyID)) > 0 {
+			return nil, errors.Errorf("--%v requires --%v", flagkey.PkgSignKeyID, flagkey.PkgSignKey)
+		}
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading signing key %v", keyFile)
+	}
+	key, err := utils.ParseEd25519PrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error loading signing key %v", keyFile)
+	}
+	return &signingKey{id: input.String(flagkey.PkgSignKeyID), key: key}, nil
+}
+
+// signArchive attaches a detached signature of the archive contents,
+// identified by its checksum, to the archive.
+func signArchive(key *signingKey, archive *fv1.Archive, checksum *fv1.Checksum) error {
+	if key == nil {
+		return nil
+	}
+	sig, err := utils.SignChecksum(key.key, key.id, checksum)
+	if err != nil {
+		return errors.Wrap(err, "error signing archive")
+	}
+	archive.Signature = *sig
+	return nil
 }
 
 // makeArchiveFile creates a zip file from the given list of input files,
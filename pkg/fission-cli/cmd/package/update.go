@@ -131,6 +131,16 @@ func UpdatePackage(input cli.Input, client client.Interface, pkg *fv1.Package) (
 		needToUpdate = true
 	}
 
+	if input.IsSet(flagkey.PkgBuildTimeout) {
+		pkg.Spec.BuildTimeout = input.Int(flagkey.PkgBuildTimeout)
+		needToUpdate = true
+	}
+
+	if input.IsSet(flagkey.PkgBuildPriority) {
+		pkg.Spec.BuildPriority = input.Int(flagkey.PkgBuildPriority)
+		needToUpdate = true
+	}
+
 	if input.IsSet(flagkey.PkgSrcArchive) {
 		srcArchive, err := CreateArchive(client, input, srcArchiveFiles, noZip, insecure, srcChecksum, "", "")
 		if err != nil {
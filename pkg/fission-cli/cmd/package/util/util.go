@@ -16,6 +16,7 @@ limitations under the License.
 package util
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/controller/client"
@@ -187,3 +189,26 @@ func PrintPackageSummary(writer io.Writer, pkg *fv1.Package) {
 	fmt.Fprintf(w, "%v\n%v", "Build Logs:", buildlog)
 	w.Flush()
 }
+
+// IsBuilding returns true if the package build is pending or running.
+func IsBuilding(pkg *fv1.Package) bool {
+	return pkg.Status.BuildStatus == fv1.BuildStatusPending ||
+		pkg.Status.BuildStatus == fv1.BuildStatusRunning
+}
+
+// FollowBuildLogs writes the build logs of a package to writer, following
+// the build until it finishes if it's in progress. Each line is prefixed
+// with prefix.
+func FollowBuildLogs(writer io.Writer, client client.Interface, pkgMeta metav1.ObjectMeta, prefix string) error {
+	logs, err := client.V1().Package().BuildLogs(&pkgMeta)
+	if err != nil {
+		return errors.Wrapf(err, "error getting build logs of package %v", pkgMeta.Name)
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		fmt.Fprintf(writer, "%v%v\n", prefix, scanner.Text())
+	}
+	return errors.Wrapf(scanner.Err(), "error reading build logs of package %v", pkgMeta.Name)
+}
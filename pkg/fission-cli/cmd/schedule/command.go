@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"github.com/spf13/cobra"
+
+	wrapper "github.com/fission/fission/pkg/fission-cli/cliwrapper/driver/cobra"
+	"github.com/fission/fission/pkg/fission-cli/cmd/timetrigger"
+	"github.com/fission/fission/pkg/fission-cli/flag"
+)
+
+// Commands returns the commands managing one-shot invocations, which are
+// time triggers with a single invocation time instead of a cron spec.
+func Commands() *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Schedule a one-shot invocation of a function",
+		RunE:  wrapper.Wrapper(timetrigger.Create),
+	}
+	wrapper.SetFlags(createCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.TtFnName, flag.TtAt},
+		Optional: []flag.Flag{flag.TtName, flag.TtTTL, flag.TtCloudEvents, flag.TtMethod, flag.TtBody,
+			flag.TtContentType, flag.TtHeader, flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
+	})
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete",
+		Aliases: []string{},
+		Short:   "Delete a scheduled invocation",
+		RunE:    wrapper.Wrapper(timetrigger.Delete),
+	}
+	wrapper.SetFlags(deleteCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.TtName},
+		Optional: []flag.Flag{flag.NamespaceTrigger},
+	})
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{},
+		Short:   "List scheduled invocations",
+		Long:    "List all scheduled invocations in a namespace if specified, else, list scheduled invocations across all namespaces",
+		RunE:    wrapper.Wrapper(List),
+	}
+	wrapper.SetFlags(listCmd, flag.FlagSet{
+		Optional: []flag.Flag{flag.NamespaceTrigger},
+	})
+
+	command := &cobra.Command{
+		Use:     "schedule",
+		Aliases: []string{"sched"},
+		Short:   "Schedule and manage one-shot function invocations",
+	}
+
+	command.AddCommand(createCmd, deleteCmd, listCmd)
+
+	return command
+}
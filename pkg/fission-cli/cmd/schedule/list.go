@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+	"github.com/fission/fission/pkg/fission-cli/util"
+)
+
+type ListSubCommand struct {
+	cmd.CommandActioner
+}
+
+func List(input cli.Input) error {
+	return (&ListSubCommand{}).do(input)
+}
+
+func (opts *ListSubCommand) do(input cli.Input) error {
+	ttNs := input.String(flagkey.NamespaceTrigger)
+	tts, err := opts.Client().V1().TimeTrigger().List(ttNs)
+	if err != nil {
+		return errors.Wrap(err, "list scheduled invocations")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", "NAME", "AT", "FUNCTION_NAME", "STATUS")
+	for _, tt := range tts {
+		if tt.Spec.At == nil {
+			continue
+		}
+		status := "Pending"
+		for _, run := range tt.Status.Runs {
+			if !run.ScheduleTime.Equal(tt.Spec.At) {
+				continue
+			}
+			switch {
+			case len(run.Error) > 0:
+				status = fmt.Sprintf("Failed: %v", run.Error)
+			case run.StatusCode > 0 && run.StatusCode < 400:
+				status = fmt.Sprintf("Succeeded (%v)", run.StatusCode)
+			default:
+				status = fmt.Sprintf("Failed (%v)", run.StatusCode)
+			}
+			break
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n",
+			tt.ObjectMeta.Name, tt.Spec.At.Format(time.RFC3339), util.FunctionReferenceSummary(tt.Spec.FunctionReference), status)
+	}
+	w.Flush()
+
+	return nil
+}
@@ -78,7 +78,8 @@ func (opts *ApplySubCommand) run(input cli.Input) error {
 
 	if watchResources || waitForBuild {
 		// init package build watcher
-		pbw = makePackageBuildWatcher(opts.Client())
+		// when watching files, builds are followed as they happen
+		pbw = makePackageBuildWatcher(opts.Client(), watchResources)
 	}
 
 	if watchResources {
@@ -40,14 +40,22 @@ type (
 
 		// set of metadata in the app spec.  packages outside this set should be ignored.
 		pkgMeta map[string]metav1.ObjectMeta
+
+		// if set, the logs of builds in progress are streamed as they're produced
+		followLogs bool
+
+		// set of builds whose logs are being followed
+		following map[string]bool
 	}
 )
 
-func makePackageBuildWatcher(fclient client.Interface) *packageBuildWatcher {
+func makePackageBuildWatcher(fclient client.Interface, followLogs bool) *packageBuildWatcher {
 	return &packageBuildWatcher{
-		fclient:  fclient,
-		finished: make(map[string]bool),
-		pkgMeta:  make(map[string]metav1.ObjectMeta),
+		fclient:    fclient,
+		finished:   make(map[string]bool),
+		pkgMeta:    make(map[string]metav1.ObjectMeta),
+		followLogs: followLogs,
+		following:  make(map[string]bool),
 	}
 }
 
@@ -98,12 +106,22 @@ func (w *packageBuildWatcher) watch(ctx context.Context) {
 			if _, printed := w.finished[k]; printed {
 				continue
 			}
+			if w.followLogs && util.IsBuilding(&pkg) && !w.following[mapKey(&pkg.ObjectMeta)] {
+				w.following[mapKey(&pkg.ObjectMeta)] = true
+				go w.follow(pkg.ObjectMeta)
+			}
 			if pkg.Status.BuildStatus == fv1.BuildStatusFailed ||
 				pkg.Status.BuildStatus == fv1.BuildStatusSucceeded {
 				w.finished[k] = true
-				fmt.Printf("------\n")
-				util.PrintPackageSummary(os.Stdout, &pkg)
-				fmt.Printf("------\n")
+				if w.following[mapKey(&pkg.ObjectMeta)] {
+					// the build logs have been printed already
+					delete(w.following, mapKey(&pkg.ObjectMeta))
+					fmt.Printf("package %v build %v\n", pkg.ObjectMeta.Name, pkg.Status.BuildStatus)
+				} else {
+					fmt.Printf("------\n")
+					util.PrintPackageSummary(os.Stdout, &pkg)
+					fmt.Printf("------\n")
+				}
 			}
 			if pkg.Status.BuildStatus == fv1.BuildStatusFailed {
 				os.Exit(1)
@@ -118,6 +136,15 @@ func (w *packageBuildWatcher) watch(ctx context.Context) {
 	}
 }
 
+// follow prints the build logs of a package, prefixed with its name,
+// until its build finishes.
+func (w *packageBuildWatcher) follow(pkgMeta metav1.ObjectMeta) {
+	err := util.FollowBuildLogs(os.Stdout, w.fclient, pkgMeta, fmt.Sprintf("[%v] ", pkgMeta.Name))
+	if err != nil {
+		fmt.Printf("Following build logs: %v\n", err)
+	}
+}
+
 func pkgKey(pkg *fv1.Package) string {
 	// packages are mutable so we want to keep track of them by resource version
 	return fmt.Sprintf("%v:%v:%v", pkg.ObjectMeta.Name, pkg.ObjectMeta.Namespace, pkg.ObjectMeta.ResourceVersion)
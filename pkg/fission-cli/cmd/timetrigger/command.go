@@ -31,7 +31,8 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(createCmd, flag.FlagSet{
 		Optional: []flag.Flag{flag.TtName, flag.TtFnName,
-			flag.TtCron, flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
+			flag.TtCron, flag.TtAt, flag.TtTTL, flag.TtCloudEvents, flag.TtMethod, flag.TtBody, flag.TtContentType, flag.TtHeader,
+			flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
 	})
 
 	updateCmd := &cobra.Command{
@@ -42,7 +43,8 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(updateCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.TtName},
-		Optional: []flag.Flag{flag.TtFnName, flag.TtCron, flag.NamespaceTrigger},
+		Optional: []flag.Flag{flag.TtFnName, flag.TtCron, flag.TtCloudEvents, flag.TtMethod, flag.TtBody,
+			flag.TtContentType, flag.TtHeader, flag.NamespaceTrigger},
 	})
 
 	deleteCmd := &cobra.Command{
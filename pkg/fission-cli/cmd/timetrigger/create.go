@@ -18,6 +18,7 @@ package timetrigger
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fission/fission/pkg/fission-cli/cmd"
@@ -66,11 +67,34 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 
 	fnNamespace := input.String(flagkey.NamespaceFunction)
 
+	var at *metav1.Time
+	if input.IsSet(flagkey.TtAt) {
+		t, err := parseAt(input.String(flagkey.TtAt), time.Now())
+		if err != nil {
+			return err
+		}
+		at = &metav1.Time{Time: t}
+	}
+
 	cronSpec := input.String(flagkey.TtCron)
-	if len(cronSpec) == 0 {
+	if at != nil && len(cronSpec) > 0 {
+		return errors.New("--cron and --at cannot be used together")
+	}
+	if at == nil && len(cronSpec) == 0 {
 		return errors.New("Need a cron spec like '0 30 * * * *', '@every 1h30m', or '@hourly'; use --cron")
 	}
 
+	var ttl *int64
+	if input.IsSet(flagkey.TtTTL) {
+		seconds := int64(input.Duration(flagkey.TtTTL).Seconds())
+		ttl = &seconds
+	}
+
+	headers, err := headersFromFlags(input)
+	if err != nil {
+		return err
+	}
+
 	if input.Bool(flagkey.SpecSave) {
 		specDir := util.GetSpecDir(input)
 		fr, err := spec.ReadSpecs(specDir)
@@ -99,11 +123,18 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 			Namespace: fnNamespace,
 		},
 		Spec: fv1.TimeTriggerSpec{
-			Cron: cronSpec,
+			Cron:                    cronSpec,
+			At:                      at,
+			TTLSecondsAfterFinished: ttl,
 			FunctionReference: fv1.FunctionReference{
 				Type: fv1.FunctionReferenceTypeFunctionName,
 				Name: fnName,
 			},
+			CloudEvents: fv1.CloudEventsMode(input.String(flagkey.TtCloudEvents)),
+			Method:      strings.ToUpper(input.String(flagkey.TtMethod)),
+			Body:        input.String(flagkey.TtBody),
+			ContentType: input.String(flagkey.TtContentType),
+			Headers:     headers,
 		},
 	}
 
@@ -133,6 +164,11 @@ func (opts *CreateSubCommand) run(input cli.Input) error {
 
 	fmt.Printf("trigger '%v' created\n", opts.trigger.ObjectMeta.Name)
 
+	if opts.trigger.Spec.At != nil {
+		fmt.Printf("Invocation at: \t%v\n", opts.trigger.Spec.At.Format(time.RFC3339))
+		return nil
+	}
+
 	t, err := getAPITimeInfo(opts.Client())
 	if err != nil {
 		return err
@@ -146,6 +182,40 @@ func (opts *CreateSubCommand) run(input cli.Input) error {
 	return nil
 }
 
+// headersFromFlags returns the request headers given with --header, in
+// the "key: value" format.
+func headersFromFlags(input cli.Input) (map[string]string, error) {
+	flags := input.StringSlice(flagkey.TtHeader)
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(flags))
+	for _, header := range flags {
+		keyValue := strings.SplitN(header, ":", 2)
+		if len(keyValue) != 2 || len(strings.TrimSpace(keyValue[0])) == 0 {
+			return nil, errors.Errorf("invalid header '%v', must be in the format 'key: value'", header)
+		}
+		headers[strings.TrimSpace(keyValue[0])] = strings.TrimSpace(keyValue[1])
+	}
+	return headers, nil
+}
+
+// parseAt returns the time of a one-shot invocation given with --at, either
+// an RFC3339 time or a delay relative to now.
+func parseAt(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time '%v', must be an RFC3339 time like '2021-01-02T15:04:05Z' or a delay like '15m'", value)
+	}
+	if delay < 0 {
+		return time.Time{}, errors.Errorf("invalid delay '%v', must not be negative", value)
+	}
+	return now.Add(delay).Truncate(time.Second), nil
+}
+
 func getAPITimeInfo(client client.Interface) (time.Time, error) {
 	serverInfo, err := client.V1().Misc().ServerInfo()
 	if err != nil {
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timetrigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 500, time.UTC)
+
+	at, err := parseAt("2021-06-02T08:30:00Z", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2021, 6, 2, 8, 30, 0, 0, time.UTC), at.UTC())
+
+	at, err = parseAt("15m", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2021, 6, 1, 12, 15, 0, 0, time.UTC), at)
+
+	_, err = parseAt("-15m", now)
+	require.Error(t, err)
+
+	_, err = parseAt("tomorrow", now)
+	require.Error(t, err)
+}
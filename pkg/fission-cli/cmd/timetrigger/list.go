@@ -20,12 +20,14 @@ import (
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
 	"github.com/fission/fission/pkg/fission-cli/cmd"
 	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+	"github.com/fission/fission/pkg/fission-cli/util"
 )
 
 type ListSubCommand struct {
@@ -45,10 +47,17 @@ func (opts *ListSubCommand) do(input cli.Input) error {
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
 
-	fmt.Fprintf(w, "%v\t%v\t%v\n", "NAME", "CRON", "FUNCTION_NAME")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", "NAME", "CRON", "FUNCTION_NAME", "LAST_SCHEDULE", "LAST_SUCCESS")
 	for _, tt := range tts {
-		fmt.Fprintf(w, "%v\t%v\t%v\n",
-			tt.ObjectMeta.Name, tt.Spec.Cron, tt.Spec.FunctionReference.Name)
+		var lastSchedule, lastSuccess string
+		if tt.Status.LastScheduleTime != nil {
+			lastSchedule = tt.Status.LastScheduleTime.Format(time.RFC822)
+		}
+		if tt.Status.LastSuccessfulTime != nil {
+			lastSuccess = tt.Status.LastSuccessfulTime.Format(time.RFC822)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
+			tt.ObjectMeta.Name, tt.Spec.Cron, util.FunctionReferenceSummary(tt.Spec.FunctionReference), lastSchedule, lastSuccess)
 	}
 	w.Flush()
 
@@ -18,6 +18,7 @@ package timetrigger
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -66,12 +67,44 @@ func (opts *UpdateSubCommand) complete(input cli.Input) error {
 
 	fnName := input.String("function")
 	if len(fnName) > 0 {
-		tt.Spec.FunctionReference.Name = fnName
+		tt.Spec.FunctionReference = fv1.FunctionReference{
+			Type: fv1.FunctionReferenceTypeFunctionName,
+			Name: fnName,
+		}
+		updated = true
+	}
+
+	if input.IsSet(flagkey.TtCloudEvents) {
+		tt.Spec.CloudEvents = fv1.CloudEventsMode(input.String(flagkey.TtCloudEvents))
+		updated = true
+	}
+
+	if input.IsSet(flagkey.TtMethod) {
+		tt.Spec.Method = strings.ToUpper(input.String(flagkey.TtMethod))
+		updated = true
+	}
+
+	if input.IsSet(flagkey.TtBody) {
+		tt.Spec.Body = input.String(flagkey.TtBody)
+		updated = true
+	}
+
+	if input.IsSet(flagkey.TtContentType) {
+		tt.Spec.ContentType = input.String(flagkey.TtContentType)
+		updated = true
+	}
+
+	if input.IsSet(flagkey.TtHeader) {
+		// the given headers replace the current ones
+		tt.Spec.Headers, err = headersFromFlags(input)
+		if err != nil {
+			return err
+		}
 		updated = true
 	}
 
 	if !updated {
-		return errors.New("nothing to update. Use --cron or --function")
+		return errors.New("nothing to update. Use --cron, --function, --cloudevents, --method, --body, --contenttype or --header")
 	}
 
 	opts.trigger = tt
@@ -87,6 +120,11 @@ func (opts *UpdateSubCommand) run(input cli.Input) error {
 
 	fmt.Printf("trigger '%v' updated\n", opts.trigger.ObjectMeta.Name)
 
+	// one-shot triggers have no cron spec
+	if opts.trigger.Spec.At != nil {
+		return nil
+	}
+
 	t, err := getAPITimeInfo(opts.Client())
 	if err != nil {
 		return err
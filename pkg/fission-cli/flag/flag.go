@@ -136,14 +136,21 @@ var (
 	HtPrefix            = Flag{Type: String, Name: flagkey.HtPrefix, Usage: "Prefix with which functions are exposed. NOTE: Prefix takes precedence over URL/RelativeURL"}
 	HtKeepPrefix        = Flag{Type: Bool, Name: flagkey.HtKeepPrefix, Usage: "Keep the prefix in the URL while forwarding request to the function"}
 
-	TtName   = Flag{Type: String, Name: flagkey.TtName, Usage: "Time Trigger name"}
-	TtCron   = Flag{Type: String, Name: flagkey.TtCron, Usage: "Time trigger cron spec with each asterisk representing respectively second, minute, hour, the day of the month, month and day of the week. Also supports readable formats like '@every 5m', '@hourly'"}
-	TtFnName = Flag{Type: String, Name: flagkey.TtFnName, Usage: "Function name"}
-	TtRound  = Flag{Type: Int, Name: flagkey.TtRound, Usage: "Get next N rounds of invocation time", DefaultValue: 1}
+	TtName        = Flag{Type: String, Name: flagkey.TtName, Usage: "Time Trigger name"}
+	TtCron        = Flag{Type: String, Name: flagkey.TtCron, Usage: "Time trigger cron spec with each asterisk representing respectively second, minute, hour, the day of the month, month and day of the week. Also supports readable formats like '@every 5m', '@hourly'"}
+	TtFnName      = Flag{Type: String, Name: flagkey.TtFnName, Usage: "Function name"}
+	TtRound       = Flag{Type: Int, Name: flagkey.TtRound, Usage: "Get next N rounds of invocation time", DefaultValue: 1}
+	TtCloudEvents = Flag{Type: String, Name: flagkey.TtCloudEvents, Usage: "Deliver invocations as CloudEvents in the binary or structured content mode"}
+	TtMethod      = Flag{Type: String, Name: flagkey.TtMethod, Usage: "HTTP method of the invocations (POST if unspecified)"}
+	TtBody        = Flag{Type: String, Name: flagkey.TtBody, Short: "b", Usage: "Request body of the invocations"}
+	TtContentType = Flag{Type: String, Name: flagkey.TtContentType, Short: "c", Usage: "Content type of the request body"}
+	TtHeader      = Flag{Type: StringSlice, Name: flagkey.TtHeader, Short: "H", Usage: "Request headers of the invocations: --header 'key1: value1' --header 'key2: value2'"}
+	TtAt          = Flag{Type: String, Name: flagkey.TtAt, Usage: "Time of a one-shot invocation, either in RFC3339 format like '2021-01-02T15:04:05Z' or as a delay from now like '15m'"}
+	TtTTL         = Flag{Type: Duration, Name: flagkey.TtTTL, Usage: "Time to keep a one-shot trigger after its invocation before deleting it (1h if unspecified)"}
 
 	MqtName            = Flag{Type: String, Name: flagkey.MqtName, Usage: "Message queue trigger name"}
 	MqtFnName          = Flag{Type: String, Name: flagkey.MqtFnName, Usage: "Function name"}
-	MqtMQType          = Flag{Type: String, Name: flagkey.MqtMQType, Usage: "Message queue type, e.g. nats-streaming, azure-storage-queue, kafka", DefaultValue: "nats-streaming"}
+	MqtMQType          = Flag{Type: String, Name: flagkey.MqtMQType, Usage: "Message queue type, e.g. nats-streaming, nats-jetstream, azure-storage-queue, kafka, redis-streams, rabbitmq", DefaultValue: "nats-streaming"}
 	MqtTopic           = Flag{Type: String, Name: flagkey.MqtTopic, Usage: "Message queue Topic the trigger listens on"}
 	MqtRespTopic       = Flag{Type: String, Name: flagkey.MqtRespTopic, Usage: "Topic that the function response is sent on (response discarded if unspecified)"}
 	MqtErrorTopic      = Flag{Type: String, Name: flagkey.MqtErrorTopic, Usage: "Topic that the function error messages are sent to (errors discarded if unspecified"}
@@ -156,6 +163,21 @@ var (
 	MqtMetadata        = Flag{Type: StringSlice, Name: flagkey.MqtMetadata, Usage: "Metadata needed for connecting to source system in format: --metadata key1=value1 --metadata key2=value2"}
 	MqtSecret          = Flag{Type: String, Name: flagkey.MqtSecret, Usage: "Name of secret object", DefaultValue: ""}
 	MqtKind            = Flag{Type: String, Name: flagkey.MqtKind, Usage: "Kind of Message Queue Trigger, e.g. fission, keda", DefaultValue: "fission"}
+	MqtRetryBackoff    = Flag{Type: Int, Name: flagkey.MqtRetryBackoff, Usage: "Backoff in milliseconds before the first retry, doubled for each following retry (retries immediately if 0)"}
+	MqtRetryMaxBackoff = Flag{Type: Int, Name: flagkey.MqtRetryMaxBackoff, Usage: "Maximum backoff in milliseconds between retries (unbounded if 0)"}
+	MqtRetryStatusCode = Flag{Type: IntSlice, Name: flagkey.MqtRetryStatusCode, Usage: "Function response status code to retry, all failures are retried if none is given: --retrystatuscode 502 --retrystatuscode 503"}
+	MqtRetryMaxAge     = Flag{Type: Int, Name: flagkey.MqtRetryMaxAge, Usage: "Maximum time in seconds since the first attempt to keep retrying (unbounded if 0)"}
+	MqtBatchSize       = Flag{Type: Int, Name: flagkey.MqtBatchSize, Usage: "Deliver messages to the function in batches of at most this size (Kafka, NATS streaming and Azure storage queue only)"}
+	MqtBatchWait       = Flag{Type: Int, Name: flagkey.MqtBatchWait, Usage: "Maximum time in milliseconds to wait for the messages of a batch (only messages available at once are batched if 0)"}
+	MqtBatchFormat     = Flag{Type: String, Name: flagkey.MqtBatchFormat, Usage: "Body format of a batch: json or multipart"}
+	MqtSchemaType      = Flag{Type: String, Name: flagkey.MqtSchemaType, Usage: "Type of the schema messages are checked against: json, avro or protobuf"}
+	MqtSchemaConfigMap = Flag{Type: String, Name: flagkey.MqtSchemaConfigMap, Usage: "Name of the ConfigMap holding the schema, in the namespace of the trigger"}
+	MqtSchemaKey       = Flag{Type: String, Name: flagkey.MqtSchemaKey, Usage: "Key of the schema in the schema ConfigMap, a serialized FileDescriptorSet for protobuf"}
+	MqtSchemaRegistry  = Flag{Type: String, Name: flagkey.MqtSchemaRegistry, Usage: "URL of a Confluent compatible schema registry to read the schema from"}
+	MqtSchemaSubject   = Flag{Type: String, Name: flagkey.MqtSchemaSubject, Usage: "Registry subject of the schema of messages without a schema ID"}
+	MqtSchemaMsgType   = Flag{Type: String, Name: flagkey.MqtSchemaMsgType, Usage: "Full name of the protobuf message type of messages, e.g. orders.v1.Order"}
+	MqtSchemaDecode    = Flag{Type: Bool, Name: flagkey.MqtSchemaDecode, Usage: "Convert Avro and protobuf messages to JSON before invoking the function"}
+	MqtCloudEvents     = Flag{Type: String, Name: flagkey.MqtCloudEvents, Usage: "Deliver messages as CloudEvents in the binary or structured content mode"}
 
 	EnvName                   = Flag{Type: String, Name: flagkey.EnvName, Usage: "Environment name"}
 	EnvPoolsize               = Flag{Type: Int, Name: flagkey.EnvPoolsize, Usage: "Size of the pool", DefaultValue: 3}
@@ -168,32 +190,43 @@ var (
 	EnvVersion                = Flag{Type: Int, Name: flagkey.EnvVersion, Usage: "Environment API version (1 means v1 interface)", DefaultValue: 1}
 	EnvImagePullSecret        = Flag{Type: String, Name: flagkey.EnvImagePullSecret, Usage: "Secret for Kubernetes to pull an image from a private registry"}
 
-	KwName      = Flag{Type: String, Name: flagkey.KwName, Usage: "Watch name"}
-	KwFnName    = Flag{Type: String, Name: flagkey.KwFnName, Usage: "Function name"}
-	KwNamespace = Flag{Type: String, Name: flagkey.KwNamespace, Aliases: []string{"ns"}, Usage: "Namespace of resource to watch", DefaultValue: metav1.NamespaceDefault}
-	KwObjType   = Flag{Type: String, Name: flagkey.KwObjType, Usage: "Type of resource to watch (Pod, Service, etc.)", DefaultValue: "pod"}
-	KwLabels    = Flag{Type: String, Name: flagkey.KwLabels, Usage: "Label selector of the form a=b,c=d"}
+	KwName         = Flag{Type: String, Name: flagkey.KwName, Usage: "Watch name"}
+	KwFnName       = Flag{Type: String, Name: flagkey.KwFnName, Usage: "Function name"}
+	KwNamespace    = Flag{Type: String, Name: flagkey.KwNamespace, Aliases: []string{"ns"}, Usage: "Namespace of resource to watch", DefaultValue: metav1.NamespaceDefault}
+	KwObjType      = Flag{Type: String, Name: flagkey.KwObjType, Usage: "Type of resource to watch, a resource or a kind optionally qualified by version and group (pod, Service, deployments.apps, functions.v1.fission.io, etc.)", DefaultValue: "pod"}
+	KwLabels       = Flag{Type: String, Name: flagkey.KwLabels, Usage: "Label selector of the form a=b,c=d"}
+	KwFields       = Flag{Type: String, Name: flagkey.KwFields, Usage: "Field selector of the form a=b,c!=d, e.g. status.phase=Running"}
+	KwCloudEvents  = Flag{Type: String, Name: flagkey.KwCloudEvents, Usage: "Deliver events as CloudEvents in the binary or structured content mode"}
+	KwEventTypes   = Flag{Type: StringSlice, Name: flagkey.KwEventTypes, Usage: "Types of the events to invoke the function with, ADDED, MODIFIED or DELETED (all of them if unspecified): --eventtypes added --eventtypes deleted"}
+	KwIgnoreStatus = Flag{Type: Bool, Name: flagkey.KwIgnoreStatus, Usage: "Skip the MODIFIED events changing only the status or resource version of objects"}
+	KwDebounce     = Flag{Type: Duration, Name: flagkey.KwDebounce, Usage: "Coalesce the events of each object over this window, e.g. 10s, and invoke the function once with the net event"}
+	KwOldObject    = Flag{Type: Bool, Name: flagkey.KwOldObject, Usage: "Send MODIFIED events as {\"object\": new, \"oldObject\": old}"}
 
 	PkgName           = Flag{Type: String, Name: flagkey.PkgName, Usage: "Package name"}
 	PkgForce          = Flag{Type: Bool, Name: flagkey.PkgForce, Short: "f", Usage: "Force update a package even if it is used by one or more functions"}
 	PkgEnvironment    = Flag{Type: String, Name: flagkey.PkgEnvironment, Usage: "Environment name"}
 	PkgBuildCmd       = Flag{Type: String, Name: flagkey.PkgBuildCmd, Usage: "Build command for builder to run with"}
+	PkgBuildTimeout   = Flag{Type: Int, Name: flagkey.PkgBuildTimeout, Usage: "Maximum duration of the build in seconds, the build is killed and marked failed once exceeded (0 means no timeout)"}
+	PkgBuildPriority  = Flag{Type: Int, Name: flagkey.PkgBuildPriority, Usage: "Priority of the build among the builds waiting for the environment builder, higher starts first"}
 	PkgOutput         = Flag{Type: String, Name: flagkey.PkgOutput, Short: "o", Usage: "Output filename to save archive content"}
 	PkgStatus         = Flag{Type: String, Name: flagkey.PkgStatus, Usage: `Filter packages by status`}
 	PkgOrphan         = Flag{Type: Bool, Name: flagkey.PkgOrphan, Usage: "Orphan packages that are not referenced by any function"}
 	PkgCode           = Flag{Type: String, Name: flagkey.PkgCode, Usage: "URL or local path for single file source code"}
-	PkgDeployArchive  = Flag{Type: StringSlice, Name: flagkey.PkgDeployArchive, Aliases: []string{"deploy"}, Usage: "URL or local paths for binary archive"}
+	PkgDeployArchive  = Flag{Type: StringSlice, Name: flagkey.PkgDeployArchive, Aliases: []string{"deploy"}, Usage: "URL, oci:// reference or local paths for binary archive"}
 	PkgDeployChecksum = Flag{Type: String, Name: flagkey.PkgDeployChecksum, Usage: "SHA256 checksum of deploy archive when providing URL"}
-	PkgSrcArchive     = Flag{Type: StringSlice, Name: flagkey.PkgSrcArchive, Aliases: []string{"source", "src"}, Usage: "URL or local paths for source archive"}
+	PkgSrcArchive     = Flag{Type: StringSlice, Name: flagkey.PkgSrcArchive, Aliases: []string{"source", "src"}, Usage: "URL, oci:// reference or local paths for source archive"}
 	PkgSrcChecksum    = Flag{Type: String, Name: flagkey.PkgSrcChecksum, Usage: "SHA256 checksum of source archive when providing URL"}
 	PkgInsecure       = Flag{Type: Bool, Name: flagkey.PkgInsecure, Usage: "Skip generating SHA256 checksum for file integrity validation"}
+	PkgSignKey        = Flag{Type: String, Name: flagkey.PkgSignKey, Usage: "Path to a PEM encoded ed25519 private key to sign the source and deploy archives with"}
+	PkgSignKeyID      = Flag{Type: String, Name: flagkey.PkgSignKeyID, Usage: "ID of the signing key, that is its data key in the trusted keys secret of the environment. All trusted keys are tried if empty"}
+	PkgFollow         = Flag{Type: Bool, Name: flagkey.PkgFollow, Short: "f", Usage: "Follow the build logs if the package is being built"}
 
 	SpecSave       = Flag{Type: Bool, Name: flagkey.SpecSave, Usage: "Save to the spec directory instead of creating on cluster"}
 	SpecDir        = Flag{Type: String, Name: flagkey.SpecDir, Usage: "Directory to store specs, defaults to ./specs"}
 	SpecName       = Flag{Type: String, Name: flagkey.SpecName, Usage: "Name for the app, applied to resources as a Kubernetes annotation"}
 	SpecDeployID   = Flag{Type: String, Name: flagkey.SpecDeployID, Aliases: []string{"id"}, Usage: "Deployment ID for the spec deployment config"}
 	SpecWait       = Flag{Type: Bool, Name: flagkey.SpecWait, Usage: "Wait for package builds"}
-	SpecWatch      = Flag{Type: Bool, Name: flagkey.SpecWatch, Usage: "Watch local files for change, and re-apply specs as necessary"}
+	SpecWatch      = Flag{Type: Bool, Name: flagkey.SpecWatch, Usage: "Watch local files for change, and re-apply specs as necessary while following package build logs"}
 	SpecDelete     = Flag{Type: Bool, Name: flagkey.SpecDelete, Usage: "Allow apply to delete resources that no longer exist in the specification"}
 	SpecDry        = Flag{Type: Bool, Name: flagkey.SpecDry, Usage: "View the generated specs"}
 	SpecValidation = Flag{Type: String, Name: flagkey.SpecValidate, Usage: "Turns server side validations of Fission objects on/off"}
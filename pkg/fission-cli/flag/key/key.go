@@ -89,10 +89,17 @@ const (
 	HtPrefix            = "prefix"
 	HtKeepPrefix        = "keepprefix"
 
-	TtName   = resourceName
-	TtCron   = "cron"
-	TtFnName = "function"
-	TtRound  = "round"
+	TtName        = resourceName
+	TtCron        = "cron"
+	TtFnName      = "function"
+	TtRound       = "round"
+	TtCloudEvents = "cloudevents"
+	TtMethod      = "method"
+	TtBody        = "body"
+	TtContentType = "contenttype"
+	TtHeader      = "header"
+	TtAt          = "at"
+	TtTTL         = "ttl"
 
 	MqtName            = resourceName
 	MqtFnName          = "function"
@@ -109,6 +116,21 @@ const (
 	MqtMetadata        = "metadata"
 	MqtSecret          = "secret"
 	MqtKind            = "mqtkind"
+	MqtRetryBackoff    = "retrybackoff"
+	MqtRetryMaxBackoff = "retrymaxbackoff"
+	MqtRetryStatusCode = "retrystatuscode"
+	MqtRetryMaxAge     = "retrymaxage"
+	MqtBatchSize       = "batchsize"
+	MqtBatchWait       = "batchwait"
+	MqtBatchFormat     = "batchformat"
+	MqtSchemaType      = "schematype"
+	MqtSchemaConfigMap = "schemaconfigmap"
+	MqtSchemaKey       = "schemakey"
+	MqtSchemaRegistry  = "schemaregistry"
+	MqtSchemaSubject   = "schemasubject"
+	MqtSchemaMsgType   = "schemamessagetype"
+	MqtSchemaDecode    = "schemadecode"
+	MqtCloudEvents     = "cloudevents"
 
 	EnvName            = resourceName
 	EnvPoolsize        = "poolsize"
@@ -121,11 +143,17 @@ const (
 	EnvVersion         = "version"
 	EnvImagePullSecret = "imagepullsecret"
 
-	KwName      = resourceName
-	KwFnName    = "function"
-	KwNamespace = "namespace"
-	KwObjType   = "type"
-	KwLabels    = "labels"
+	KwName         = resourceName
+	KwFnName       = "function"
+	KwNamespace    = "namespace"
+	KwObjType      = "type"
+	KwLabels       = "labels"
+	KwFields       = "fields"
+	KwCloudEvents  = "cloudevents"
+	KwEventTypes   = "eventtypes"
+	KwIgnoreStatus = "ignorestatus"
+	KwDebounce     = "debounce"
+	KwOldObject    = "oldobject"
 
 	PkgName           = resourceName
 	PkgForce          = force
@@ -136,10 +164,15 @@ const (
 	PkgSrcChecksum    = "srcchecksum"
 	PkgDeployChecksum = "deploychecksum"
 	PkgInsecure       = "insecure"
+	PkgSignKey        = "sign-key"
+	PkgSignKeyID      = "sign-key-id"
 	PkgBuildCmd       = "buildcmd"
+	PkgBuildTimeout   = "buildtimeout"
+	PkgBuildPriority  = "buildpriority"
 	PkgOutput         = Output
 	PkgStatus         = "status"
 	PkgOrphan         = "orphan"
+	PkgFollow         = "follow"
 
 	SpecSave     = "spec"
 	SpecDir      = "specdir"
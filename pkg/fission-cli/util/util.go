@@ -22,6 +22,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -37,6 +38,7 @@ import (
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/controller/client"
 	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
 	"github.com/fission/fission/pkg/fission-cli/console"
@@ -352,6 +354,20 @@ func UrlForFunction(name, namespace string) string {
 	return fmt.Sprintf("%v/%v", prefix, name)
 }
 
+// FunctionReferenceSummary returns the function of a function reference,
+// or its functions with their weights for a function-weights reference.
+func FunctionReferenceSummary(ref fv1.FunctionReference) string {
+	if ref.Type != fv1.FunctionReferenceTypeFunctionWeights {
+		return ref.Name
+	}
+	var functions []string
+	for name, weight := range ref.FunctionWeights {
+		functions = append(functions, fmt.Sprintf("%s:%v", name, weight))
+	}
+	sort.Strings(functions)
+	return strings.Join(functions, " ")
+}
+
 func ParseAnnotations(annotations []string) (map[string]string, error) {
 	var invalidAnnotations string
 	annotationMap := make(map[string]string)
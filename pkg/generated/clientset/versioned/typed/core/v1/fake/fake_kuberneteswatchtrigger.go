@@ -102,6 +102,18 @@ func (c *FakeKubernetesWatchTriggers) Update(ctx context.Context, _kubernetesWat
 	return obj.(*corev1.KubernetesWatchTrigger), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeKubernetesWatchTriggers) UpdateStatus(ctx context.Context, _kubernetesWatchTrigger *corev1.KubernetesWatchTrigger, opts v1.UpdateOptions) (*corev1.KubernetesWatchTrigger, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(kuberneteswatchtriggersResource, "status", c.ns, _kubernetesWatchTrigger), &corev1.KubernetesWatchTrigger{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*corev1.KubernetesWatchTrigger), err
+}
+
 // Delete takes name of the _kubernetesWatchTrigger and deletes it. Returns an error if one occurs.
 func (c *FakeKubernetesWatchTriggers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -102,6 +102,18 @@ func (c *FakeMessageQueueTriggers) Update(ctx context.Context, _messageQueueTrig
 	return obj.(*corev1.MessageQueueTrigger), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeMessageQueueTriggers) UpdateStatus(ctx context.Context, _messageQueueTrigger *corev1.MessageQueueTrigger, opts v1.UpdateOptions) (*corev1.MessageQueueTrigger, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(messagequeuetriggersResource, "status", c.ns, _messageQueueTrigger), &corev1.MessageQueueTrigger{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*corev1.MessageQueueTrigger), err
+}
+
 // Delete takes name of the _messageQueueTrigger and deletes it. Returns an error if one occurs.
 func (c *FakeMessageQueueTriggers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -102,6 +102,18 @@ func (c *FakeTimeTriggers) Update(ctx context.Context, _timeTrigger *corev1.Time
 	return obj.(*corev1.TimeTrigger), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeTimeTriggers) UpdateStatus(ctx context.Context, _timeTrigger *corev1.TimeTrigger, opts v1.UpdateOptions) (*corev1.TimeTrigger, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(timetriggersResource, "status", c.ns, _timeTrigger), &corev1.TimeTrigger{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*corev1.TimeTrigger), err
+}
+
 // Delete takes name of the _timeTrigger and deletes it. Returns an error if one occurs.
 func (c *FakeTimeTriggers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
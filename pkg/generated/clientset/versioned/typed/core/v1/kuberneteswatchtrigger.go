@@ -40,6 +40,7 @@ type KubernetesWatchTriggersGetter interface {
 type KubernetesWatchTriggerInterface interface {
 	Create(ctx context.Context, _kubernetesWatchTrigger *v1.KubernetesWatchTrigger, opts metav1.CreateOptions) (*v1.KubernetesWatchTrigger, error)
 	Update(ctx context.Context, _kubernetesWatchTrigger *v1.KubernetesWatchTrigger, opts metav1.UpdateOptions) (*v1.KubernetesWatchTrigger, error)
+	UpdateStatus(ctx context.Context, _kubernetesWatchTrigger *v1.KubernetesWatchTrigger, opts metav1.UpdateOptions) (*v1.KubernetesWatchTrigger, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.KubernetesWatchTrigger, error)
@@ -135,6 +136,22 @@ func (c *kubernetesWatchTriggers) Update(ctx context.Context, _kubernetesWatchTr
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *kubernetesWatchTriggers) UpdateStatus(ctx context.Context, _kubernetesWatchTrigger *v1.KubernetesWatchTrigger, opts metav1.UpdateOptions) (result *v1.KubernetesWatchTrigger, err error) {
+	result = &v1.KubernetesWatchTrigger{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("kuberneteswatchtriggers").
+		Name(_kubernetesWatchTrigger.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(_kubernetesWatchTrigger).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the _kubernetesWatchTrigger and deletes it. Returns an error if one occurs.
 func (c *kubernetesWatchTriggers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
@@ -40,6 +40,7 @@ type MessageQueueTriggersGetter interface {
 type MessageQueueTriggerInterface interface {
 	Create(ctx context.Context, _messageQueueTrigger *v1.MessageQueueTrigger, opts metav1.CreateOptions) (*v1.MessageQueueTrigger, error)
 	Update(ctx context.Context, _messageQueueTrigger *v1.MessageQueueTrigger, opts metav1.UpdateOptions) (*v1.MessageQueueTrigger, error)
+	UpdateStatus(ctx context.Context, _messageQueueTrigger *v1.MessageQueueTrigger, opts metav1.UpdateOptions) (*v1.MessageQueueTrigger, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.MessageQueueTrigger, error)
@@ -135,6 +136,22 @@ func (c *messageQueueTriggers) Update(ctx context.Context, _messageQueueTrigger
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *messageQueueTriggers) UpdateStatus(ctx context.Context, _messageQueueTrigger *v1.MessageQueueTrigger, opts metav1.UpdateOptions) (result *v1.MessageQueueTrigger, err error) {
+	result = &v1.MessageQueueTrigger{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("messagequeuetriggers").
+		Name(_messageQueueTrigger.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(_messageQueueTrigger).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the _messageQueueTrigger and deletes it. Returns an error if one occurs.
 func (c *messageQueueTriggers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
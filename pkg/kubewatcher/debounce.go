@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type (
+	// watchEvent is an event of a watched object, with the object before
+	// the change for MODIFIED events.
+	watchEvent struct {
+		watch.Event
+		OldObject runtime.Object
+		// done are called once the event is processed, for the event
+		// and the ones coalesced into it
+		done []func()
+	}
+
+	// debouncer coalesces the events of each object over a window, and
+	// publishes the net event at its end.
+	debouncer struct {
+		window  time.Duration
+		publish func(watchEvent)
+		lock    sync.Mutex
+		pending map[string]*watchEvent
+		stopped bool
+	}
+)
+
+func makeDebouncer(window time.Duration, publish func(watchEvent)) *debouncer {
+	return &debouncer{
+		window:  window,
+		publish: publish,
+		pending: make(map[string]*watchEvent),
+	}
+}
+
+// add publishes the event of the object with the given key, at the end of
+// the window of the first pending event of the object if any. The pending
+// events are dropped without being processed once the debouncer stops.
+func (d *debouncer) add(key string, ev watchEvent) {
+	if d.window <= 0 {
+		d.publish(ev)
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.stopped {
+		return
+	}
+	if p, ok := d.pending[key]; ok {
+		merged, keep := coalesce(*p, ev)
+		if !keep {
+			delete(d.pending, key)
+			merged.finish()
+			return
+		}
+		*p = merged
+		return
+	}
+	p := &ev
+	d.pending[key] = p
+	time.AfterFunc(d.window, func() { d.flush(key, p) })
+}
+
+func (d *debouncer) flush(key string, p *watchEvent) {
+	d.lock.Lock()
+	// the event may have been dropped, and another one added since
+	if d.stopped || d.pending[key] != p {
+		d.lock.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	ev := *p
+	d.lock.Unlock()
+	d.publish(ev)
+}
+
+// stop drops the pending events.
+func (d *debouncer) stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.stopped = true
+	d.pending = make(map[string]*watchEvent)
+}
+
+// coalesce returns the net event of two successive events of an object,
+// or false if the object didn't change, i.e. it was added and deleted.
+// The net event is done once both events are.
+func coalesce(first watchEvent, next watchEvent) (watchEvent, bool) {
+	done := append(append([]func(){}, first.done...), next.done...)
+	switch {
+	case first.Type == watch.Added && next.Type == watch.Deleted:
+		return watchEvent{done: done}, false
+	case first.Type == watch.Added:
+		return watchEvent{Event: watch.Event{Type: watch.Added, Object: next.Object}, done: done}, true
+	case first.Type == watch.Modified && next.Type == watch.Modified:
+		next.OldObject = first.OldObject
+		next.done = done
+		return next, true
+	default:
+		next.done = done
+		return next, true
+	}
+}
+
+// finish calls the done functions of the event once it's processed.
+func (ev watchEvent) finish() {
+	for _, done := range ev.done {
+		done()
+	}
+}
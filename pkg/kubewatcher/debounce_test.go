@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type eventRecorder struct {
+	lock   sync.Mutex
+	events []watchEvent
+}
+
+func (r *eventRecorder) publish(ev watchEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *eventRecorder) get() []watchEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]watchEvent{}, r.events...)
+}
+
+func TestDebouncer(t *testing.T) {
+	recorder := &eventRecorder{}
+	d := makeDebouncer(50*time.Millisecond, recorder.publish)
+
+	v1, v2, v3 := makePod("1", "a", ""), makePod("2", "b", ""), makePod("3", "c", "")
+
+	// added and modified objects are added once
+	d.add("ns/a", watchEvent{Event: watch.Event{Type: watch.Added, Object: v1}})
+	d.add("ns/a", watchEvent{Event: watch.Event{Type: watch.Modified, Object: v2}, OldObject: v1})
+	// modifications are coalesced from the first old object to the last object
+	d.add("ns/b", watchEvent{Event: watch.Event{Type: watch.Modified, Object: v2}, OldObject: v1})
+	d.add("ns/b", watchEvent{Event: watch.Event{Type: watch.Modified, Object: v3}, OldObject: v2})
+	// added and deleted objects never existed
+	d.add("ns/c", watchEvent{Event: watch.Event{Type: watch.Added, Object: v1}})
+	d.add("ns/c", watchEvent{Event: watch.Event{Type: watch.Deleted, Object: v1}})
+	require.Empty(t, recorder.get())
+
+	require.Eventually(t, func() bool { return len(recorder.get()) == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	events := recorder.get()
+	require.Len(t, events, 2)
+	byType := map[watch.EventType]watchEvent{}
+	for _, ev := range events {
+		byType[ev.Type] = ev
+	}
+	require.Equal(t, v2, byType[watch.Added].Object)
+	require.Equal(t, v3, byType[watch.Modified].Object)
+	require.Equal(t, v1, byType[watch.Modified].OldObject)
+
+	// stopped debouncers drop the pending events
+	d.add("ns/a", watchEvent{Event: watch.Event{Type: watch.Deleted, Object: v2}})
+	d.stop()
+	time.Sleep(100 * time.Millisecond)
+	require.Len(t, recorder.get(), 2)
+}
+
+func TestDebouncerWithoutWindow(t *testing.T) {
+	recorder := &eventRecorder{}
+	d := makeDebouncer(0, recorder.publish)
+	d.add("ns/a", watchEvent{Event: watch.Event{Type: watch.Added, Object: makePod("1", "a", "")}})
+	require.Len(t, recorder.get(), 1)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// statusFields are the fields of objects which aren't changed by their
+// users, but by Kubernetes and controllers.
+var statusFields = [][]string{
+	{"status"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "managedFields"},
+}
+
+// wanted returns whether a watch invokes its function with an event.
+func wanted(spec fv1.KubernetesWatchTriggerSpec, ev watchEvent) bool {
+	if len(spec.EventTypes) > 0 {
+		found := false
+		for _, eventType := range spec.EventTypes {
+			if eventType == string(ev.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if ev.Type != watch.Modified {
+		return true
+	}
+	obj, ok1 := ev.Object.(*unstructured.Unstructured)
+	old, ok2 := ev.OldObject.(*unstructured.Unstructured)
+	if !ok1 || !ok2 {
+		return true
+	}
+	// relists send the objects again without changes
+	if obj.GetResourceVersion() == old.GetResourceVersion() {
+		return false
+	}
+	return !spec.IgnoreStatusChanges || !statusOnlyChange(old, obj)
+}
+
+// statusOnlyChange returns whether two versions of an object differ only
+// by their status fields.
+func statusOnlyChange(old *unstructured.Unstructured, obj *unstructured.Unstructured) bool {
+	old, obj = old.DeepCopy(), obj.DeepCopy()
+	for _, field := range statusFields {
+		unstructured.RemoveNestedField(old.Object, field...)
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	return reflect.DeepEqual(old.Object, obj.Object)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func makePod(resourceVersion string, image string, phase string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"image": image},
+		"status": map[string]interface{}{"phase": phase},
+	}}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetName("hello")
+	pod.SetResourceVersion(resourceVersion)
+	return pod
+}
+
+func TestWanted(t *testing.T) {
+	added := watchEvent{Event: watch.Event{Type: watch.Added, Object: makePod("1", "a", "Pending")}}
+	statusChange := watchEvent{
+		Event:     watch.Event{Type: watch.Modified, Object: makePod("2", "a", "Running")},
+		OldObject: makePod("1", "a", "Pending"),
+	}
+	specChange := watchEvent{
+		Event:     watch.Event{Type: watch.Modified, Object: makePod("3", "b", "Running")},
+		OldObject: makePod("2", "a", "Running"),
+	}
+	resync := watchEvent{
+		Event:     watch.Event{Type: watch.Modified, Object: makePod("3", "b", "Running")},
+		OldObject: makePod("3", "b", "Running"),
+	}
+
+	spec := fv1.KubernetesWatchTriggerSpec{}
+	require.True(t, wanted(spec, added))
+	require.True(t, wanted(spec, statusChange))
+	require.True(t, wanted(spec, specChange))
+	require.False(t, wanted(spec, resync))
+
+	spec.EventTypes = []string{fv1.KubeWatchEventModified}
+	require.False(t, wanted(spec, added))
+	require.True(t, wanted(spec, statusChange))
+
+	spec.IgnoreStatusChanges = true
+	require.False(t, wanted(spec, statusChange))
+	require.True(t, wanted(spec, specChange))
+}
@@ -22,21 +22,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"reflect"
-	"strings"
 	"sync/atomic"
 	"time"
 
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/cloudevents"
+	"github.com/fission/fission/pkg/crd"
 	ferror "github.com/fission/fission/pkg/error"
 	"github.com/fission/fission/pkg/publisher"
 	"github.com/fission/fission/pkg/utils"
@@ -50,21 +55,31 @@ const (
 
 type (
 	KubeWatcher struct {
-		logger           *zap.Logger
-		watches          map[types.UID]watchSubscription
-		kubernetesClient *kubernetes.Clientset
-		requestChannel   chan *kubeWatcherRequest
-		publisher        publisher.Publisher
+		logger         *zap.Logger
+		watches        map[types.UID]*watchSubscription
+		fissionClient  *crd.FissionClient
+		dynamicClient  dynamic.Interface
+		mapper         *restmapper.DeferredDiscoveryRESTMapper
+		requestChannel chan *kubeWatcherRequest
+		publisher      publisher.Publisher
 	}
 
 	watchSubscription struct {
-		logger              *zap.Logger
-		watch               fv1.KubernetesWatchTrigger
-		kubeWatch           watch.Interface
-		lastResourceVersion string
-		stopped             *int32
-		kubernetesClient    *kubernetes.Clientset
-		publisher           publisher.Publisher
+		logger    *zap.Logger
+		watch     fv1.KubernetesWatchTrigger
+		informer  cache.SharedIndexInformer
+		stopCh    chan struct{}
+		debouncer *debouncer
+		progress  progress
+		// resumedFrom is the version the watch resumed from after a
+		// restart, resumed is true once its informer did.
+		resumedFrom   string
+		resumed       bool
+		stopped       *int32
+		fissionClient *crd.FissionClient
+		dynamicClient dynamic.Interface
+		mapper        *restmapper.DeferredDiscoveryRESTMapper
+		publisher     publisher.Publisher
 	}
 
 	kubeWatcherRequest struct {
@@ -77,13 +92,18 @@ type (
 	}
 )
 
-func MakeKubeWatcher(logger *zap.Logger, kubernetesClient *kubernetes.Clientset, publisher publisher.Publisher) *KubeWatcher {
+// MakeKubeWatcher returns a KubeWatcher watching resources with the dynamic
+// client, the mapper resolves the resource types of the watches. The
+// progress of the watches is saved in their status with the fission client.
+func MakeKubeWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, dynamicClient dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, publisher publisher.Publisher) *KubeWatcher {
 	kw := &KubeWatcher{
-		logger:           logger.Named("kube_watcher"),
-		watches:          make(map[types.UID]watchSubscription),
-		kubernetesClient: kubernetesClient,
-		publisher:        publisher,
-		requestChannel:   make(chan *kubeWatcherRequest),
+		logger:         logger.Named("kube_watcher"),
+		watches:        make(map[types.UID]*watchSubscription),
+		fissionClient:  fissionClient,
+		dynamicClient:  dynamicClient,
+		mapper:         mapper,
+		publisher:      publisher,
+		requestChannel: make(chan *kubeWatcherRequest),
 	}
 	go kw.svc()
 	return kw
@@ -130,14 +150,13 @@ func (kw *KubeWatcher) svc() {
 func printKubernetesObject(obj runtime.Object, w io.Writer) error {
 	switch obj := obj.(type) {
 	case *runtime.Unknown:
-		var buf bytes.Buffer
-		err := json.Indent(&buf, obj.Raw, "", "    ")
+		return printJSON(obj.Raw, w)
+	case *unstructured.Unstructured:
+		data, err := obj.MarshalJSON()
 		if err != nil {
 			return err
 		}
-		buf.WriteRune('\n')
-		_, err = buf.WriteTo(w)
-		return err
+		return printJSON(data, w)
 	}
 
 	data, err := json.MarshalIndent(obj, "", "    ")
@@ -149,40 +168,42 @@ func printKubernetesObject(obj runtime.Object, w io.Writer) error {
 	return err
 }
 
-func createKubernetesWatch(kubeClient *kubernetes.Clientset, w *fv1.KubernetesWatchTrigger, resourceVersion string) (watch.Interface, error) {
-	var wi watch.Interface
-	var err error
-	var watchTimeoutSec int64 = 120
-
-	// TODO populate labelselector and fieldselector
-	listOptions := metav1.ListOptions{
-		ResourceVersion: resourceVersion,
-		TimeoutSeconds:  &watchTimeoutSec,
-	}
-
-	// TODO handle the full list of types
-	switch strings.ToUpper(w.Spec.Type) {
-	case "POD":
-		wi, err = kubeClient.CoreV1().Pods(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	case "SERVICE":
-		wi, err = kubeClient.CoreV1().Services(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	case "REPLICATIONCONTROLLER":
-		wi, err = kubeClient.CoreV1().ReplicationControllers(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	case "JOB":
-		wi, err = kubeClient.BatchV1().Jobs(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	default:
-		err = errors.NewBadRequest(fmt.Sprintf("Error: unknown obj type '%v'", w.Spec.Type))
-	}
-	return wi, err
+func printJSON(data []byte, w io.Writer) error {
+	var buf bytes.Buffer
+	err := json.Indent(&buf, data, "", "    ")
+	if err != nil {
+		return err
+	}
+	buf.WriteRune('\n')
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// resourceClient returns the client of the resources of a watch.
+func resourceClient(dynamicClient dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, w *fv1.KubernetesWatchTrigger) (dynamic.ResourceInterface, error) {
+	mapping, err := resourceMapping(mapper, w.Spec.Type)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			// the resource may have been added since the last discovery,
+			// like the CRD of a custom resource
+			mapper.Reset()
+		}
+		return nil, errors.NewBadRequest(fmt.Sprintf("Error: unknown obj type '%v': %v", w.Spec.Type, err))
+	}
+
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return dynamicClient.Resource(mapping.Resource), nil
+	}
+	return dynamicClient.Resource(mapping.Resource).Namespace(w.Spec.Namespace), nil
 }
 
 func (kw *KubeWatcher) addWatch(w *fv1.KubernetesWatchTrigger) error {
 	kw.logger.Info("adding watch", zap.String("name", w.ObjectMeta.Name), zap.Any("function", w.Spec.FunctionReference))
-	ws, err := MakeWatchSubscription(kw.logger.Named("watchsubscription"), w, kw.kubernetesClient, kw.publisher)
+	ws, err := MakeWatchSubscription(kw.logger.Named("watchsubscription"), w, kw.fissionClient, kw.dynamicClient, kw.mapper, kw.publisher)
 	if err != nil {
 		return err
 	}
-	kw.watches[w.ObjectMeta.UID] = *ws
+	kw.watches[w.ObjectMeta.UID] = ws
 	return nil
 }
 
@@ -198,50 +219,95 @@ func (kw *KubeWatcher) removeWatch(w *fv1.KubernetesWatchTrigger) error {
 	return nil
 }
 
-func MakeWatchSubscription(logger *zap.Logger, w *fv1.KubernetesWatchTrigger, kubeClient *kubernetes.Clientset, publisher publisher.Publisher) (*watchSubscription, error) {
+func MakeWatchSubscription(logger *zap.Logger, w *fv1.KubernetesWatchTrigger, fissionClient *crd.FissionClient, dynamicClient dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, publisher publisher.Publisher) (*watchSubscription, error) {
 	var stopped int32 = 0
 	ws := &watchSubscription{
-		logger:              logger.Named("watch_subscription"),
-		watch:               *w,
-		kubeWatch:           nil,
-		stopped:             &stopped,
-		kubernetesClient:    kubeClient,
-		publisher:           publisher,
-		lastResourceVersion: "",
+		logger:        logger.Named("watch_subscription"),
+		watch:         *w,
+		stopCh:        make(chan struct{}),
+		stopped:       &stopped,
+		fissionClient: fissionClient,
+		dynamicClient: dynamicClient,
+		mapper:        mapper,
+		publisher:     publisher,
+	}
+	ws.progress.resourceVersion = w.Status.LastResourceVersion
+	ws.resumedFrom = w.Status.LastResourceVersion
+	if w.Status.LastEventTime != nil {
+		ws.progress.eventTime = w.Status.LastEventTime.Time
 	}
+	ws.debouncer = makeDebouncer(time.Duration(w.Spec.DebounceSeconds)*time.Second, ws.publish)
 
-	err := ws.restartWatch()
+	resource, err := ws.resourceClient()
 	if err != nil {
 		return nil, err
 	}
+	ws.makeInformer(resource)
 
-	go ws.eventDispatchLoop()
+	ws.logger.Info("listening to watch", zap.String("name", ws.watch.ObjectMeta.Name))
+	go ws.run()
 	return ws, nil
 }
 
-func (ws *watchSubscription) restartWatch() error {
+// makeInformer creates the informer of the watch, handling the events of
+// its resources.
+func (ws *watchSubscription) makeInformer(resource dynamic.ResourceInterface) {
+	ws.informer = cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return ws.list(resource, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return resource.Watch(context.TODO(), ws.listOptions(options))
+		},
+	}, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	ws.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ws.handle(watch.Added, obj, nil)
+		},
+		UpdateFunc: func(oldObj interface{}, obj interface{}) {
+			ws.handle(watch.Modified, obj, oldObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			// the final state of objects deleted while the watch was
+			// disconnected is unknown
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			ws.handle(watch.Deleted, obj, nil)
+		},
+	})
+}
+
+func (ws *watchSubscription) resourceClient() (dynamic.ResourceInterface, error) {
 	retries := 60
 	for {
-		ws.logger.Info("(re)starting watch",
+		ws.logger.Info("starting watch",
 			zap.Any("watch", ws.watch.ObjectMeta),
 			zap.String("namespace", ws.watch.Spec.Namespace),
 			zap.String("type", ws.watch.Spec.Type),
-			zap.String("last_resource_version", ws.lastResourceVersion))
-		wi, err := createKubernetesWatch(ws.kubernetesClient, &ws.watch, ws.lastResourceVersion)
+			zap.String("last_resource_version", ws.watch.Status.LastResourceVersion))
+		resource, err := resourceClient(ws.dynamicClient, ws.mapper, &ws.watch)
 		if err != nil {
 			retries--
 			if retries > 0 {
 				time.Sleep(500 * time.Millisecond)
 				continue
 			} else {
-				return err
+				return nil, err
 			}
 		}
-		ws.kubeWatch = wi
-		return nil
+		return resource, nil
 	}
 }
 
+// listOptions returns the options to list and watch the resources of the
+// watch, with its selectors.
+func (ws *watchSubscription) listOptions(options metav1.ListOptions) metav1.ListOptions {
+	options.LabelSelector = labels.SelectorFromSet(ws.watch.Spec.LabelSelector).String()
+	options.FieldSelector = ws.watch.Spec.FieldSelector
+	return options
+}
+
 func getResourceVersion(obj runtime.Object) (string, error) {
 	m, err := meta.Accessor(obj)
 	if err != nil {
@@ -250,86 +316,153 @@ func getResourceVersion(obj runtime.Object) (string, error) {
 	return m.GetResourceVersion(), nil
 }
 
-func (ws *watchSubscription) eventDispatchLoop() {
-	ws.logger.Info("listening to watch", zap.String("name", ws.watch.ObjectMeta.Name))
-	for {
-		// check watchSubscription is stopped or not before waiting for event
-		// comes from the kubeWatch.ResultChan(). This fix the edge case that
-		// new kubewatch is created in the restartWatch() while the old kubewatch
-		// is being used in watchSubscription.stop().
-		if ws.isStopped() {
-			break
-		}
-		ev, more := <-ws.kubeWatch.ResultChan()
-		if !more {
-			if ws.isStopped() {
-				// watch is removed by user.
-				ws.logger.Warn("watch stopped", zap.String("watch_name", ws.watch.ObjectMeta.Name))
-				return
-			} else {
-				// watch closed due to timeout, restart it.
-				ws.logger.Warn("watch timed out - restarting", zap.String("watch_name", ws.watch.ObjectMeta.Name))
-				err := ws.restartWatch()
-				if err != nil {
-					ws.logger.Panic("failed to restart watch", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-				}
-				continue
-			}
-		}
+// handle filters the events of the informer, and publishes the wanted
+// ones once debounced.
+func (ws *watchSubscription) handle(eventType watch.EventType, obj interface{}, oldObj interface{}) {
+	object, ok := obj.(runtime.Object)
+	if !ok {
+		ws.logger.Error("unexpected object in watch event", zap.Any("object", obj), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		return
+	}
+	ev := watchEvent{Event: watch.Event{Type: eventType, Object: object}}
+	if oldObj != nil {
+		ev.OldObject, _ = oldObj.(runtime.Object)
+	}
 
-		if ev.Type == watch.Error {
-			e := errors.FromObject(ev.Object)
-			ws.logger.Warn("watch error - retrying after one second", zap.Error(e), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			// Start from the beginning to get around "too old resource version"
-			ws.lastResourceVersion = ""
-			time.Sleep(time.Second)
-			err := ws.restartWatch()
-			if err != nil {
-				ws.logger.Panic("failed to restart watch", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			}
-			continue
-		}
-		rv, err := getResourceVersion(ev.Object)
-		if err != nil {
-			ws.logger.Error("error getting resourceVersion from object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-		} else {
-			ws.lastResourceVersion = rv
-		}
+	if !ws.diff(&ev) {
+		return
+	}
 
-		// Serialize the object
-		var buf bytes.Buffer
-		err = printKubernetesObject(ev.Object, &buf)
-		if err != nil {
-			ws.logger.Error("failed to serialize object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			// TODO send a POST request indicating error
-		}
+	rv, err := getResourceVersion(object)
+	if err != nil {
+		ws.logger.Error("error getting resourceVersion from object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+	}
+	// the progress only advances past the event once it's delivered
+	ticket := ws.progress.start(rv)
+	ev.done = []func(){func() { ws.progress.finish(ticket) }}
+
+	if !wanted(ws.watch.Spec, ev) {
+		ev.finish()
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(object)
+	if err != nil {
+		ws.logger.Error("error getting key of object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		ev.finish()
+		return
+	}
+	ws.debouncer.add(key, ev)
+}
+
+// publish invokes the function of the watch with an event. The event is
+// done once it's delivered, or dropped.
+func (ws *watchSubscription) publish(ev watchEvent) {
+	if ws.isStopped() {
+		return
+	}
 
-		// Event and object type aren't in the serialized object
-		headers := map[string]string{
-			"Content-Type":             "application/json",
-			"X-Kubernetes-Event-Type":  string(ev.Type),
-			"X-Kubernetes-Object-Type": reflect.TypeOf(ev.Object).Elem().Name(),
+	// Serialize the object
+	var buf bytes.Buffer
+	err := ws.serialize(ev, &buf)
+	if err != nil {
+		ws.logger.Error("failed to serialize object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		// TODO send a POST request indicating error
+	}
+
+	// Event and object type aren't in the serialized object
+	headers := map[string]string{
+		"Content-Type":             "application/json",
+		"X-Kubernetes-Event-Type":  string(ev.Type),
+		"X-Kubernetes-Object-Type": ev.Object.GetObjectKind().GroupVersionKind().Kind,
+	}
+	body := buf.Bytes()
+	if len(ws.watch.Spec.CloudEvents) > 0 {
+		var ceHeaders map[string]string
+		body, ceHeaders = cloudevents.Encode(ws.watch.Spec.CloudEvents, ws.cloudEvent(ev.Event, body))
+		for k, v := range ceHeaders {
+			headers[k] = v
 		}
+	}
 
-		// TODO support other function ref types. Or perhaps delegate to router?
-		if ws.watch.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
-			ws.logger.Error("unsupported function ref type - cannot publish event",
-				zap.Any("type", ws.watch.Spec.FunctionReference.Type),
-				zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			continue
+	// with the addition of multi-tenancy, the users can create functions in any namespace. however,
+	// the triggers can only be created in the same namespace as the function.
+	// so essentially, function namespace = trigger namespace.
+	url, err := utils.UrlForFunctionReference(ws.watch.Spec.FunctionReference, ws.watch.ObjectMeta.Namespace)
+	if err != nil {
+		ws.logger.Error("failed to resolve function reference - cannot publish event",
+			zap.Error(err),
+			zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		ev.finish()
+		return
+	}
+
+	// keep the events of an object in order, if the publisher can
+	if op, ok := ws.publisher.(publisher.OrderedPublisher); ok {
+		key, err := cache.MetaNamespaceKeyFunc(ev.Object)
+		if err == nil {
+			op.PublishOrdered(key, string(body), headers, url, ev.finish)
+			return
 		}
+	}
+	ws.publisher.Publish(string(body), headers, url)
+	ev.finish()
+}
+
+// serialize writes the object of an event, along with the old object of
+// MODIFIED events if the watch includes it.
+func (ws *watchSubscription) serialize(ev watchEvent, w io.Writer) error {
+	if !ws.watch.Spec.IncludeOldObject || ev.OldObject == nil {
+		return printKubernetesObject(ev.Object, w)
+	}
+
+	var obj, oldObj bytes.Buffer
+	err := printKubernetesObject(ev.Object, &obj)
+	if err != nil {
+		return err
+	}
+	err = printKubernetesObject(ev.OldObject, &oldObj)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(struct {
+		Object    json.RawMessage `json:"object"`
+		OldObject json.RawMessage `json:"oldObject"`
+	}{obj.Bytes(), oldObj.Bytes()})
+	if err != nil {
+		return err
+	}
+	return printJSON(data, w)
+}
 
-		// with the addition of multi-tenancy, the users can create functions in any namespace. however,
-		// the triggers can only be created in the same namespace as the function.
-		// so essentially, function namespace = trigger namespace.
-		url := utils.UrlForFunction(ws.watch.Spec.FunctionReference.Name, ws.watch.ObjectMeta.Namespace)
-		ws.publisher.Publish(buf.String(), headers, url)
+// cloudEvent returns the CloudEvent of a watch event, data is the
+// serialized object. The object UID and resource version identify the
+// event, the object namespace and name are its subject.
+func (ws *watchSubscription) cloudEvent(ev watch.Event, data []byte) cloudevents.Event {
+	event := cloudevents.Event{
+		Source:          cloudevents.Source("kuberneteswatchtriggers", ws.watch.ObjectMeta),
+		Type:            cloudevents.KubeWatchType(string(ev.Type)),
+		Time:            time.Now(),
+		DataContentType: "application/json",
+		Data:            data,
 	}
+	m, err := meta.Accessor(ev.Object)
+	if err != nil {
+		event.ID = uuid.NewV4().String()
+		return event
+	}
+	event.ID = fmt.Sprintf("%v-%v", m.GetUID(), m.GetResourceVersion())
+	event.Subject = m.GetName()
+	if len(m.GetNamespace()) > 0 {
+		event.Subject = m.GetNamespace() + "/" + m.GetName()
+	}
+	return event
 }
 
 func (ws *watchSubscription) stop() {
-	atomic.StoreInt32(ws.stopped, 1)
-	ws.kubeWatch.Stop()
+	if atomic.CompareAndSwapInt32(ws.stopped, 0, 1) {
+		close(ws.stopCh)
+		ws.debouncer.stop()
+	}
 }
 
 func (ws *watchSubscription) isStopped() bool {
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestListOptions(t *testing.T) {
+	podsResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+	for _, resumedFrom := range []string{"", "5"} {
+		ws, _ := makeTestSubscription(resumedFrom, time.Time{})
+		ws.resumedFrom = resumedFrom
+		ws.watch.Spec.LabelSelector = map[string]string{"app": "web", "tier": "front"}
+		ws.watch.Spec.FieldSelector = "status.phase=Running"
+
+		client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{podsResource: "PodList"})
+		ws.makeInformer(client.Resource(podsResource).Namespace("default"))
+		go ws.informer.Run(ws.stopCh)
+
+		// the resources are listed and watched with both selectors
+		var lists, watches []k8stesting.Action
+		require.Eventually(t, func() bool {
+			lists, watches = nil, nil
+			for _, action := range client.Actions() {
+				switch action.GetVerb() {
+				case "list":
+					lists = append(lists, action)
+				case "watch":
+					watches = append(watches, action)
+				}
+			}
+			return len(lists) > 0 && len(watches) > 0
+		}, 10*time.Second, 10*time.Millisecond)
+		close(ws.stopCh)
+
+		for _, action := range lists {
+			restrictions := action.(k8stesting.ListAction).GetListRestrictions()
+			require.Equal(t, "app=web,tier=front", restrictions.Labels.String())
+			require.Equal(t, "status.phase=Running", restrictions.Fields.String())
+		}
+		for _, action := range watches {
+			restrictions := action.(k8stesting.WatchAction).GetWatchRestrictions()
+			require.Equal(t, "app=web,tier=front", restrictions.Labels.String())
+			require.Equal(t, "status.phase=Running", restrictions.Fields.String())
+		}
+	}
+}
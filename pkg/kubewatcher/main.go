@@ -17,13 +17,22 @@ limitations under the License.
 package kubewatcher
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
 
 	"github.com/fission/fission/pkg/crd"
 	"github.com/fission/fission/pkg/publisher"
+	"github.com/fission/fission/pkg/utils/leader"
 )
 
+const metricAddr = ":8080"
+
 func Start(logger *zap.Logger, routerUrl string) error {
 	fissionClient, kubeClient, _, _, err := crd.MakeFissionClient()
 	if err != nil {
@@ -35,9 +44,46 @@ func Start(logger *zap.Logger, routerUrl string) error {
 		return errors.Wrap(err, "error waiting for CRDs")
 	}
 
-	poster := publisher.MakeWebhookPublisher(logger, routerUrl)
-	kubeWatch := MakeKubeWatcher(logger, kubeClient, poster)
-	MakeWatchSync(logger, fissionClient, kubeWatch)
+	dynamicClient, err := crd.GetDynamicClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get dynamic client")
+	}
+	// resolves the resource types of watches, discovering them again
+	// when a watch refers to an unknown one
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kubeClient.Discovery()))
+
+	config, err := leader.ConfigFromEnv("kubewatcher")
+	if err != nil {
+		return errors.Wrap(err, "error reading leader election config")
+	}
+
+	publisherConfig, err := publisher.WebhookPublisherConfigFromEnv()
+	if err != nil {
+		return errors.Wrap(err, "error reading publisher config")
+	}
+
+	go serveMetric(logger)
+
+	// with several replicas, only the leader runs the kubewatcher
+	go func() {
+		err := leader.Run(context.Background(), logger, kubeClient, "kubewatcher", config, func(ctx context.Context) {
+			poster := publisher.MakeWebhookPublisherWithConfig(logger, routerUrl, publisherConfig)
+			kubeWatch := MakeKubeWatcher(logger, fissionClient, dynamicClient, mapper, poster)
+			MakeWatchSync(logger, fissionClient, kubeWatch)
+		})
+		if err != nil {
+			logger.Fatal("error running leader election", zap.Error(err))
+		}
+		logger.Fatal("stopped leading, exiting to stand by")
+	}()
 
 	return nil
 }
+
+func serveMetric(logger *zap.Logger) {
+	// Expose the registered metrics via HTTP.
+	http.Handle("/metrics", promhttp.Handler())
+	err := http.ListenAndServe(metricAddr, nil)
+
+	logger.Fatal("done listening on metrics endpoint", zap.Error(err))
+}
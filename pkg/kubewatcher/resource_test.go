@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResourceMapping(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "fission.io", Version: "v1", Kind: "Function"}, meta.RESTScopeNamespace)
+
+	for _, test := range []struct {
+		resourceOrKind string
+		resource       schema.GroupVersionResource
+		scope          meta.RESTScopeName
+	}{
+		{"pod", schema.GroupVersionResource{Version: "v1", Resource: "pods"}, meta.RESTScopeNameNamespace},
+		{"POD", schema.GroupVersionResource{Version: "v1", Resource: "pods"}, meta.RESTScopeNameNamespace},
+		{"Deployment", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, meta.RESTScopeNameNamespace},
+		{"deployments.apps", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, meta.RESTScopeNameNamespace},
+		{"namespaces", schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, meta.RESTScopeNameRoot},
+		{"functions.v1.fission.io", schema.GroupVersionResource{Group: "fission.io", Version: "v1", Resource: "functions"}, meta.RESTScopeNameNamespace},
+		{"Function.v1.fission.io", schema.GroupVersionResource{Group: "fission.io", Version: "v1", Resource: "functions"}, meta.RESTScopeNameNamespace},
+	} {
+		mapping, err := resourceMapping(mapper, test.resourceOrKind)
+		require.NoError(t, err, test.resourceOrKind)
+		require.Equal(t, test.resource, mapping.Resource, test.resourceOrKind)
+		require.Equal(t, test.scope, mapping.Scope.Name(), test.resourceOrKind)
+	}
+
+	_, err := resourceMapping(mapper, "widgets.example.com")
+	require.Error(t, err)
+}
+
+func TestPrintUnstructuredObject(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("fission.io/v1")
+	obj.SetKind("Function")
+	obj.SetName("hello")
+
+	var buf bytes.Buffer
+	require.NoError(t, printKubernetesObject(obj, &buf))
+	require.JSONEq(t, `{"apiVersion": "fission.io/v1", "kind": "Function", "metadata": {"name": "hello"}}`, buf.String())
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// statusUpdateInterval is how often the progress of watches is saved in
+// their status.
+const statusUpdateInterval = 10 * time.Second
+
+type (
+	// progress is how far a watch went through the events of its
+	// resources. Events are processed once they are delivered to the
+	// function, or filtered out.
+	progress struct {
+		lock sync.Mutex
+		// resourceVersion is the version up to which all events were
+		// processed, the last one at eventTime.
+		resourceVersion string
+		eventTime       time.Time
+
+		// pending are the events being processed, in the order they
+		// were received
+		nextTicket uint64
+		pending    []pendingEvent
+	}
+
+	pendingEvent struct {
+		ticket          uint64
+		resourceVersion string
+		processed       bool
+	}
+)
+
+// start registers an event of the given version received by the watch,
+// events must be started in order. The returned ticket is passed to
+// finish once the event is processed.
+func (p *progress) start(resourceVersion string) uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.nextTicket++
+	p.pending = append(p.pending, pendingEvent{ticket: p.nextTicket, resourceVersion: resourceVersion})
+	return p.nextTicket
+}
+
+// finish records that the event of a ticket was processed, and advances
+// the progress past the events processed up to the first pending one.
+func (p *progress) finish(ticket uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for i := range p.pending {
+		if p.pending[i].ticket == ticket {
+			p.pending[i].processed = true
+			break
+		}
+	}
+	for len(p.pending) > 0 && p.pending[0].processed {
+		resourceVersion := p.pending[0].resourceVersion
+		p.pending = p.pending[1:]
+		// the objects of lists aren't in the order of their versions
+		if c, ok := compareResourceVersions(resourceVersion, p.resourceVersion); len(resourceVersion) == 0 || ok && c < 0 {
+			continue
+		}
+		p.resourceVersion = resourceVersion
+		p.eventTime = time.Now()
+	}
+}
+
+func (p *progress) get() (string, time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.resourceVersion, p.eventTime
+}
+
+// compareResourceVersions compares two resource versions, or returns false
+// if they aren't comparable. Resource versions are opaque, but the ones of
+// etcd backed API servers are increasing integers.
+func compareResourceVersions(a string, b string) (int, bool) {
+	x, err := strconv.ParseUint(a, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	y, err := strconv.ParseUint(b, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+// run runs the informer of the watch until the watch is stopped, saving
+// its progress meanwhile.
+func (ws *watchSubscription) run() {
+	go ws.saveProgress()
+	ws.informer.Run(ws.stopCh)
+}
+
+// list lists the resources of the watch for its informer. After a restart
+// the informer first lists them as they were at the version saved in the
+// status of the watch, so that it resumes watching from there and handles
+// the events missed meanwhile. The resources are listed as they are now if
+// that version was compacted, diff then skips the ones which didn't change.
+func (ws *watchSubscription) list(resource dynamic.ResourceInterface, options metav1.ListOptions) (runtime.Object, error) {
+	if !ws.resumed && len(ws.resumedFrom) > 0 {
+		ws.resumed = true
+		resumeOptions := options
+		resumeOptions.ResourceVersion = ws.resumedFrom
+		resumeOptions.ResourceVersionMatch = metav1.ResourceVersionMatchExact
+		list, err := resource.List(context.TODO(), ws.listOptions(resumeOptions))
+		if err == nil {
+			ws.logger.Info("resuming watch", zap.String("watch_name", ws.watch.ObjectMeta.Name), zap.String("last_resource_version", ws.resumedFrom))
+			return list, nil
+		}
+		ws.logger.Info("error resuming watch, listing the current resources", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name), zap.String("last_resource_version", ws.resumedFrom))
+	}
+	return resource.List(context.TODO(), ws.listOptions(options))
+}
+
+// diff handles the objects the informer adds while it resumes the watch.
+// The objects listed which didn't change since the version the watch
+// resumed from were processed before the restart, diff returns false for
+// them. The others are turned into MODIFIED events if they were created
+// before the last processed event: the informer adds them as it doesn't
+// know them, their old version is unknown.
+func (ws *watchSubscription) diff(ev *watchEvent) bool {
+	if ev.Type != watch.Added || len(ws.resumedFrom) == 0 {
+		return true
+	}
+	m, err := meta.Accessor(ev.Object)
+	if err != nil {
+		return true
+	}
+	if c, ok := compareResourceVersions(m.GetResourceVersion(), ws.resumedFrom); ok && c <= 0 {
+		return false
+	}
+	if last := ws.watch.Status.LastEventTime; last != nil && m.GetCreationTimestamp().Time.Before(last.Time) {
+		ev.Type = watch.Modified
+	}
+	return true
+}
+
+// saveProgress periodically saves the progress of the watch in its status,
+// to resume from it after restarts.
+func (ws *watchSubscription) saveProgress() {
+	ticker := time.NewTicker(statusUpdateInterval)
+	defer ticker.Stop()
+
+	saved := ws.watch.Status.LastResourceVersion
+	triggers := ws.fissionClient.CoreV1().KubernetesWatchTriggers(ws.watch.ObjectMeta.Namespace)
+	for {
+		select {
+		case <-ws.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		resourceVersion, eventTime := ws.progress.get()
+		if resourceVersion == saved {
+			continue
+		}
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := triggers.Get(context.Background(), ws.watch.ObjectMeta.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			// the watch was deleted and created again
+			if current.ObjectMeta.UID != ws.watch.ObjectMeta.UID {
+				return nil
+			}
+			current.Status.LastResourceVersion = resourceVersion
+			current.Status.LastEventTime = &metav1.Time{Time: eventTime}
+			_, err = triggers.UpdateStatus(context.Background(), current, metav1.UpdateOptions{})
+			return err
+		})
+		if errors.IsNotFound(err) {
+			return
+		}
+		if err != nil {
+			ws.logger.Warn("failed to update status of watch", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+			continue
+		}
+		saved = resourceVersion
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+type fakePublisher struct {
+	lock    sync.Mutex
+	headers []map[string]string
+}
+
+func (p *fakePublisher) Publish(body string, headers map[string]string, target string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.headers = append(p.headers, headers)
+}
+
+func (p *fakePublisher) eventTypes() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var eventTypes []string
+	for _, headers := range p.headers {
+		eventTypes = append(eventTypes, headers["X-Kubernetes-Event-Type"])
+	}
+	return eventTypes
+}
+
+func makeTestSubscription(lastResourceVersion string, lastEventTime time.Time) (*watchSubscription, *fakePublisher) {
+	var stopped int32
+	publisher := &fakePublisher{}
+	ws := &watchSubscription{
+		logger: zap.NewNop(),
+		watch: fv1.KubernetesWatchTrigger{
+			ObjectMeta: metav1.ObjectMeta{Name: "watch", Namespace: "default"},
+			Spec: fv1.KubernetesWatchTriggerSpec{
+				Namespace:         "default",
+				Type:              "pod",
+				FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			},
+			Status: fv1.KubernetesWatchTriggerStatus{
+				LastResourceVersion: lastResourceVersion,
+				LastEventTime:       &metav1.Time{Time: lastEventTime},
+			},
+		},
+		stopCh:    make(chan struct{}),
+		stopped:   &stopped,
+		publisher: publisher,
+	}
+	ws.progress.resourceVersion = lastResourceVersion
+	ws.debouncer = makeDebouncer(0, ws.publish)
+	return ws, publisher
+}
+
+func TestCompareResourceVersions(t *testing.T) {
+	c, ok := compareResourceVersions("9", "10")
+	require.True(t, ok)
+	require.Equal(t, -1, c)
+	c, ok = compareResourceVersions("10", "10")
+	require.True(t, ok)
+	require.Equal(t, 0, c)
+	_, ok = compareResourceVersions("", "10")
+	require.False(t, ok)
+}
+
+// listResource lists the resources of a fake dynamic client with the
+// given function, which can't see the list options of the client.
+type listResource struct {
+	dynamic.ResourceInterface
+	list func(opts metav1.ListOptions) (*unstructured.UnstructuredList, error)
+}
+
+func (r listResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return r.list(opts)
+}
+
+func TestResume(t *testing.T) {
+	podsResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	lastEventTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	pod := func(resourceVersion string, name string, created time.Time) *unstructured.Unstructured {
+		pod := makePod(resourceVersion, "a", "")
+		pod.SetName(name)
+		pod.SetCreationTimestamp(metav1.NewTime(created))
+		return pod
+	}
+	before, after := lastEventTime.Add(-time.Hour), lastEventTime.Add(time.Minute)
+
+	for _, test := range []struct {
+		name string
+		// resources at the saved version, nil if it was compacted
+		saved []unstructured.Unstructured
+		// current resources
+		current []unstructured.Unstructured
+		// events of the watch from the saved version
+		events     []watch.Event
+		eventTypes []string
+	}{
+		{
+			name:  "watches from the saved version",
+			saved: []unstructured.Unstructured{*pod("3", "a", before), *pod("4", "b", before)},
+			events: []watch.Event{
+				{Type: watch.Modified, Object: pod("6", "a", before)},
+				{Type: watch.Deleted, Object: pod("7", "b", before)},
+				{Type: watch.Added, Object: pod("8", "c", after)},
+			},
+			eventTypes: []string{"MODIFIED", "DELETED", "ADDED"},
+		},
+		{
+			name:       "diffs the current resources when the saved version was compacted",
+			current:    []unstructured.Unstructured{*pod("4", "a", before), *pod("7", "b", before), *pod("9", "c", after)},
+			eventTypes: []string{"MODIFIED", "ADDED"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ws, publisher := makeTestSubscription("5", lastEventTime)
+			ws.resumedFrom = "5"
+
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			client.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				watcher := watch.NewFakeWithChanSize(len(test.events), false)
+				if action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion == "5" {
+					for _, ev := range test.events {
+						watcher.Action(ev.Type, ev.Object)
+					}
+				}
+				return true, watcher, nil
+			})
+			resource := listResource{
+				ResourceInterface: client.Resource(podsResource).Namespace("default"),
+				list: func(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+					list := &unstructured.UnstructuredList{Items: test.current}
+					list.SetResourceVersion("10")
+					if opts.ResourceVersionMatch == metav1.ResourceVersionMatchExact {
+						require.Equal(t, "5", opts.ResourceVersion)
+						if test.saved == nil {
+							return nil, apierrors.NewResourceExpired("too old resource version")
+						}
+						list = &unstructured.UnstructuredList{Items: test.saved}
+						list.SetResourceVersion("5")
+					}
+					return list, nil
+				},
+			}
+
+			ws.makeInformer(resource)
+			go ws.informer.Run(ws.stopCh)
+			defer close(ws.stopCh)
+
+			require.Eventually(t, func() bool {
+				return len(publisher.eventTypes()) == len(test.eventTypes)
+			}, 10*time.Second, 10*time.Millisecond)
+			require.Equal(t, test.eventTypes, publisher.eventTypes())
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	lastEventTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	ws, _ := makeTestSubscription("5", lastEventTime)
+	ws.resumedFrom = "5"
+
+	added := func(resourceVersion string, created time.Time) *watchEvent {
+		pod := makePod(resourceVersion, "a", "")
+		pod.SetCreationTimestamp(metav1.NewTime(created))
+		return &watchEvent{Event: watch.Event{Type: watch.Added, Object: pod}}
+	}
+
+	// objects processed before the restart are skipped
+	require.False(t, ws.diff(added("4", lastEventTime.Add(-time.Hour))))
+
+	// objects modified since are modified
+	ev := added("7", lastEventTime.Add(-time.Hour))
+	require.True(t, ws.diff(ev))
+	require.Equal(t, watch.Modified, ev.Type)
+
+	// objects created since are added
+	ev = added("8", lastEventTime.Add(time.Minute))
+	require.True(t, ws.diff(ev))
+	require.Equal(t, watch.Added, ev.Type)
+
+	// other events are left as is
+	ev = added("9", lastEventTime.Add(-time.Hour))
+	ev.Type = watch.Deleted
+	require.True(t, ws.diff(ev))
+	require.Equal(t, watch.Deleted, ev.Type)
+}
+
+// orderedPublisher is a publisher delivering requests once told to.
+type orderedPublisher struct {
+	fakePublisher
+	done []func()
+}
+
+func (p *orderedPublisher) PublishOrdered(key string, body string, headers map[string]string, target string, done func()) {
+	p.Publish(body, headers, target)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.done = append(p.done, done)
+}
+
+func TestProgress(t *testing.T) {
+	ws, _ := makeTestSubscription("5", time.Now())
+	publisher := &orderedPublisher{}
+	ws.publisher = publisher
+	ws.watch.Spec.EventTypes = []string{fv1.KubeWatchEventAdded}
+	progress := func() string {
+		resourceVersion, _ := ws.progress.get()
+		return resourceVersion
+	}
+
+	ws.handle(watch.Added, makePod("6", "a", ""), nil)
+	ws.handle(watch.Modified, makePod("7", "a", ""), makePod("6", "a", ""))
+	ws.handle(watch.Added, makePod("8", "b", ""), nil)
+	require.Len(t, publisher.done, 2)
+
+	// the progress doesn't move past events not delivered yet, even if
+	// later ones were
+	require.Equal(t, "5", progress())
+	publisher.done[1]()
+	require.Equal(t, "5", progress())
+
+	// filtered out events are processed along with the events before
+	publisher.done[0]()
+	require.Equal(t, "8", progress())
+}
+
+func TestProgressDebounce(t *testing.T) {
+	ws, publisher := makeTestSubscription("5", time.Now())
+	ws.debouncer = makeDebouncer(time.Hour, ws.publish)
+	progress := func() string {
+		resourceVersion, _ := ws.progress.get()
+		return resourceVersion
+	}
+	pod := func(resourceVersion string, name string) *unstructured.Unstructured {
+		pod := makePod(resourceVersion, "a", "")
+		pod.SetName(name)
+		return pod
+	}
+
+	// events waiting for the end of their window aren't processed
+	ws.handle(watch.Modified, pod("6", "a"), pod("5", "a"))
+	ws.handle(watch.Added, pod("7", "b"), nil)
+	require.Equal(t, "5", progress())
+
+	// events cancelling out are
+	ws.handle(watch.Deleted, pod("8", "b"), nil)
+	require.Equal(t, "5", progress())
+	ws.debouncer.flush("a", ws.debouncer.pending["a"])
+	require.Equal(t, "8", progress())
+	require.Equal(t, []string{"MODIFIED"}, publisher.eventTypes())
+}
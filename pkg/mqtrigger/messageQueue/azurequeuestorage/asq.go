@@ -18,14 +18,13 @@ package azurequeuestorage
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"regexp"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -36,8 +35,8 @@ import (
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
 	"github.com/fission/fission/pkg/mqtrigger/validator"
-	"github.com/fission/fission/pkg/utils"
 )
 
 func init() {
@@ -49,10 +48,15 @@ func init() {
 const (
 	// AzureQueuePollingInterval is the polling interval (default is 1 minute).
 	AzureQueuePollingInterval = time.Minute
-	// AzureQueueRetryLimit is the limit for attempts to retry invoking a function.
+	// AzureQueueRetryLimit is the limit for attempts to retry invoking a function,
+	// used when the trigger doesn't set MaxRetries or a retry policy.
 	AzureQueueRetryLimit = 3
 	// AzureMessageFetchCount is the number of messages to fetch at a time.
 	AzureMessageFetchCount = 10
+	// AzureMaxMessageFetchCount is the most messages the queue service returns at a time.
+	AzureMaxMessageFetchCount = 32
+	// AzureBatchPollingInterval is the polling interval while waiting for the messages of a batch.
+	AzureBatchPollingInterval = time.Second
 	// AzureMessageVisibilityTimeout is the visibility timeout for dequeued messages.
 	AzureMessageVisibilityTimeout = time.Minute
 	// AzurePoisonQueueSuffix is the suffix used for poison queues.
@@ -71,15 +75,20 @@ type AzureStorageConnection struct {
 	routerURL  string
 	service    AzureQueueService
 	httpClient AzureHTTPClient
+	schemas    *schema.Loader
 }
 
 // AzureQueueSubscription represents an Azure storage message queue subscription.
 type AzureQueueSubscription struct {
+	messageQueue.StatusRecorder
 	queue           AzureQueue
 	queueName       string
 	outputQueueName string
-	functionURL     string
+	trigger         *fv1.MessageQueueTrigger
+	schema          *schema.Schema
 	contentType     string
+	retryPolicy     messageQueue.RetryPolicy
+	batch           *messageQueue.BatchConfig
 	unsubscribe     chan bool
 	done            chan bool
 }
@@ -101,6 +110,7 @@ type AzureQueue interface {
 // AzureMessage is the interface that abstracts Azure storage messages.
 // This exists to enable unit testing.
 type AzureMessage interface {
+	ID() string
 	Bytes() []byte
 	Put(options *storage.PutMessageOptions) error
 	Delete(options *storage.QueueServiceOptions) error
@@ -174,6 +184,10 @@ type azureMessage struct {
 	bytes []byte
 }
 
+func (m azureMessage) ID() string {
+	return m.ref.ID
+}
+
 func (m azureMessage) Bytes() []byte {
 	return m.bytes
 }
@@ -216,33 +230,48 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 		httpClient: &http.Client{
 			Timeout: AzureFunctionInvocationTimeout,
 		},
+		schemas: schema.MakeLoader(mqCfg.KubeClient),
 	}, nil
 }
 
 func (asc AzureStorageConnection) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Subscription, error) {
 	asc.logger.Info("subscribing to Azure storage queue", zap.String("queue", trigger.Spec.Topic))
 
-	if trigger.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
-		return nil, fmt.Errorf("unsupported function reference type (%v) for trigger %q", trigger.Spec.FunctionReference.Type, trigger.ObjectMeta.Name)
+	if _, err := messageQueue.FunctionURL(asc.routerURL, trigger); err != nil {
+		return nil, err
+	}
+	msgSchema, err := asc.schemas.Load(context.TODO(), trigger)
+	if err != nil {
+		return nil, err
 	}
 
 	subscription := &AzureQueueSubscription{
 		queue:           asc.service.GetQueue(trigger.Spec.Topic),
 		queueName:       trigger.Spec.Topic,
 		outputQueueName: trigger.Spec.ResponseTopic,
-		// with the addition of multi-tenancy, the users can create functions in any namespace. however,
-		// the triggers can only be created in the same namespace as the function.
-		// so essentially, function namespace = trigger namespace.
-		functionURL: asc.routerURL + "/" + strings.TrimPrefix(utils.UrlForFunction(trigger.Spec.FunctionReference.Name, trigger.ObjectMeta.Namespace), "/"),
-		contentType: trigger.Spec.ContentType,
-		unsubscribe: make(chan bool),
-		done:        make(chan bool),
+		trigger:         trigger,
+		schema:          msgSchema,
+		contentType:     msgSchema.ContentType(trigger.Spec.ContentType),
+		retryPolicy:     retryPolicy(trigger),
+		batch:           messageQueue.MakeBatchConfig(trigger),
+		unsubscribe:     make(chan bool),
+		done:            make(chan bool),
 	}
 
 	go runAzureQueueSubscription(asc, subscription)
 	return subscription, nil
 }
 
+// retryPolicy returns the retry policy of a trigger, retrying
+// AzureQueueRetryLimit times if the trigger doesn't set one.
+func retryPolicy(trigger *fv1.MessageQueueTrigger) messageQueue.RetryPolicy {
+	policy := messageQueue.MakeRetryPolicy(trigger)
+	if trigger.Spec.MaxRetries == 0 && trigger.Spec.RetryPolicy == nil {
+		policy.MaxRetries = AzureQueueRetryLimit
+	}
+	return policy
+}
+
 func (asc AzureStorageConnection) Unsubscribe(subscription messageQueue.Subscription) error {
 	sub := subscription.(*AzureQueueSubscription)
 
@@ -286,6 +315,12 @@ func pollAzureQueueSubscription(conn AzureStorageConnection, sub *AzureQueueSubs
 	err := sub.queue.Create(nil)
 	if err != nil {
 		conn.logger.Error("failed to create message queue", zap.Error(err), zap.String("queue", sub.queueName))
+		sub.Error(err)
+		return
+	}
+
+	if sub.batch != nil {
+		pollAzureQueueBatches(conn, sub, wg)
 		return
 	}
 
@@ -293,6 +328,7 @@ func pollAzureQueueSubscription(conn AzureStorageConnection, sub *AzureQueueSubs
 		err := sub.queue.Create(nil)
 		if err != nil {
 			conn.logger.Error("failed to create message queue", zap.Error(err), zap.String("queue", sub.queueName))
+			sub.Error(err)
 			return
 		}
 
@@ -302,6 +338,7 @@ func pollAzureQueueSubscription(conn AzureStorageConnection, sub *AzureQueueSubs
 		})
 		if err != nil {
 			conn.logger.Error("failed to retrieve messages from Azure storage queue", zap.Error(err), zap.String("queue", sub.queueName))
+			sub.Error(err)
 			break
 		}
 		if len(messages) == 0 {
@@ -319,6 +356,22 @@ func pollAzureQueueSubscription(conn AzureStorageConnection, sub *AzureQueueSubs
 }
 
 func invokeTriggeredFunction(conn AzureStorageConnection, sub *AzureQueueSubscription, message AzureMessage) {
+	functionURL, err := messageQueue.FunctionURL(conn.routerURL, sub.trigger)
+	if err != nil {
+		// the message is visible again after the visibility timeout
+		conn.logger.Error("failed to get function URL", zap.Error(err), zap.String("queue", sub.queueName))
+		sub.Error(err)
+		return
+	}
+
+	data, err := sub.schema.Check(context.Background(), message.Bytes())
+	if schema.IsUnavailable(err) {
+		// the message is visible again after the visibility timeout
+		conn.logger.Error("failed to read the schema of message", zap.Error(err), zap.String("queue", sub.queueName))
+		sub.Error(err)
+		return
+	}
+
 	defer func() {
 		err := message.Delete(nil)
 		if err != nil {
@@ -326,77 +379,109 @@ func invokeTriggeredFunction(conn AzureStorageConnection, sub *AzureQueueSubscri
 		}
 	}()
 
-	conn.logger.Info("making HTTP request to invoke function", zap.String("function_url", sub.functionURL))
+	if err != nil {
+		conn.logger.Error("message doesn't match the trigger schema - moving message to poison queue",
+			zap.Error(err),
+			zap.String("function_url", functionURL))
+		result := schema.InvalidMessageResult(err)
+		sub.Invoked(result)
+		putPoisonMessage(conn, sub, functionURL, message, result)
+		return
+	}
 
-	for i := 0; i <= AzureQueueRetryLimit; i++ {
-		if i > 0 {
-			conn.logger.Info("retrying function invocation", zap.Int("retry", i), zap.String("function_url", sub.functionURL))
+	data, ceHeaders := messageQueue.CloudEvent(sub.trigger, message.ID(), time.Time{}, sub.contentType, data)
+
+	conn.logger.Info("making HTTP request to invoke function", zap.String("function_url", functionURL))
+
+	result := sub.retryPolicy.Invoke(context.Background(), func(ctx context.Context, attempt int) (int, []byte, error) {
+		if attempt > 0 {
+			conn.logger.Info("retrying function invocation", zap.Int("retry", attempt), zap.String("function_url", functionURL))
 		}
-		request, err := http.NewRequest("POST", sub.functionURL, bytes.NewReader(message.Bytes()))
+		request, err := http.NewRequestWithContext(ctx, "POST", functionURL, bytes.NewReader(data))
 		if err != nil {
-			conn.logger.Error("failed to create HTTP request to invoke function", zap.Error(err), zap.String("function_url", sub.functionURL))
-			continue
+			conn.logger.Error("failed to create HTTP request to invoke function", zap.Error(err), zap.String("function_url", functionURL))
+			return 0, nil, err
 		}
 
 		request.Header.Set("X-Fission-MQTrigger-Topic", sub.queueName)
 		if len(sub.outputQueueName) > 0 {
 			request.Header.Set("X-Fission-MQTrigger-RespTopic", sub.outputQueueName)
 		}
-		if i > 0 {
-			request.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(i))
+		if attempt > 0 {
+			request.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(attempt))
 		}
 		request.Header.Set("Content-Type", sub.contentType)
+		for k, v := range ceHeaders {
+			request.Header.Set(k, v)
+		}
 
 		response, err := conn.httpClient.Do(request)
 		if err != nil {
-			conn.logger.Error("sending function invocation request failed", zap.Error(err), zap.String("function_url", sub.functionURL))
-			continue
+			conn.logger.Error("sending function invocation request failed", zap.Error(err), zap.String("function_url", functionURL))
+			return 0, nil, err
 		}
 		defer response.Body.Close()
 
 		body, err := ioutil.ReadAll(response.Body)
 		if err != nil {
-			conn.logger.Error("failed to read response body from function invocation", zap.Error(err), zap.String("function_url", sub.functionURL))
-			continue
+			conn.logger.Error("failed to read response body from function invocation", zap.Error(err), zap.String("function_url", functionURL))
+			return response.StatusCode, nil, err
 		}
 
 		if response.StatusCode < 200 || response.StatusCode >= 300 {
 			conn.logger.Error("function invocation request returned a failure status code",
-				zap.String("function_url", sub.functionURL),
+				zap.String("function_url", functionURL),
 				zap.String("body", string(body)),
 				zap.Int("status_code", response.StatusCode))
-			continue
-		}
-
-		if len(sub.outputQueueName) > 0 {
-			outputQueue := conn.service.GetQueue(sub.outputQueueName)
-			err = outputQueue.Create(nil)
-			if err != nil {
-				conn.logger.Error("failed to create output queue",
-					zap.Error(err),
-					zap.String("output_queue", sub.outputQueueName),
-					zap.String("function_url", sub.functionURL))
-				return
-			}
-
-			outputMessage := outputQueue.NewMessage(string(body))
-			err = outputMessage.Put(nil)
-			if err != nil {
-				conn.logger.Error("failed to post response body from function invocation to output queue",
-					zap.String("output_queue", sub.outputQueueName),
-					zap.String("function_url", sub.functionURL))
-				return
-			}
+			return response.StatusCode, body, nil
 		}
+		// any 2xx status code is a success
+		return http.StatusOK, body, nil
+	})
+	sub.Invoked(result)
 
-		// Function invocation was successful
+	if result.Succeeded() {
+		putOutputMessage(conn, sub, functionURL, result.Body)
 		return
 	}
 
 	conn.logger.Error("function invocation retired too many times - moving message to poison queue",
-		zap.Int("retry_limit", AzureQueueRetryLimit),
-		zap.String("function_url", sub.functionURL))
+		zap.Int("attempts", result.Attempts),
+		zap.Int("status_code", result.StatusCode),
+		zap.String("error", result.Error()),
+		zap.String("function_url", functionURL))
+	putPoisonMessage(conn, sub, functionURL, message, result)
+}
+
+// putOutputMessage posts the response body of a function invocation to
+// the output queue of the subscription, if it has one.
+func putOutputMessage(conn AzureStorageConnection, sub *AzureQueueSubscription, functionURL string, body []byte) {
+	if len(sub.outputQueueName) == 0 {
+		return
+	}
 
+	outputQueue := conn.service.GetQueue(sub.outputQueueName)
+	err := outputQueue.Create(nil)
+	if err != nil {
+		conn.logger.Error("failed to create output queue",
+			zap.Error(err),
+			zap.String("output_queue", sub.outputQueueName),
+			zap.String("function_url", functionURL))
+		return
+	}
+
+	outputMessage := outputQueue.NewMessage(string(body))
+	err = outputMessage.Put(nil)
+	if err != nil {
+		conn.logger.Error("failed to post response body from function invocation to output queue",
+			zap.String("output_queue", sub.outputQueueName),
+			zap.String("function_url", functionURL))
+	}
+}
+
+// putPoisonMessage moves a message the function failed to process to
+// the poison queue, along with the failure metadata of the result.
+func putPoisonMessage(conn AzureStorageConnection, sub *AzureQueueSubscription, functionURL string, message AzureMessage, result messageQueue.InvocationResult) {
 	poisonQueueName := sub.queueName + AzurePoisonQueueSuffix
 	poisonQueue := conn.service.GetQueue(poisonQueueName)
 	err := poisonQueue.Create(nil)
@@ -404,18 +489,25 @@ func invokeTriggeredFunction(conn AzureStorageConnection, sub *AzureQueueSubscri
 		conn.logger.Error("failed to create poison queue",
 			zap.Error(err),
 			zap.String("poison_queue_name", poisonQueueName),
-			zap.String("function_url", sub.functionURL))
+			zap.String("function_url", functionURL))
 		return
 	}
 
-	poisonMessage := poisonQueue.NewMessage(string(message.Bytes()))
+	// queue messages have no headers
+	envelope, err := result.FailureEnvelope(message.Bytes())
+	if err != nil {
+		conn.logger.Error("failed to encode function invocation failure",
+			zap.Error(err),
+			zap.String("function_url", functionURL))
+		return
+	}
+	poisonMessage := poisonQueue.NewMessage(string(envelope))
 	err = poisonMessage.Put(nil)
 	if err != nil {
 		conn.logger.Error("failed to post response body from function invocation failure poison queue",
 			zap.Error(err),
 			zap.String("poison_queue_name", poisonQueueName),
-			zap.String("function_url", sub.functionURL))
-		return
+			zap.String("function_url", functionURL))
 	}
 }
 
@@ -78,6 +78,10 @@ type azureMessageMock struct {
 	mock.Mock
 }
 
+func (m *azureMessageMock) ID() string {
+	return "message"
+}
+
 func (m *azureMessageMock) Bytes() []byte {
 	args := m.Called()
 	return args.Get(0).([]byte)
@@ -294,7 +298,9 @@ func TestAzureStorageQueuePoisonMessage(t *testing.T) {
 			},
 		),
 	).Return(nil)
-	poisonQueue.On("NewMessage", MessageBody).Return(poisonMessage).Once()
+	// the poison message carries the failure of the last attempt
+	poisonBody := `{"headers":{"X-Fission-MQTrigger-Attempts":"4","X-Fission-MQTrigger-Error":"request returned failure: 403","X-Fission-MQTrigger-StatusCode":"403"},"body":"aW5wdXQ="}`
+	poisonQueue.On("NewMessage", poisonBody).Return(poisonMessage).Once()
 
 	// Mock the queue service to return the input queue
 	service := new(azureQueueServiceMock)
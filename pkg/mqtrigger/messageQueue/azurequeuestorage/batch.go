@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azurequeuestorage
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"go.uber.org/zap"
+
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+// pollAzureQueueBatches collects the messages of the queue into batches
+// and invokes the function with each batch, until the queue is empty.
+func pollAzureQueueBatches(conn AzureStorageConnection, sub *AzureQueueSubscription, wg *sync.WaitGroup) {
+	for {
+		messages, err := collectAzureQueueBatch(conn, sub)
+		if len(messages) > 0 {
+			wg.Add(1)
+			go func(messages []AzureMessage) {
+				defer wg.Done()
+				invokeTriggeredFunctionBatch(conn, sub, messages)
+			}(messages)
+		}
+		if err != nil {
+			conn.logger.Error("failed to retrieve messages from Azure storage queue", zap.Error(err), zap.String("queue", sub.queueName))
+			sub.Error(err)
+			return
+		}
+		if len(messages) < sub.batch.MaxSize {
+			return
+		}
+	}
+}
+
+// collectAzureQueueBatch returns the messages of a batch. It fetches
+// messages until the batch is full, or until the queue is empty and the
+// max wait since the first message is over.
+func collectAzureQueueBatch(conn AzureStorageConnection, sub *AzureQueueSubscription) ([]AzureMessage, error) {
+	var (
+		batch []AzureMessage
+		start time.Time
+	)
+
+	// the messages must stay invisible while the batch is collected
+	visibilityTimeout := AzureMessageVisibilityTimeout + sub.batch.MaxWait
+
+	for len(batch) < sub.batch.MaxSize {
+		count := sub.batch.MaxSize - len(batch)
+		if count > AzureMaxMessageFetchCount {
+			count = AzureMaxMessageFetchCount
+		}
+		messages, err := sub.queue.GetMessages(&storage.GetMessagesOptions{
+			NumOfMessages:     count,
+			VisibilityTimeout: int(visibilityTimeout / time.Second),
+		})
+		if err != nil {
+			return batch, err
+		}
+		if len(messages) > 0 {
+			if len(batch) == 0 {
+				start = time.Now()
+			}
+			batch = append(batch, messages...)
+			continue
+		}
+
+		wait := sub.batch.MaxWait - time.Since(start)
+		if len(batch) == 0 || wait <= 0 {
+			break
+		}
+		if wait > AzureBatchPollingInterval {
+			wait = AzureBatchPollingInterval
+		}
+		time.Sleep(wait)
+	}
+	return batch, nil
+}
+
+// invokeTriggeredFunctionBatch invokes the function with a batch of
+// messages. The responses are posted to the output queue, and the
+// messages that failed are moved to the poison queue.
+func invokeTriggeredFunctionBatch(conn AzureStorageConnection, sub *AzureQueueSubscription, messages []AzureMessage) {
+	functionURL, err := messageQueue.FunctionURL(conn.routerURL, sub.trigger)
+	if err != nil {
+		// the messages are visible again after the visibility timeout
+		conn.logger.Error("failed to get function URL", zap.Error(err), zap.String("queue", sub.queueName))
+		sub.Error(err)
+		return
+	}
+
+	conn.logger.Info("making HTTP request to invoke function with batch", zap.String("function_url", functionURL), zap.Int("size", len(messages)))
+
+	batch := make([]messageQueue.Message, len(messages))
+	for i, message := range messages {
+		batch[i] = messageQueue.Message{
+			ID:      message.ID(),
+			Headers: map[string]string{"Content-Type": sub.contentType},
+			Body:    message.Bytes(),
+		}
+	}
+
+	result, err := sub.schema.InvokeBatch(context.Background(), sub.retryPolicy, batch, func(ctx context.Context, attempt int, batch []messageQueue.Message) (int, []byte, error) {
+		if attempt > 0 {
+			conn.logger.Info("retrying function invocation", zap.Int("retry", attempt), zap.String("function_url", functionURL))
+		}
+		request, err := sub.batch.NewBatchRequest(ctx, functionURL, messageQueue.CloudEventBatch(sub.trigger, "", batch))
+		if err != nil {
+			conn.logger.Error("failed to create HTTP request to invoke function", zap.Error(err), zap.String("function_url", functionURL))
+			return 0, nil, err
+		}
+
+		request.Header.Set("X-Fission-MQTrigger-Topic", sub.queueName)
+		if len(sub.outputQueueName) > 0 {
+			request.Header.Set("X-Fission-MQTrigger-RespTopic", sub.outputQueueName)
+		}
+		if attempt > 0 {
+			request.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(attempt))
+		}
+
+		response, err := conn.httpClient.Do(request)
+		if err != nil {
+			conn.logger.Error("sending function invocation request failed", zap.Error(err), zap.String("function_url", functionURL))
+			return 0, nil, err
+		}
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			conn.logger.Error("failed to read response body from function invocation", zap.Error(err), zap.String("function_url", functionURL))
+			return response.StatusCode, nil, err
+		}
+
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			conn.logger.Error("function invocation request returned a failure status code",
+				zap.String("function_url", functionURL),
+				zap.String("body", string(body)),
+				zap.Int("status_code", response.StatusCode))
+			return response.StatusCode, body, nil
+		}
+		// any 2xx status code is a success, except for the partial
+		// failures of the batch
+		if response.StatusCode == http.StatusMultiStatus {
+			return response.StatusCode, body, nil
+		}
+		return http.StatusOK, body, nil
+	})
+	if err != nil {
+		// the messages are visible again after the visibility timeout
+		conn.logger.Error("failed to read the schema of message", zap.Error(err), zap.String("queue", sub.queueName))
+		sub.Error(err)
+		return
+	}
+	defer func() {
+		for _, message := range messages {
+			err := message.Delete(nil)
+			if err != nil {
+				conn.logger.Error(err.Error())
+			}
+		}
+	}()
+	sub.InvokedBatch(result)
+
+	for _, body := range result.Responses {
+		putOutputMessage(conn, sub, functionURL, body)
+	}
+
+	for i, msgResult := range result.Results {
+		if msgResult.Succeeded() {
+			continue
+		}
+		conn.logger.Error("function invocation retired too many times - moving message of batch to poison queue",
+			zap.Int("index", i),
+			zap.Int("attempts", msgResult.Attempts),
+			zap.Int("status_code", msgResult.StatusCode),
+			zap.String("error", msgResult.Error()),
+			zap.String("function_url", functionURL))
+		putPoisonMessage(conn, sub, functionURL, messages[i], msgResult)
+	}
+}
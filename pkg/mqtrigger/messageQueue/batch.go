@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+const (
+	// BatchSizeHeader is the number of messages of a batch.
+	BatchSizeHeader = "X-Fission-MQTrigger-Batch-Size"
+
+	// MessageIDHeader is the ID of a message in the part of a
+	// multipart batch.
+	MessageIDHeader = "X-Fission-MQTrigger-Message-Id"
+)
+
+// errPartialFailure is the error of the messages of a batch that the
+// function reported as failed.
+var errPartialFailure = errors.New("function reported the message of the batch as failed")
+
+type (
+	// BatchConfig is the batch configuration of a trigger, see
+	// fv1.MessageQueueBatch.
+	BatchConfig struct {
+		MaxSize int
+		MaxWait time.Duration
+		Format  fv1.BatchFormat
+	}
+
+	// Message is a message of a batch.
+	Message struct {
+		ID      string
+		Headers map[string]string
+		Body    []byte
+		// Time is when the message was published, it's zero if unknown.
+		Time time.Time
+	}
+
+	// BatchInvokeFunc makes an attempt to invoke a function with a
+	// batch, attempt is 0 for the first one. It returns the status code
+	// and body of the response, or an error if there's no response.
+	BatchInvokeFunc func(ctx context.Context, attempt int, batch []Message) (statusCode int, body []byte, err error)
+
+	// BatchResult is the outcome of invoking a function with a batch.
+	BatchResult struct {
+		// Results has the result of each message of the batch.
+		Results []InvocationResult
+		// Responses has the body of the responses to the invocations
+		// that succeeded for all their messages.
+		Responses [][]byte
+	}
+
+	// batchMessage is a message of a JSON batch. Body is base64
+	// encoded if it isn't valid UTF-8.
+	batchMessage struct {
+		ID       string            `json:"id,omitempty"`
+		Headers  map[string]string `json:"headers,omitempty"`
+		Body     string            `json:"body"`
+		Encoding string            `json:"encoding,omitempty"`
+	}
+
+	// batchFailures is the body of a 207 Multi-Status response to a
+	// batch, listing the indexes of the failed messages.
+	batchFailures struct {
+		Failed []int `json:"failed"`
+	}
+)
+
+// MakeBatchConfig returns the batch configuration of a trigger, or nil
+// if the trigger doesn't batch messages.
+func MakeBatchConfig(trigger *fv1.MessageQueueTrigger) *BatchConfig {
+	batch := trigger.Spec.Batch
+	if batch == nil {
+		return nil
+	}
+	config := &BatchConfig{
+		MaxSize: batch.MaxSize,
+		MaxWait: time.Duration(batch.MaxWait) * time.Millisecond,
+		Format:  batch.Format,
+	}
+	if config.MaxSize < 1 {
+		config.MaxSize = 1
+	}
+	if len(config.Format) == 0 {
+		config.Format = fv1.BatchFormatJSON
+	}
+	return config
+}
+
+// NewBatchRequest returns a request delivering a batch to url.
+func (config BatchConfig) NewBatchRequest(ctx context.Context, url string, batch []Message) (*http.Request, error) {
+	body, contentType, err := config.encode(batch)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode batch")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create HTTP request to invoke function")
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(BatchSizeHeader, strconv.Itoa(len(batch)))
+	return req, nil
+}
+
+// encode returns the body of a batch and its content type.
+func (config BatchConfig) encode(batch []Message) ([]byte, string, error) {
+	if config.Format == fv1.BatchFormatMultipart {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for _, msg := range batch {
+			header := make(textproto.MIMEHeader)
+			for k, v := range msg.Headers {
+				header.Set(k, v)
+			}
+			if len(msg.ID) > 0 {
+				header.Set(MessageIDHeader, msg.ID)
+			}
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				return nil, "", err
+			}
+			_, err = part.Write(msg.Body)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		err := writer.Close()
+		if err != nil {
+			return nil, "", err
+		}
+		return body.Bytes(), "multipart/mixed; boundary=" + writer.Boundary(), nil
+	}
+
+	msgs := make([]batchMessage, len(batch))
+	for i, msg := range batch {
+		msgs[i] = batchMessage{
+			ID:      msg.ID,
+			Headers: msg.Headers,
+		}
+		if utf8.Valid(msg.Body) {
+			msgs[i].Body = string(msg.Body)
+		} else {
+			msgs[i].Body = base64.StdEncoding.EncodeToString(msg.Body)
+			msgs[i].Encoding = "base64"
+		}
+	}
+	body, err := json.Marshal(msgs)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, "application/json", nil
+}
+
+// InvokeBatch calls invoke with a batch, and again with the messages
+// that failed as set by the retry policy. A response with 207
+// Multi-Status reports the failed messages, the others succeeded.
+func (policy RetryPolicy) InvokeBatch(ctx context.Context, batch []Message, invoke BatchInvokeFunc) BatchResult {
+	result := BatchResult{Results: make([]InvocationResult, len(batch))}
+
+	pending := make([]int, len(batch))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	last := policy.Invoke(ctx, func(ctx context.Context, attempt int) (int, []byte, error) {
+		msgs := make([]Message, len(pending))
+		for i, index := range pending {
+			msgs[i] = batch[index]
+		}
+
+		statusCode, body, err := invoke(ctx, attempt, msgs)
+		if err != nil || (statusCode != http.StatusOK && statusCode != http.StatusMultiStatus) {
+			return statusCode, body, err
+		}
+
+		failed := make(map[int]bool)
+		if statusCode == http.StatusMultiStatus {
+			var failures batchFailures
+			err = json.Unmarshal(body, &failures)
+			if err != nil {
+				return statusCode, body, errors.Wrap(err, "error parsing failed messages of batch")
+			}
+			for _, index := range failures.Failed {
+				if index < 0 || index >= len(msgs) {
+					return statusCode, body, errors.Errorf("failed message index %v out of batch of %v messages", index, len(msgs))
+				}
+				failed[index] = true
+			}
+		} else {
+			result.Responses = append(result.Responses, body)
+		}
+
+		var stillPending []int
+		for i, index := range pending {
+			if failed[i] {
+				stillPending = append(stillPending, index)
+				continue
+			}
+			result.Results[index] = InvocationResult{Attempts: attempt + 1, StatusCode: http.StatusOK}
+		}
+		pending = stillPending
+		if len(pending) > 0 {
+			return statusCode, body, errPartialFailure
+		}
+		return http.StatusOK, body, nil
+	})
+
+	for _, index := range pending {
+		result.Results[index] = last
+	}
+	return result
+}
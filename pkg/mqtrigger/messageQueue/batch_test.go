@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+var testBatch = []Message{
+	{ID: "1", Headers: map[string]string{"Content-Type": "text/plain"}, Body: []byte("hello")},
+	{ID: "2", Body: []byte{0xff, 0xfe}},
+}
+
+func TestMakeBatchConfig(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{}
+	require.Nil(t, MakeBatchConfig(trigger))
+
+	trigger.Spec.Batch = &fv1.MessageQueueBatch{}
+	require.Equal(t, &BatchConfig{MaxSize: 1, Format: fv1.BatchFormatJSON}, MakeBatchConfig(trigger))
+
+	trigger.Spec.Batch = &fv1.MessageQueueBatch{MaxSize: 10, MaxWait: 500, Format: fv1.BatchFormatMultipart}
+	require.Equal(t, &BatchConfig{
+		MaxSize: 10,
+		MaxWait: 500 * time.Millisecond,
+		Format:  fv1.BatchFormatMultipart,
+	}, MakeBatchConfig(trigger))
+}
+
+func TestNewBatchRequestJSON(t *testing.T) {
+	config := BatchConfig{MaxSize: 2, Format: fv1.BatchFormatJSON}
+	req, err := config.NewBatchRequest(context.Background(), "http://router/fn", testBatch)
+	require.NoError(t, err)
+	require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	require.Equal(t, "2", req.Header.Get(BatchSizeHeader))
+
+	var msgs []batchMessage
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(body, &msgs))
+	require.Equal(t, []batchMessage{
+		{ID: "1", Headers: map[string]string{"Content-Type": "text/plain"}, Body: "hello"},
+		{ID: "2", Body: "//4=", Encoding: "base64"},
+	}, msgs)
+}
+
+func TestNewBatchRequestMultipart(t *testing.T) {
+	config := BatchConfig{MaxSize: 2, Format: fv1.BatchFormatMultipart}
+	req, err := config.NewBatchRequest(context.Background(), "http://router/fn", testBatch)
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(req.Body, params["boundary"])
+	for _, msg := range testBatch {
+		part, err := reader.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, msg.ID, part.Header.Get(MessageIDHeader))
+		require.Equal(t, msg.Headers["Content-Type"], part.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(part)
+		require.NoError(t, err)
+		require.Equal(t, msg.Body, body)
+	}
+	_, err = reader.NextPart()
+	require.Error(t, err)
+}
+
+func TestInvokeBatch(t *testing.T) {
+	batch := []Message{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	// the function fails the second message once, then the third one
+	// every time
+	var sent [][]string
+	policy := RetryPolicy{MaxRetries: 2}
+	result := policy.InvokeBatch(context.Background(), batch, func(ctx context.Context, attempt int, batch []Message) (int, []byte, error) {
+		var ids []string
+		var failed []int
+		for i, msg := range batch {
+			ids = append(ids, msg.ID)
+			if msg.ID == "c" || (msg.ID == "b" && attempt == 0) {
+				failed = append(failed, i)
+			}
+		}
+		sent = append(sent, ids)
+		body, err := json.Marshal(batchFailures{Failed: failed})
+		require.NoError(t, err)
+		return http.StatusMultiStatus, body, nil
+	})
+
+	require.Equal(t, [][]string{{"a", "b", "c"}, {"b", "c"}, {"c"}}, sent)
+	require.Len(t, result.Results, 3)
+	require.True(t, result.Results[0].Succeeded())
+	require.Equal(t, 1, result.Results[0].Attempts)
+	require.True(t, result.Results[1].Succeeded())
+	require.Equal(t, 2, result.Results[1].Attempts)
+	require.False(t, result.Results[2].Succeeded())
+	require.Equal(t, 3, result.Results[2].Attempts)
+	require.Equal(t, http.StatusMultiStatus, result.Results[2].StatusCode)
+	require.Empty(t, result.Responses)
+
+	// a batch that succeeds has its response
+	result = policy.InvokeBatch(context.Background(), batch, func(ctx context.Context, attempt int, batch []Message) (int, []byte, error) {
+		return http.StatusOK, []byte("done"), nil
+	})
+	for _, msgResult := range result.Results {
+		require.True(t, msgResult.Succeeded())
+	}
+	require.Equal(t, [][]byte{[]byte("done")}, result.Responses)
+
+	// an invalid index fails the whole batch
+	result = RetryPolicy{}.InvokeBatch(context.Background(), batch, func(ctx context.Context, attempt int, batch []Message) (int, []byte, error) {
+		return http.StatusMultiStatus, []byte(`{"failed": [3]}`), nil
+	})
+	for _, msgResult := range result.Results {
+		require.False(t, msgResult.Succeeded())
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"time"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/cloudevents"
+)
+
+// CloudEvent returns the body and headers of a request delivering a
+// message of a trigger as a CloudEvent. id identifies the message in the
+// topic, published is when it was published or zero if unknown. The body
+// is data and there are no headers if the trigger doesn't send
+// CloudEvents. The headers are set after the X-Fission-MQTrigger ones,
+// they include the Content-Type header.
+func CloudEvent(trigger *fv1.MessageQueueTrigger, id string, published time.Time, contentType string, data []byte) ([]byte, map[string]string) {
+	if len(trigger.Spec.CloudEvents) == 0 {
+		return data, nil
+	}
+	return cloudevents.Encode(trigger.Spec.CloudEvents, cloudevents.Event{
+		ID:              id,
+		Source:          cloudevents.Source("messagequeuetriggers", trigger.ObjectMeta),
+		Type:            cloudevents.TypeMessage,
+		Subject:         trigger.Spec.Topic,
+		Time:            published,
+		DataContentType: contentType,
+		Data:            data,
+	})
+}
+
+// CloudEventBatch returns the messages of a batch as CloudEvents, or
+// the batch as is if the trigger doesn't send CloudEvents. The event
+// IDs are the message IDs after idPrefix, and the data content types
+// their Content-Type headers.
+func CloudEventBatch(trigger *fv1.MessageQueueTrigger, idPrefix string, batch []Message) []Message {
+	if len(trigger.Spec.CloudEvents) == 0 {
+		return batch
+	}
+	events := make([]Message, len(batch))
+	for i, msg := range batch {
+		body, ceHeaders := CloudEvent(trigger, idPrefix+msg.ID, msg.Time, msg.Headers["Content-Type"], msg.Body)
+		headers := make(map[string]string, len(msg.Headers)+len(ceHeaders))
+		for k, v := range msg.Headers {
+			if k != "Content-Type" {
+				headers[k] = v
+			}
+		}
+		for k, v := range ceHeaders {
+			headers[k] = v
+		}
+		events[i] = Message{ID: msg.ID, Headers: headers, Body: body, Time: msg.Time}
+	}
+	return events
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/cloudevents"
+)
+
+func cloudEventsTrigger(mode fv1.CloudEventsMode) *fv1.MessageQueueTrigger {
+	return &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			Topic:       "orders",
+			CloudEvents: mode,
+		},
+	}
+}
+
+func TestCloudEvent(t *testing.T) {
+	body, headers := CloudEvent(cloudEventsTrigger(""), "1", time.Time{}, "text/plain", []byte("hello"))
+	require.Equal(t, "hello", string(body))
+	require.Nil(t, headers)
+
+	published := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	body, headers = CloudEvent(cloudEventsTrigger(fv1.CloudEventsModeBinary), "1", published, "text/plain", []byte("hello"))
+	require.Equal(t, "hello", string(body))
+	require.Equal(t, "1", headers["ce-id"])
+	require.Equal(t, "/apis/fission.io/v1/namespaces/default/messagequeuetriggers/orders", headers["ce-source"])
+	require.Equal(t, cloudevents.TypeMessage, headers["ce-type"])
+	require.Equal(t, "orders", headers["ce-subject"])
+	require.Equal(t, "2021-06-01T12:00:00Z", headers["ce-time"])
+	require.Equal(t, "text/plain", headers["Content-Type"])
+}
+
+func TestCloudEventBatch(t *testing.T) {
+	require.Equal(t, testBatch, CloudEventBatch(cloudEventsTrigger(""), "0-", testBatch))
+
+	events := CloudEventBatch(cloudEventsTrigger(fv1.CloudEventsModeStructured), "0-", testBatch)
+	require.Len(t, events, len(testBatch))
+	for i, event := range events {
+		require.Equal(t, testBatch[i].ID, event.ID)
+		require.Equal(t, cloudevents.StructuredContentType, event.Headers["Content-Type"])
+		require.Contains(t, string(event.Body), `"id":"0-`+testBatch[i].ID+`"`)
+	}
+	require.Contains(t, string(events[0].Body), `"data":"hello"`)
+	require.Contains(t, string(events[1].Body), `"data_base64":"//4="`)
+	// the batch isn't modified
+	require.Equal(t, "text/plain", testBatch[0].Headers["Content-Type"])
+}
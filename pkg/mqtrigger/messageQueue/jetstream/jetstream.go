@@ -0,0 +1,519 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/mqtrigger/factory"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
+	"github.com/fission/fission/pkg/mqtrigger/validator"
+)
+
+func init() {
+	factory.Register(fv1.MessageQueueTypeJetStream, &Factory{})
+	validator.Register(fv1.MessageQueueTypeJetStream, IsTopicValid)
+}
+
+const (
+	// ackWaitGrace is added to the function timeout for the ack wait
+	// of the consumer, so that messages aren't redelivered while the
+	// function is still handling them.
+	ackWaitGrace = 10 * time.Second
+
+	// fetchBatch is the number of messages fetched and handled at once.
+	fetchBatch = 10
+
+	// fetchWait is how long a fetch waits for messages.
+	fetchWait = 5 * time.Second
+
+	// streamMetadataKey is the trigger metadata key to bind the
+	// consumer to a stream, instead of looking the stream up by topic.
+	streamMetadataKey = "stream"
+)
+
+type (
+	JetStream struct {
+		logger        *zap.Logger
+		conn          *nats.Conn
+		js            nats.JetStreamContext
+		routerUrl     string
+		fissionClient *crd.FissionClient
+		schemas       *schema.Loader
+	}
+
+	// subscription is the pull consumer of a trigger.
+	subscription struct {
+		messageQueue.StatusRecorder
+		trigger fv1.MessageQueueTrigger
+		schema  *schema.Schema
+		stream  string
+		durable string
+		sub     *nats.Subscription
+		stop    chan struct{}
+		done    chan struct{}
+	}
+
+	Factory struct{}
+)
+
+func (factory *Factory) Create(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messageQueue.MessageQueue, error) {
+	return New(logger, mqCfg, routerUrl)
+}
+
+// New connects to the NATS server at mqCfg.Url, credentials can be
+// part of the URL.
+func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messageQueue.MessageQueue, error) {
+	if len(routerUrl) == 0 || len(mqCfg.Url) == 0 {
+		return nil, errors.New("the router URL or MQ URL is empty")
+	}
+
+	conn, err := nats.Connect(mqCfg.Url,
+		nats.Name("fission-mqtrigger"),
+		// keep reconnecting, subscriptions resume once connected again
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("disconnected from NATS server", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("reconnected to NATS server", zap.String("url", conn.ConnectedUrl()))
+		}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error connecting to NATS server")
+	}
+
+	// the function timeouts of triggers are looked up to
+	// configure the ack wait of their consumers
+	fissionClient, _, _, _, err := crd.MakeFissionClient()
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "failed to get fission client")
+	}
+
+	jetstream, err := newJetStream(logger, conn, routerUrl, fissionClient)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	jetstream.schemas = schema.MakeLoader(mqCfg.KubeClient)
+	return jetstream, nil
+}
+
+// newJetStream makes a message queue using an existing connection.
+// fissionClient may be nil, the default function timeout is used then.
+func newJetStream(logger *zap.Logger, conn *nats.Conn, routerUrl string, fissionClient *crd.FissionClient) (*JetStream, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting JetStream context")
+	}
+	return &JetStream{
+		logger:        logger.Named("jetstream"),
+		conn:          conn,
+		js:            js,
+		routerUrl:     routerUrl,
+		fissionClient: fissionClient,
+	}, nil
+}
+
+func (jetstream *JetStream) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Subscription, error) {
+	if _, err := messageQueue.FunctionURL(jetstream.routerUrl, trigger); err != nil {
+		return nil, err
+	}
+	if !IsTopicValid(trigger.Spec.Topic) {
+		return nil, fmt.Errorf("not a valid topic: %q", trigger.Spec.Topic)
+	}
+	msgSchema, err := jetstream.schemas.Load(context.TODO(), trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := trigger.Spec.Metadata[streamMetadataKey]
+	if len(stream) == 0 {
+		stream, err = jetstream.streamForSubject(trigger.Spec.Topic)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// messages are marked in progress before each attempt, the ack
+	// wait covers an attempt and the backoff before it
+	timeout := jetstream.functionTimeout(trigger)
+	ackWait := timeout + messageQueue.MakeRetryPolicy(trigger).LongestBackoff() + ackWaitGrace
+	maxDeliver := maxDeliver(trigger)
+	durable := durableName(trigger)
+
+	// consumer settings can't be changed, so a consumer left by an
+	// earlier version of the trigger is replaced if they differ.
+	info, err := jetstream.js.ConsumerInfo(stream, durable)
+	if err == nil && (info.Config.FilterSubject != trigger.Spec.Topic ||
+		info.Config.AckWait != ackWait || info.Config.MaxDeliver != maxDeliver) {
+		jetstream.logger.Info("replacing consumer of updated trigger",
+			zap.String("trigger", trigger.ObjectMeta.Name), zap.String("consumer", durable))
+		err = jetstream.js.DeleteConsumer(stream, durable)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error deleting consumer %v", durable)
+		}
+	}
+
+	sub, err := jetstream.js.PullSubscribe(trigger.Spec.Topic, durable,
+		nats.BindStream(stream),
+		nats.AckExplicit(),
+		nats.AckWait(ackWait),
+		nats.MaxDeliver(maxDeliver),
+		// like NATS streaming subscriptions, new triggers only
+		// get messages published after they were created
+		nats.DeliverNew(),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error subscribing to %q", trigger.Spec.Topic)
+	}
+
+	s := &subscription{
+		trigger: *trigger,
+		schema:  msgSchema,
+		stream:  stream,
+		durable: durable,
+		sub:     sub,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go jetstream.consume(s, timeout, maxDeliver)
+
+	jetstream.logger.Info("subscribed to JetStream consumer",
+		zap.String("trigger", trigger.ObjectMeta.Name),
+		zap.String("stream", stream),
+		zap.String("consumer", durable),
+		zap.Duration("ack_wait", ackWait),
+		zap.Int("max_deliver", maxDeliver))
+	return s, nil
+}
+
+// Unsubscribe stops fetching messages. The durable consumer is kept for
+// the next version of the trigger, unless the trigger was deleted.
+func (jetstream *JetStream) Unsubscribe(triggerSub messageQueue.Subscription) error {
+	s := triggerSub.(*subscription)
+	close(s.stop)
+	<-s.done
+
+	// draining keeps durable consumers
+	err := s.sub.Drain()
+	if err != nil {
+		return errors.Wrap(err, "error draining subscription")
+	}
+
+	if jetstream.triggerDeleted(&s.trigger) {
+		err = jetstream.js.DeleteConsumer(s.stream, s.durable)
+		if err != nil {
+			return errors.Wrapf(err, "error deleting consumer %v", s.durable)
+		}
+		jetstream.logger.Info("deleted consumer of deleted trigger", zap.String("consumer", s.durable))
+	}
+	return nil
+}
+
+// consume fetches and handles the messages of a subscription until
+// it's unsubscribed.
+func (jetstream *JetStream) consume(s *subscription, timeout time.Duration, maxDeliver int) {
+	defer close(s.done)
+
+	client := &http.Client{Timeout: timeout}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		msgs, err := s.sub.Fetch(fetchBatch, nats.MaxWait(fetchWait))
+		if err != nil {
+			if err == nats.ErrTimeout {
+				continue
+			}
+			jetstream.logger.Error("error fetching messages", zap.Error(err), zap.String("trigger", s.trigger.ObjectMeta.Name))
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for _, msg := range msgs {
+			wg.Add(1)
+			go func(msg *nats.Msg) {
+				defer wg.Done()
+				jetstream.handle(client, s, msg, maxDeliver)
+			}(msg)
+		}
+		wg.Wait()
+	}
+}
+
+// handle invokes the function with a message, retrying as set by the
+// retry policy of the trigger. The message is acked and the response
+// published if the function succeeds. Otherwise the last error response
+// is published to the error topic and the message is terminated.
+// Messages are only redelivered if they aren't acked in time, e.g. when
+// the trigger crashes, up to maxDeliver times.
+func (jetstream *JetStream) handle(client *http.Client, s *subscription, msg *nats.Msg, maxDeliver int) {
+	trigger := &s.trigger
+	url, err := messageQueue.FunctionURL(jetstream.routerUrl, trigger)
+	if err != nil {
+		// redelivered once the ack wait is over
+		jetstream.logger.Error("failed to get function URL", zap.Error(err), zap.String("trigger", trigger.ObjectMeta.Name))
+		s.Error(err)
+		return
+	}
+	logger := jetstream.logger.With(zap.String("trigger", trigger.ObjectMeta.Name), zap.String("function_url", url))
+
+	delivered := 1
+	// the stream sequence identifies the CloudEvent of the message
+	var (
+		eventID   string
+		published time.Time
+	)
+	if meta, err := msg.Metadata(); err == nil {
+		delivered = int(meta.NumDelivered)
+		// the messages of the stream not delivered to the consumer yet
+		s.SetConsumerLag("", int64(meta.NumPending))
+		eventID = strconv.FormatUint(meta.Sequence.Stream, 10)
+		published = meta.Timestamp
+	} else {
+		eventID = uuid.NewV4().String()
+	}
+
+	var result messageQueue.InvocationResult
+	data, err := s.schema.Check(context.Background(), msg.Data)
+	if schema.IsUnavailable(err) {
+		// redelivered once the ack wait expires
+		logger.Error("failed to read the schema of message, leaving it to be redelivered", zap.Error(err))
+		s.Error(err)
+		return
+	}
+	if err != nil {
+		result = schema.InvalidMessageResult(err)
+	} else {
+		contentType := s.schema.ContentType(trigger.Spec.ContentType)
+		data, ceHeaders := messageQueue.CloudEvent(trigger, eventID, published, contentType, data)
+		result = messageQueue.MakeRetryPolicy(trigger).Invoke(context.Background(), func(ctx context.Context, attempt int) (int, []byte, error) {
+			// resets the ack wait of the message
+			if err := msg.InProgress(); err != nil {
+				logger.Warn("failed to mark message in progress", zap.Error(err))
+			}
+			return invoke(ctx, client, url, trigger, contentType, data, ceHeaders, attempt)
+		})
+	}
+	s.Invoked(result)
+	if result.Succeeded() {
+		if len(trigger.Spec.ResponseTopic) > 0 {
+			err := jetstream.publish(nats.NewMsg(trigger.Spec.ResponseTopic), result.Body)
+			if err != nil {
+				logger.Error("failed to publish function invocation response to topic",
+					zap.Error(err), zap.String("topic", trigger.Spec.ResponseTopic))
+				s.Error(err)
+			}
+		}
+		err := msg.Ack()
+		if err != nil {
+			logger.Error("failed to ack message after successful function invocation", zap.Error(err))
+		}
+		return
+	}
+
+	logger.Error("function invocation failed",
+		zap.String("error", result.Error()),
+		zap.Int("attempts", result.Attempts),
+		zap.Int("delivery", delivered),
+		zap.Int("max_deliver", maxDeliver))
+
+	// only the last error response is published
+	if len(trigger.Spec.ErrorTopic) > 0 {
+		body := result.Body
+		if len(body) == 0 {
+			body = []byte(result.Error())
+		}
+		errMsg := nats.NewMsg(trigger.Spec.ErrorTopic)
+		for k, v := range result.FailureHeaders() {
+			errMsg.Header.Set(k, v)
+		}
+		publishErr := jetstream.publish(errMsg, body)
+		if publishErr != nil {
+			logger.Error("failed to publish function invocation error to error topic",
+				zap.Error(publishErr), zap.String("topic", trigger.Spec.ErrorTopic))
+		}
+	}
+	err = msg.Term()
+	if err != nil {
+		logger.Error("failed to terminate message", zap.Error(err))
+	}
+}
+
+// invoke sends a message to the function and returns its response.
+// contentType is the one of the message as returned by the trigger
+// schema, ceHeaders are the CloudEvent headers of the message if any.
+func invoke(ctx context.Context, client *http.Client, url string, trigger *fv1.MessageQueueTrigger, contentType string, data []byte, ceHeaders map[string]string, retry int) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to create HTTP request to invoke function")
+	}
+	req.Header.Set("X-Fission-MQTrigger-Topic", trigger.Spec.Topic)
+	req.Header.Set("X-Fission-MQTrigger-RespTopic", trigger.Spec.ResponseTopic)
+	req.Header.Set("X-Fission-MQTrigger-ErrorTopic", trigger.Spec.ErrorTopic)
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range ceHeaders {
+		req.Header.Set(k, v)
+	}
+	if retry > 0 {
+		req.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(retry))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, errors.Wrap(err, "error reading function invocation response")
+	}
+	return resp.StatusCode, body, nil
+}
+
+// publish publishes a message with data through JetStream, or as a
+// core NATS message if no stream captures its subject.
+func (jetstream *JetStream) publish(msg *nats.Msg, data []byte) error {
+	msg.Data = data
+	_, err := jetstream.js.PublishMsg(msg)
+	if err == nats.ErrNoStreamResponse || err == nats.ErrNoResponders {
+		return jetstream.conn.PublishMsg(msg)
+	}
+	return err
+}
+
+// streamForSubject looks up the stream capturing subject.
+func (jetstream *JetStream) streamForSubject(subject string) (string, error) {
+	req, err := json.Marshal(map[string]string{"subject": subject})
+	if err != nil {
+		return "", err
+	}
+	resp, err := jetstream.conn.Request("$JS.API.STREAM.NAMES", req, fetchWait)
+	if err != nil {
+		return "", errors.Wrap(err, "error looking up stream")
+	}
+	var names struct {
+		Streams []string `json:"streams"`
+		Error   *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	err = json.Unmarshal(resp.Data, &names)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing stream names")
+	}
+	if names.Error != nil {
+		return "", errors.Errorf("error looking up stream: %v", names.Error.Description)
+	}
+	if len(names.Streams) != 1 {
+		return "", errors.Errorf("no stream captures subject %q, set the %q trigger metadata to the stream name", subject, streamMetadataKey)
+	}
+	return names.Streams[0], nil
+}
+
+// functionTimeout returns the timeout of the function of a trigger, the
+// longest one of the functions of a function-weights reference.
+func (jetstream *JetStream) functionTimeout(trigger *fv1.MessageQueueTrigger) time.Duration {
+	fns, err := messageQueue.Functions(context.TODO(), jetstream.fissionClient, trigger)
+	if err != nil {
+		jetstream.logger.Warn("error getting functions of trigger, using the default timeout",
+			zap.Error(err), zap.String("trigger", trigger.ObjectMeta.Name))
+	}
+	return messageQueue.FunctionTimeout(fns)
+}
+
+// triggerDeleted returns true if the trigger no longer exists.
+func (jetstream *JetStream) triggerDeleted(trigger *fv1.MessageQueueTrigger) bool {
+	if jetstream.fissionClient == nil {
+		return false
+	}
+	current, err := jetstream.fissionClient.CoreV1().MessageQueueTriggers(trigger.ObjectMeta.Namespace).
+		Get(context.TODO(), trigger.ObjectMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return k8serrors.IsNotFound(err)
+	}
+	return current.ObjectMeta.UID != trigger.ObjectMeta.UID
+}
+
+// durableName is the name of the consumer of a trigger, it's kept
+// across trigger updates.
+func durableName(trigger *fv1.MessageQueueTrigger) string {
+	return "fission-" + string(trigger.ObjectMeta.UID)
+}
+
+// maxDeliver returns the number of deliveries of a message, the first
+// one and a redelivery for each retry. Retries are made while handling
+// a delivery, redeliveries only happen when a message isn't acked in
+// time.
+func maxDeliver(trigger *fv1.MessageQueueTrigger) int {
+	if trigger.Spec.MaxRetries < 0 {
+		return 1
+	}
+	return trigger.Spec.MaxRetries + 1
+}
+
+// IsTopicValid returns true if topic is a valid NATS subject. Wildcards
+// are allowed, they must be whole tokens and '>' must be the last one.
+func IsTopicValid(topic string) bool {
+	if len(topic) == 0 || strings.ContainsAny(topic, " \t\r\n") {
+		return false
+	}
+	tokens := strings.Split(topic, ".")
+	for i, token := range tokens {
+		if len(token) == 0 {
+			return false
+		}
+		if strings.ContainsAny(token, "*>") && len(token) > 1 {
+			return false
+		}
+		if token == ">" && i != len(tokens)-1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestIsTopicValid(t *testing.T) {
+	for topic, valid := range map[string]bool{
+		"orders":            true,
+		"orders.created":    true,
+		"orders.*":          true,
+		"orders.>":          true,
+		"*.created":         true,
+		"":                  false,
+		"orders.":           false,
+		".orders":           false,
+		"orders..created":   false,
+		"orders.>.created":  false,
+		"orders.cre*ted":    false,
+		"orders created":    false,
+		"orders.created\t":  false,
+		"orders.created.>>": false,
+	} {
+		require.Equal(t, valid, IsTopicValid(topic), "topic %q", topic)
+	}
+}
+
+func TestMaxDeliver(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{}
+	require.Equal(t, 1, maxDeliver(trigger))
+	trigger.Spec.MaxRetries = 3
+	require.Equal(t, 4, maxDeliver(trigger))
+	trigger.Spec.MaxRetries = -1
+	require.Equal(t, 1, maxDeliver(trigger))
+}
+
+// runServer starts an in-process NATS server with JetStream enabled, and
+// returns its URL.
+func runServer(t *testing.T) string {
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+	require.True(t, s.ReadyForConnections(10*time.Second), "NATS server not ready")
+	return s.ClientURL()
+}
+
+func TestJetStream(t *testing.T) {
+	url := runServer(t)
+
+	conn, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:     "FISSION_TEST",
+		Subjects: []string{"fission-test.>"},
+	})
+	require.NoError(t, err)
+
+	// the function fails the first delivery of every message
+	var calls int32
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			require.Empty(t, r.Header.Get("X-Fission-MQTrigger-RetryCount"))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		require.Equal(t, "1", r.Header.Get("X-Fission-MQTrigger-RetryCount"))
+		w.Write(append([]byte("re: "), body...)) // nolint: errcheck
+	}))
+	defer router.Close()
+
+	mq, err := newJetStream(zap.NewNop(), conn, router.URL, nil)
+	require.NoError(t, err)
+
+	responses, err := conn.SubscribeSync("fission-test.responses")
+	require.NoError(t, err)
+
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: types.UID("test-uid")},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			MessageQueueType:  fv1.MessageQueueTypeJetStream,
+			Topic:             "fission-test.requests",
+			ResponseTopic:     "fission-test.responses",
+			MaxRetries:        1,
+		},
+	}
+	sub, err := mq.Subscribe(trigger)
+	require.NoError(t, err)
+
+	_, err = js.Publish("fission-test.requests", []byte("hello"))
+	require.NoError(t, err)
+
+	msg, err := responses.NextMsg(30 * time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "re: hello", string(msg.Data))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	require.NoError(t, mq.Unsubscribe(sub))
+
+	// the consumer is kept for the next version of the trigger
+	info, err := js.ConsumerInfo("FISSION_TEST", durableName(trigger))
+	require.NoError(t, err)
+	require.Equal(t, "fission-test.requests", info.Config.FilterSubject)
+	require.Equal(t, 2, info.Config.MaxDeliver)
+}
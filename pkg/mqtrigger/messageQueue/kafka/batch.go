@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	sarama "github.com/Shopify/sarama"
+	"go.uber.org/zap"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
+)
+
+// collectBatch returns a batch starting with first, and the messages
+// read from queue until the batch is full or the max wait is over.
+func collectBatch(queue <-chan *sarama.ConsumerMessage, first *sarama.ConsumerMessage, config *messageQueue.BatchConfig) []*sarama.ConsumerMessage {
+	msgs := []*sarama.ConsumerMessage{first}
+
+	// without a max wait, only the messages queued already are added
+	var timeout <-chan time.Time
+	if config.MaxWait > 0 {
+		timer := time.NewTimer(config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for len(msgs) < config.MaxSize {
+		if timeout == nil {
+			select {
+			case msg, ok := <-queue:
+				if !ok {
+					return msgs
+				}
+				msgs = append(msgs, msg)
+			default:
+				return msgs
+			}
+			continue
+		}
+
+		select {
+		case msg, ok := <-queue:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		case <-timeout:
+			return msgs
+		}
+	}
+	return msgs
+}
+
+// kafkaBatchHandler invokes the function with a batch of messages, and
+// publishes the responses and the errors of the failed messages. It
+// returns false if the function couldn't be invoked, or if publishing
+// failed until ctx is done, the batch isn't processed then.
+func kafkaBatchHandler(ctx context.Context, kafka *Kafka, producer sarama.SyncProducer, status *messageQueue.StatusRecorder, msgSchema *schema.Schema, trigger *fv1.MessageQueueTrigger, config *messageQueue.BatchConfig, msgs []*sarama.ConsumerMessage) bool {
+	url, err := messageQueue.FunctionURL(kafka.routerUrl, trigger)
+	if err != nil {
+		kafka.logger.Error("failed to get function URL", zap.Error(err), zap.String("trigger", trigger.ObjectMeta.Name))
+		status.Error(err)
+		return false
+	}
+	kafka.logger.Debug("making HTTP request with batch", zap.String("url", url), zap.Int("size", len(msgs)))
+
+	batch := make([]messageQueue.Message, len(msgs))
+	for i, msg := range msgs {
+		headers := make(map[string]string)
+		if kafka.version.IsAtLeast(sarama.V0_11_0_0) {
+			for _, h := range msg.Headers {
+				headers[string(h.Key)] = string(h.Value)
+			}
+		}
+		headers["Content-Type"] = trigger.Spec.ContentType
+		batch[i] = messageQueue.Message{
+			ID:      strconv.FormatInt(msg.Offset, 10),
+			Headers: headers,
+			Body:    msg.Value,
+			Time:    msg.Timestamp,
+		}
+	}
+
+	result, err := msgSchema.InvokeBatch(context.Background(), messageQueue.MakeRetryPolicy(trigger), batch, func(ctx context.Context, attempt int, batch []messageQueue.Message) (int, []byte, error) {
+		batch = messageQueue.CloudEventBatch(trigger, cloudEventIDPrefix(msgs[0].Partition), batch)
+		req, err := config.NewBatchRequest(ctx, url, batch)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("X-Fission-MQTrigger-Topic", trigger.Spec.Topic)
+		req.Header.Set("X-Fission-MQTrigger-RespTopic", trigger.Spec.ResponseTopic)
+		req.Header.Set("X-Fission-MQTrigger-ErrorTopic", trigger.Spec.ErrorTopic)
+		if attempt > 0 {
+			req.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(attempt))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			kafka.logger.Error("sending function invocation request failed",
+				zap.Error(err),
+				zap.String("function_url", url),
+				zap.String("trigger", trigger.ObjectMeta.Name))
+			return 0, nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, body, err
+	})
+	if err != nil {
+		kafka.logger.Error("failed to read the schema of message", zap.Error(err), zap.String("trigger", trigger.ObjectMeta.Name))
+		status.Error(err)
+		return false
+	}
+	status.InvokedBatch(result)
+
+	if len(trigger.Spec.ResponseTopic) > 0 {
+		for _, body := range result.Responses {
+			err := send(ctx, kafka.logger, producer, status, &sarama.ProducerMessage{
+				Topic: trigger.Spec.ResponseTopic,
+				Value: sarama.ByteEncoder(body),
+			})
+			if err != nil {
+				kafka.logger.Warn("failed to publish response body from function invocation to topic",
+					zap.Error(err),
+					zap.String("topic", trigger.Spec.ResponseTopic),
+					zap.String("function_url", url))
+				return false
+			}
+		}
+	}
+
+	for i, msgResult := range result.Results {
+		if msgResult.Succeeded() {
+			continue
+		}
+		kafka.logger.Debug("message of batch failed",
+			zap.Int64("offset", msgs[i].Offset),
+			zap.String("error", msgResult.Error()),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+		errorHeaders := kafka.errorHeaders(trigger, msgResult)
+		if errorHeaders != nil {
+			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte(messageQueue.MessageIDHeader), Value: []byte(batch[i].ID)})
+		}
+		if !errorHandler(ctx, kafka.logger, trigger, producer, status, url, msgResult, errorHeaders) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	sarama "github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+func TestCollectBatch(t *testing.T) {
+	queue := make(chan *sarama.ConsumerMessage, 10)
+	for offset := int64(1); offset < 5; offset++ {
+		queue <- &sarama.ConsumerMessage{Offset: offset}
+	}
+	first := &sarama.ConsumerMessage{Offset: 0}
+
+	// the batch is full
+	msgs := collectBatch(queue, first, &messageQueue.BatchConfig{MaxSize: 3})
+	require.Len(t, msgs, 3)
+	require.Equal(t, int64(0), msgs[0].Offset)
+	require.Equal(t, int64(2), msgs[2].Offset)
+
+	// without a max wait, only the queued messages are added
+	msgs = collectBatch(queue, first, &messageQueue.BatchConfig{MaxSize: 10})
+	require.Len(t, msgs, 3)
+	require.Equal(t, int64(4), msgs[2].Offset)
+
+	// with a max wait, messages are added until it's over
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		queue <- &sarama.ConsumerMessage{Offset: 5}
+	}()
+	start := time.Now()
+	msgs = collectBatch(queue, first, &messageQueue.BatchConfig{MaxSize: 10, MaxWait: 100 * time.Millisecond})
+	require.Len(t, msgs, 2)
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+}
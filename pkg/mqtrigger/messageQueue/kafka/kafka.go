@@ -17,15 +17,17 @@ limitations under the License.
 package kafka
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	sarama "github.com/Shopify/sarama"
 	cluster "github.com/bsm/sarama-cluster"
@@ -35,8 +37,8 @@ import (
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
 	"github.com/fission/fission/pkg/mqtrigger/validator"
-	"github.com/fission/fission/pkg/utils"
 )
 
 func init() {
@@ -47,9 +49,6 @@ func init() {
 var (
 	// Need to use raw string to support escape sequence for - & . chars
 	validKafkaTopicName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-\._]*[a-zA-Z0-9]$`)
-
-	// Map for ErrorTopic messages to maintain recycle counter
-	errorMessageMap = make(map[string]int)
 )
 
 type (
@@ -60,6 +59,16 @@ type (
 		version   sarama.KafkaVersion
 		authKeys  map[string][]byte
 		tls       bool
+		schemas   *schema.Loader
+	}
+
+	// kafkaSubscription is the consumer of a trigger and the producer
+	// of its responses.
+	kafkaSubscription struct {
+		messageQueue.StatusRecorder
+		consumer *cluster.Consumer
+		producer sarama.SyncProducer
+		done     chan struct{}
 	}
 
 	Factory struct{}
@@ -89,6 +98,7 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 		routerUrl: routerUrl,
 		brokers:   strings.Split(mqCfg.Url, ","),
 		version:   kafkaVersion,
+		schemas:   schema.MakeLoader(mqCfg.KubeClient),
 	}
 
 	if tls, _ := strconv.ParseBool(os.Getenv("TLS_ENABLED")); tls {
@@ -115,10 +125,20 @@ func (kafka Kafka) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Sub
 	kafka.logger.Info("inside kakfa subscribe", zap.Any("trigger", trigger))
 	kafka.logger.Info("brokers set", zap.Strings("brokers", kafka.brokers))
 
+	if _, err := messageQueue.FunctionURL(kafka.routerUrl, trigger); err != nil {
+		return nil, err
+	}
+	msgSchema, err := kafka.schemas.Load(context.TODO(), trigger)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create new consumer
 	consumerConfig := cluster.NewConfig()
 	consumerConfig.Consumer.Return.Errors = true
 	consumerConfig.Group.Return.Notifications = true
+	// partitions are consumed by their own workers
+	consumerConfig.Group.Mode = cluster.ConsumerModePartitions
 	consumerConfig.Config.Version = kafka.version
 
 	// Create new producer
@@ -181,15 +201,28 @@ func (kafka Kafka) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Sub
 		}
 	}()
 
-	// consume messages
+	sub := &kafkaSubscription{
+		consumer: consumer,
+		producer: producer,
+		done:     make(chan struct{}),
+	}
+
+	// consume the partitions assigned to the consumer, until the
+	// consumer is closed
 	go func() {
-		for msg := range consumer.Messages() {
-			kafka.logger.Debug("calling message handler", zap.String("message", string(msg.Value[:])))
-			go kafkaMsgHandler(&kafka, producer, trigger, msg, consumer)
+		defer close(sub.done)
+		var wg sync.WaitGroup
+		for pc := range consumer.Partitions() {
+			wg.Add(1)
+			go func(pc cluster.PartitionConsumer) {
+				defer wg.Done()
+				kafka.consumePartition(pc, trigger, producer, &sub.StatusRecorder, msgSchema)
+			}(pc)
 		}
+		wg.Wait()
 	}()
 
-	return consumer, nil
+	return sub, nil
 }
 
 func (kafka Kafka) getTLSConfig() (*tls.Config, error) {
@@ -216,20 +249,44 @@ func (kafka Kafka) getTLSConfig() (*tls.Config, error) {
 	return &tlsConfig, nil
 }
 
+// Unsubscribe closes the consumer of a subscription, and waits for the
+// messages being processed. Their offsets may not be committed then,
+// the messages are processed again by the next consumer.
 func (kafka Kafka) Unsubscribe(subscription messageQueue.Subscription) error {
-	return subscription.(*cluster.Consumer).Close()
+	sub := subscription.(*kafkaSubscription)
+	err := sub.consumer.Close()
+	<-sub.done
+	if e := sub.producer.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
 }
 
-func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.MessageQueueTrigger, msg *sarama.ConsumerMessage, consumer *cluster.Consumer) {
-	var value string = string(msg.Value[:])
-	// Support other function ref types
-	if trigger.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
-		kafka.logger.Fatal("unsupported function reference type for trigger",
-			zap.Any("function_reference_type", trigger.Spec.FunctionReference.Type),
-			zap.String("trigger", trigger.ObjectMeta.Name))
+// kafkaMsgHandler invokes the function with a message, and publishes the
+// response or error. It returns false if the function couldn't be
+// invoked, or if publishing failed until ctx is done, the message isn't
+// processed then.
+func kafkaMsgHandler(ctx context.Context, kafka *Kafka, producer sarama.SyncProducer, status *messageQueue.StatusRecorder, msgSchema *schema.Schema, trigger *fv1.MessageQueueTrigger, msg *sarama.ConsumerMessage) bool {
+	url, err := messageQueue.FunctionURL(kafka.routerUrl, trigger)
+	if err != nil {
+		kafka.logger.Error("failed to get function URL", zap.Error(err), zap.String("trigger", trigger.ObjectMeta.Name))
+		status.Error(err)
+		return false
 	}
-
-	url := kafka.routerUrl + "/" + strings.TrimPrefix(utils.UrlForFunction(trigger.Spec.FunctionReference.Name, trigger.ObjectMeta.Namespace), "/")
+	data, err := msgSchema.Check(context.Background(), msg.Value)
+	if schema.IsUnavailable(err) {
+		kafka.logger.Error("failed to read the schema of message", zap.Error(err), zap.String("trigger", trigger.ObjectMeta.Name))
+		status.Error(err)
+		return false
+	}
+	if err != nil {
+		result := schema.InvalidMessageResult(err)
+		status.Invoked(result)
+		return errorHandler(ctx, kafka.logger, trigger, producer, status, url, result, kafka.errorHeaders(trigger, result))
+	}
+	contentType := msgSchema.ContentType(trigger.Spec.ContentType)
+	data, ceHeaders := messageQueue.CloudEvent(trigger, cloudEventID(msg.Partition, msg.Offset), msg.Timestamp, contentType, data)
+	var value string = string(data)
 	kafka.logger.Debug("making HTTP request", zap.String("url", url))
 
 	// Generate the Headers
@@ -237,102 +294,73 @@ func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.Me
 		"X-Fission-MQTrigger-Topic":      trigger.Spec.Topic,
 		"X-Fission-MQTrigger-RespTopic":  trigger.Spec.ResponseTopic,
 		"X-Fission-MQTrigger-ErrorTopic": trigger.Spec.ErrorTopic,
-		"Content-Type":                   trigger.Spec.ContentType,
+		"Content-Type":                   contentType,
 	}
-
-	// Create request
-	req, err := http.NewRequest("POST", url, strings.NewReader(value))
-	if err != nil {
-		kafka.logger.Error("failed to create HTTP request to invoke function",
-			zap.Error(err),
-			zap.String("function_url", url))
-		return
+	for k, v := range ceHeaders {
+		fissionHeaders[k] = v
 	}
 
-	// Set the headers came from Kafka record
-	// Using Header.Add() as msg.Headers may have keys with more than one value
-	if kafka.version.IsAtLeast(sarama.V0_11_0_0) {
-		for _, h := range msg.Headers {
-			req.Header.Add(string(h.Key), string(h.Value))
+	var respHeaders http.Header
+	result := messageQueue.MakeRetryPolicy(trigger).Invoke(context.Background(), func(ctx context.Context, attempt int) (int, []byte, error) {
+		// Create request
+		req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(value))
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "failed to create HTTP request to invoke function")
 		}
-	} else {
-		kafka.logger.Warn("headers are not supported by current Kafka version, needs v0.11+: no record headers to add in HTTP request",
-			zap.Any("current_version", kafka.version))
-	}
 
-	for k, v := range fissionHeaders {
-		req.Header.Set(k, v)
-	}
+		// Set the headers came from Kafka record
+		// Using Header.Add() as msg.Headers may have keys with more than one value
+		if kafka.version.IsAtLeast(sarama.V0_11_0_0) {
+			for _, h := range msg.Headers {
+				req.Header.Add(string(h.Key), string(h.Value))
+			}
+		} else if attempt == 0 {
+			kafka.logger.Warn("headers are not supported by current Kafka version, needs v0.11+: no record headers to add in HTTP request",
+				zap.Any("current_version", kafka.version))
+		}
+
+		for k, v := range fissionHeaders {
+			req.Header.Set(k, v)
+		}
+		if attempt > 0 {
+			req.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(attempt))
+		}
 
-	// Make the request
-	var resp *http.Response
-	for attempt := 0; attempt <= trigger.Spec.MaxRetries; attempt++ {
 		// Make the request
-		resp, err = http.DefaultClient.Do(req)
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			kafka.logger.Error("sending function invocation request failed",
 				zap.Error(err),
 				zap.String("function_url", url),
 				zap.String("trigger", trigger.ObjectMeta.Name))
-			continue
-		}
-		if resp == nil {
-			continue
-		}
-		if err == nil && resp.StatusCode == http.StatusOK {
-			// Success, quit retrying
-			break
+			return 0, nil, err
 		}
-	}
+		defer resp.Body.Close()
 
-	generateErrorHeaders := func(errString string) []sarama.RecordHeader {
-		var errorHeaders []sarama.RecordHeader
-		if kafka.version.IsAtLeast(sarama.V0_11_0_0) {
-			if count, ok := errorMessageMap[errString]; ok {
-				errorMessageMap[errString] = count + 1
-			} else {
-				errorMessageMap[errString] = 1
-			}
-			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte("MessageSource"), Value: []byte(trigger.Spec.Topic)})
-			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte("RecycleCounter"), Value: []byte(strconv.Itoa(errorMessageMap[errString]))})
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return resp.StatusCode, body, errors.Wrap(err, "request body error")
 		}
-		return errorHeaders
-	}
-
-	if resp == nil {
-		errorString := fmt.Sprintf("request exceed retries: %v", trigger.Spec.MaxRetries)
-		errorHeaders := generateErrorHeaders(errorString)
-		errorHandler(kafka.logger, trigger, producer, url,
-			fmt.Errorf(errorString), errorHeaders)
-		return
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+		respHeaders = resp.Header
+		return resp.StatusCode, body, nil
+	})
+	body := result.Body
+	status.Invoked(result)
 
 	kafka.logger.Debug("got response from function invocation",
 		zap.String("function_url", url),
 		zap.String("trigger", trigger.ObjectMeta.Name),
+		zap.Int("attempts", result.Attempts),
 		zap.String("body", string(body)))
 
-	if err != nil {
-		errorString := "request body error: " + string(body)
-		errorHeaders := generateErrorHeaders(errorString)
-		errorHandler(kafka.logger, trigger, producer, url,
-			errors.Wrapf(err, errorString), errorHeaders)
-		return
-	}
-	if resp.StatusCode != 200 {
-		errorString := fmt.Sprintf("request returned failure: %v, request body error: %v", resp.StatusCode, body)
-		errorHeaders := generateErrorHeaders(errorString)
-		errorHandler(kafka.logger, trigger, producer, url,
-			fmt.Errorf("request returned failure: %v", resp.StatusCode), errorHeaders)
-		return
+	if !result.Succeeded() {
+		return errorHandler(ctx, kafka.logger, trigger, producer, status, url, result, kafka.errorHeaders(trigger, result))
 	}
 	if len(trigger.Spec.ResponseTopic) > 0 {
 		// Generate Kafka record headers
 		var kafkaRecordHeaders []sarama.RecordHeader
 		if kafka.version.IsAtLeast(sarama.V0_11_0_0) {
-			for k, v := range resp.Header {
+			for k, v := range respHeaders {
 				// One key may have multiple values
 				for _, v := range v {
 					kafkaRecordHeaders = append(kafkaRecordHeaders, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
@@ -343,7 +371,7 @@ func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.Me
 				zap.Any("current_version", kafka.version))
 		}
 
-		_, _, err := producer.SendMessage(&sarama.ProducerMessage{
+		err := send(ctx, kafka.logger, producer, status, &sarama.ProducerMessage{
 			Topic:   trigger.Spec.ResponseTopic,
 			Value:   sarama.StringEncoder(body),
 			Headers: kafkaRecordHeaders,
@@ -351,31 +379,75 @@ func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.Me
 		if err != nil {
 			kafka.logger.Warn("failed to publish response body from function invocation to topic",
 				zap.Error(err),
-				zap.String("topic", trigger.Spec.Topic),
+				zap.String("topic", trigger.Spec.ResponseTopic),
 				zap.String("function_url", url))
-			return
+			return false
 		}
 	}
-	consumer.MarkOffset(msg, "") // mark message as processed
+	return true
 }
 
-func errorHandler(logger *zap.Logger, trigger *fv1.MessageQueueTrigger, producer sarama.SyncProducer, funcUrl string, err error, errorTopicHeaders []sarama.RecordHeader) {
-	if len(trigger.Spec.ErrorTopic) > 0 {
-		_, _, e := producer.SendMessage(&sarama.ProducerMessage{
-			Topic:   trigger.Spec.ErrorTopic,
-			Value:   sarama.StringEncoder(err.Error()),
-			Headers: errorTopicHeaders,
-		})
-		if e != nil {
-			logger.Error("failed to publish message to error topic",
-				zap.Error(e),
-				zap.String("trigger", trigger.ObjectMeta.Name),
-				zap.String("message", err.Error()),
-				zap.String("topic", trigger.Spec.Topic))
-		}
-	} else {
+// errorHeaders returns the headers of the error topic message of a
+// failed invocation.
+func (kafka *Kafka) errorHeaders(trigger *fv1.MessageQueueTrigger, result messageQueue.InvocationResult) []sarama.RecordHeader {
+	if !kafka.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil
+	}
+	errorHeaders := []sarama.RecordHeader{{Key: []byte("MessageSource"), Value: []byte(trigger.Spec.Topic)}}
+	for k, v := range result.FailureHeaders() {
+		errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	return errorHeaders
+}
+
+// errorHandler publishes a failed invocation to the error topic. The
+// message is the error body of the function, or the error if there's
+// no body. It returns false if the message couldn't be published until
+// ctx is done, the error is discarded if there's no error topic.
+func errorHandler(ctx context.Context, logger *zap.Logger, trigger *fv1.MessageQueueTrigger, producer sarama.SyncProducer, status *messageQueue.StatusRecorder, funcUrl string, result messageQueue.InvocationResult, errorTopicHeaders []sarama.RecordHeader) bool {
+	if len(trigger.Spec.ErrorTopic) == 0 {
 		logger.Error("message received to publish to error topic, but no error topic was set",
-			zap.String("message", err.Error()), zap.String("trigger", trigger.ObjectMeta.Name), zap.String("function_url", funcUrl))
+			zap.String("message", result.Error()), zap.String("trigger", trigger.ObjectMeta.Name), zap.String("function_url", funcUrl))
+		return true
+	}
+
+	value := result.Body
+	if len(value) == 0 {
+		value = []byte(result.Error())
+	}
+	e := send(ctx, logger, producer, status, &sarama.ProducerMessage{
+		Topic:   trigger.Spec.ErrorTopic,
+		Value:   sarama.ByteEncoder(value),
+		Headers: errorTopicHeaders,
+	})
+	if e != nil {
+		logger.Error("failed to publish message to error topic",
+			zap.Error(e),
+			zap.String("trigger", trigger.ObjectMeta.Name),
+			zap.String("message", result.Error()),
+			zap.String("topic", trigger.Spec.ErrorTopic))
+		return false
+	}
+	return true
+}
+
+// send publishes a message to the response or error topic, retrying
+// every processRetryInterval so that the function isn't invoked again
+// for the message. It returns the last error if ctx is done first.
+func send(ctx context.Context, logger *zap.Logger, producer sarama.SyncProducer, status *messageQueue.StatusRecorder, msg *sarama.ProducerMessage) error {
+	for {
+		_, _, err := producer.SendMessage(msg)
+		if err == nil {
+			return nil
+		}
+		status.Error(err)
+		logger.Warn("failed to publish message, publishing it again",
+			zap.Error(err), zap.String("topic", msg.Topic), zap.Duration("delay", processRetryInterval))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(processRetryInterval):
+		}
 	}
 }
 
@@ -396,3 +468,15 @@ func IsTopicValid(topic string) bool {
 	}
 	return true
 }
+
+// cloudEventIDPrefix prefixes the offsets of the messages of a
+// partition in their CloudEvent IDs, offsets are only unique within a
+// partition.
+func cloudEventIDPrefix(partition int32) string {
+	return strconv.Itoa(int(partition)) + "-"
+}
+
+// cloudEventID is the CloudEvent ID of a message.
+func cloudEventID(partition int32, offset int64) string {
+	return cloudEventIDPrefix(partition) + strconv.FormatInt(offset, 10)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	sarama "github.com/Shopify/sarama"
+	cluster "github.com/bsm/sarama-cluster"
+	"go.uber.org/zap"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
+)
+
+const (
+	// defaultKeyWorkers is the number of workers of a partition with
+	// key ordering, if the trigger doesn't set it.
+	defaultKeyWorkers = 10
+
+	// workerQueueSize is the number of messages queued for a worker.
+	workerQueueSize = 10
+)
+
+// processRetryInterval is the delay before processing a message again if
+// the function couldn't be invoked, e.g. because the schema registry is
+// down, or before publishing its response or error again.
+var processRetryInterval = 5 * time.Second
+
+type (
+	// offsetTracker tracks the messages of a partition being processed,
+	// so that an offset is only marked once the message at the offset
+	// and all those before it are processed.
+	offsetTracker struct {
+		lock sync.Mutex
+		// offsets of the messages being processed, in order
+		pending []int64
+		// offsets of the pending messages processed already
+		processed map[int64]bool
+	}
+)
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		processed: make(map[int64]bool),
+	}
+}
+
+// start adds the offset of a message about to be processed, messages
+// must be started in order.
+func (tracker *offsetTracker) start(offset int64) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	tracker.pending = append(tracker.pending, offset)
+}
+
+// finish records that the message at offset was processed. It returns
+// the offset up to which all messages are processed and true, or false
+// if an earlier message is still being processed.
+func (tracker *offsetTracker) finish(offset int64) (int64, bool) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.processed[offset] = true
+	last, ok := int64(0), false
+	for len(tracker.pending) > 0 && tracker.processed[tracker.pending[0]] {
+		last, ok = tracker.pending[0], true
+		delete(tracker.processed, last)
+		tracker.pending = tracker.pending[1:]
+	}
+	return last, ok
+}
+
+// keyWorkers returns the number of messages of a partition processed
+// at once for a trigger.
+func keyWorkers(trigger *fv1.MessageQueueTrigger) int {
+	concurrency := trigger.Spec.Concurrency
+	if concurrency == nil || concurrency.Ordering != fv1.MessageOrderingKey {
+		return 1
+	}
+	if concurrency.KeyWorkers > 0 {
+		return concurrency.KeyWorkers
+	}
+	return defaultKeyWorkers
+}
+
+// workerForKey returns the worker processing the messages with a key.
+func workerForKey(key []byte, workers int) int {
+	if workers <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(key) // nolint: errcheck
+	return int(h.Sum32() % uint32(workers))
+}
+
+// consumePartition processes the messages of a partition until it's
+// revoked from the consumer. Messages are dispatched to workers by key,
+// a worker processes its messages in order. An offset is marked once
+// all messages up to it are processed, so that messages are processed
+// again by the next consumer if the trigger stops meanwhile. The
+// consumer lag of the partition is recorded as its offsets are marked.
+func (kafka Kafka) consumePartition(pc cluster.PartitionConsumer, trigger *fv1.MessageQueueTrigger, producer sarama.SyncProducer, status *messageQueue.StatusRecorder, msgSchema *schema.Schema) {
+	logger := kafka.logger.With(zap.String("trigger", trigger.ObjectMeta.Name),
+		zap.String("topic", pc.Topic()), zap.Int32("partition", pc.Partition()))
+	logger.Info("consuming partition", zap.Int64("initial_offset", pc.InitialOffset()))
+
+	// ctx is canceled once the partition is closed, when it's revoked or
+	// the consumer is closed, abandoning the messages not processed yet
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for err := range pc.Errors() {
+			logger.Error("partition consumer error", zap.Error(err))
+		}
+	}()
+
+	partition := strconv.Itoa(int(pc.Partition()))
+	defer status.RemoveConsumerLag(partition)
+
+	offsets := newOffsetTracker()
+	batch := messageQueue.MakeBatchConfig(trigger)
+
+	// offsets are marked, and the lag recorded, in order
+	var markLock sync.Mutex
+	mark := func(msgOffset int64) {
+		markLock.Lock()
+		defer markLock.Unlock()
+		if offset, ok := offsets.finish(msgOffset); ok {
+			pc.MarkOffset(offset, "")
+			status.SetConsumerLag(partition, consumerLag(pc.HighWaterMarkOffset(), offset))
+		}
+	}
+
+	var wg sync.WaitGroup
+	queues := make([]chan *sarama.ConsumerMessage, keyWorkers(trigger))
+	for i := range queues {
+		queues[i] = make(chan *sarama.ConsumerMessage, workerQueueSize)
+		wg.Add(1)
+		go func(queue chan *sarama.ConsumerMessage) {
+			defer wg.Done()
+			for msg := range queue {
+				msgs := []*sarama.ConsumerMessage{msg}
+				if batch != nil {
+					msgs = collectBatch(queue, msg, batch)
+				}
+				if !kafka.processMessages(ctx, logger, trigger, producer, status, msgSchema, batch, msgs) {
+					continue
+				}
+				for _, msg := range msgs {
+					mark(msg.Offset)
+				}
+			}
+		}(queues[i])
+	}
+
+	for msg := range pc.Messages() {
+		offsets.start(msg.Offset)
+		// the worker may be stuck on an earlier message
+		select {
+		case queues[workerForKey(msg.Key, len(queues))] <- msg:
+		case <-ctx.Done():
+		}
+	}
+
+	// the messages not processed yet are left to the next consumer
+	// of the partition
+	cancel()
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	logger.Info("stopped consuming partition")
+}
+
+// processMessages processes a message, or a batch if batch is set,
+// until the responses and errors are published, so that the offsets of
+// the messages are never marked before. Messages are processed again
+// if the function couldn't be invoked, the handlers retry publishing
+// themselves rather than invoking the function again. It returns false
+// if the partition was revoked before.
+func (kafka Kafka) processMessages(ctx context.Context, logger *zap.Logger, trigger *fv1.MessageQueueTrigger, producer sarama.SyncProducer, status *messageQueue.StatusRecorder, msgSchema *schema.Schema, batch *messageQueue.BatchConfig, msgs []*sarama.ConsumerMessage) bool {
+	for ctx.Err() == nil {
+		logger.Debug("calling message handler", zap.Int64("offset", msgs[0].Offset), zap.Int("messages", len(msgs)))
+		var processed bool
+		if batch != nil {
+			processed = kafkaBatchHandler(ctx, &kafka, producer, status, msgSchema, trigger, batch, msgs)
+		} else {
+			processed = kafkaMsgHandler(ctx, &kafka, producer, status, msgSchema, trigger, msgs[0])
+		}
+		if processed {
+			return true
+		}
+		logger.Warn("failed to process messages, processing them again",
+			zap.Int64("offset", msgs[0].Offset), zap.Int("messages", len(msgs)), zap.Duration("delay", processRetryInterval))
+		select {
+		case <-ctx.Done():
+		case <-time.After(processRetryInterval):
+		}
+	}
+	return false
+}
+
+// consumerLag returns the number of messages of a partition after the
+// last processed offset, given the offset of its next message.
+func consumerLag(highWaterMarkOffset int64, offset int64) int64 {
+	lag := highWaterMarkOffset - offset - 1
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
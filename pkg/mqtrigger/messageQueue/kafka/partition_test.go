@@ -0,0 +1,303 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sarama "github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+type (
+	fakePartitionConsumer struct {
+		sarama.PartitionConsumer
+		messages chan *sarama.ConsumerMessage
+		errors   chan *sarama.ConsumerError
+
+		highWaterMark int64
+
+		lock   sync.Mutex
+		marked int64
+	}
+
+	fakeProducer struct {
+		sarama.SyncProducer
+		lock     sync.Mutex
+		messages []*sarama.ProducerMessage
+		// failures is the number of sends failing before the next one
+		// succeeds
+		failures int
+	}
+)
+
+func (pc *fakePartitionConsumer) Messages() <-chan *sarama.ConsumerMessage  { return pc.messages }
+func (pc *fakePartitionConsumer) Errors() <-chan *sarama.ConsumerError      { return pc.errors }
+func (pc *fakePartitionConsumer) Topic() string                             { return "requests" }
+func (pc *fakePartitionConsumer) Partition() int32                          { return 0 }
+func (pc *fakePartitionConsumer) InitialOffset() int64                      { return 0 }
+func (pc *fakePartitionConsumer) HighWaterMarkOffset() int64                { return pc.highWaterMark }
+func (pc *fakePartitionConsumer) ResetOffset(offset int64, metadata string) {}
+
+func (pc *fakePartitionConsumer) MarkOffset(offset int64, metadata string) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	if offset < pc.marked {
+		panic(fmt.Sprintf("offset %v marked after %v", offset, pc.marked))
+	}
+	pc.marked = offset
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return 0, 0, fmt.Errorf("leader not available")
+	}
+	p.messages = append(p.messages, msg)
+	return 0, int64(len(p.messages) - 1), nil
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(3); offset < 7; offset++ {
+		tracker.start(offset)
+	}
+
+	_, ok := tracker.finish(4)
+	require.False(t, ok)
+	_, ok = tracker.finish(6)
+	require.False(t, ok)
+
+	offset, ok := tracker.finish(3)
+	require.True(t, ok)
+	require.Equal(t, int64(4), offset)
+
+	offset, ok = tracker.finish(5)
+	require.True(t, ok)
+	require.Equal(t, int64(6), offset)
+	require.Empty(t, tracker.pending)
+	require.Empty(t, tracker.processed)
+}
+
+func TestConsumerLag(t *testing.T) {
+	require.Equal(t, int64(9), consumerLag(10, 0))
+	require.Equal(t, int64(0), consumerLag(10, 9))
+	require.Equal(t, int64(0), consumerLag(0, 9))
+}
+
+func TestKeyWorkers(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{}
+	require.Equal(t, 1, keyWorkers(trigger))
+
+	trigger.Spec.Concurrency = &fv1.MessageQueueConcurrency{Ordering: fv1.MessageOrderingPartition, KeyWorkers: 5}
+	require.Equal(t, 1, keyWorkers(trigger))
+
+	trigger.Spec.Concurrency.Ordering = fv1.MessageOrderingKey
+	require.Equal(t, 5, keyWorkers(trigger))
+
+	trigger.Spec.Concurrency.KeyWorkers = 0
+	require.Equal(t, defaultKeyWorkers, keyWorkers(trigger))
+
+	require.Equal(t, 0, workerForKey([]byte("a"), 1))
+	require.Equal(t, workerForKey([]byte("a"), 10), workerForKey([]byte("a"), 10))
+}
+
+func TestConsumePartition(t *testing.T) {
+	// the function records the order of the messages of each key
+	var lock sync.Mutex
+	received := make(map[string][]string)
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		key := string(body[:1])
+		time.Sleep(time.Millisecond)
+		lock.Lock()
+		received[key] = append(received[key], string(body))
+		lock.Unlock()
+		w.Write(body) // nolint: errcheck
+	}))
+	defer router.Close()
+
+	kafka := Kafka{
+		logger:    zap.NewNop(),
+		routerUrl: router.URL,
+		version:   sarama.V2_0_0_0,
+	}
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			Topic:             "requests",
+			ResponseTopic:     "responses",
+			Concurrency:       &fv1.MessageQueueConcurrency{Ordering: fv1.MessageOrderingKey, KeyWorkers: 4},
+		},
+	}
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	const count = 50
+	pc := &fakePartitionConsumer{
+		messages:      make(chan *sarama.ConsumerMessage),
+		errors:        make(chan *sarama.ConsumerError),
+		highWaterMark: count,
+	}
+	producer := &fakeProducer{}
+	var status messageQueue.StatusRecorder
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		kafka.consumePartition(pc, trigger, producer, &status, nil)
+	}()
+
+	for offset := 0; offset < count; offset++ {
+		key := keys[offset%len(keys)]
+		pc.messages <- &sarama.ConsumerMessage{
+			Topic:  "requests",
+			Offset: int64(offset),
+			Key:    []byte(key),
+			Value:  []byte(fmt.Sprintf("%v-%02d", key, offset)),
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		pc.lock.Lock()
+		defer pc.lock.Unlock()
+		return pc.marked == count-1
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		lag := status.SubscriptionStatus().ConsumerLag
+		return lag != nil && *lag == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	require.False(t, status.SubscriptionStatus().LastMessageAt.IsZero())
+	require.Empty(t, status.SubscriptionStatus().LastError)
+	close(pc.messages)
+	close(pc.errors)
+	<-done
+
+	require.Len(t, producer.messages, count)
+	for _, key := range keys {
+		require.Len(t, received[key], count/len(keys))
+		for i := 1; i < len(received[key]); i++ {
+			require.Less(t, received[key][i-1], received[key][i], "messages of key %v out of order", key)
+		}
+	}
+}
+
+func TestPublishRetry(t *testing.T) {
+	var invocations int32
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&invocations, 1)
+		w.Write([]byte("response")) // nolint: errcheck
+	}))
+	defer router.Close()
+
+	defer func(interval time.Duration) { processRetryInterval = interval }(processRetryInterval)
+	processRetryInterval = time.Millisecond
+
+	kafka := &Kafka{
+		logger:    zap.NewNop(),
+		routerUrl: router.URL,
+		version:   sarama.V2_0_0_0,
+	}
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			Topic:             "requests",
+			ResponseTopic:     "responses",
+		},
+	}
+	msg := &sarama.ConsumerMessage{Topic: "requests", Value: []byte("request")}
+
+	// only publishing the response is retried
+	producer := &fakeProducer{failures: 3}
+	var status messageQueue.StatusRecorder
+	require.True(t, kafkaMsgHandler(context.Background(), kafka, producer, &status, nil, trigger, msg))
+	require.Equal(t, int32(1), atomic.LoadInt32(&invocations))
+	require.Len(t, producer.messages, 1)
+	require.NotEmpty(t, status.SubscriptionStatus().LastError)
+
+	// until the partition is revoked
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	producer = &fakeProducer{failures: 1}
+	require.False(t, kafkaMsgHandler(ctx, kafka, producer, &status, nil, trigger, msg))
+	require.Equal(t, int32(2), atomic.LoadInt32(&invocations))
+	require.Empty(t, producer.messages)
+}
+
+func TestConsumePartitionClose(t *testing.T) {
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer router.Close()
+
+	defer func(interval time.Duration) { processRetryInterval = interval }(processRetryInterval)
+	processRetryInterval = time.Millisecond
+
+	kafka := Kafka{
+		logger:    zap.NewNop(),
+		routerUrl: router.URL,
+		version:   sarama.V2_0_0_0,
+	}
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			Topic:             "requests",
+			ResponseTopic:     "responses",
+		},
+	}
+
+	// the response topic is never available, the worker is stuck on
+	// the first message while the dispatcher fills its queue
+	pc := &fakePartitionConsumer{
+		messages: make(chan *sarama.ConsumerMessage),
+		errors:   make(chan *sarama.ConsumerError),
+	}
+	producer := &fakeProducer{failures: math.MaxInt32}
+	var status messageQueue.StatusRecorder
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		kafka.consumePartition(pc, trigger, producer, &status, nil)
+	}()
+	for offset := 0; offset < workerQueueSize+2; offset++ {
+		pc.messages <- &sarama.ConsumerMessage{Topic: "requests", Offset: int64(offset)}
+	}
+
+	close(pc.errors)
+	close(pc.messages)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "partition consumer didn't stop")
+	}
+	require.Zero(t, pc.marked)
+}
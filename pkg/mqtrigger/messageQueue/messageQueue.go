@@ -17,9 +17,23 @@ limitations under the License.
 package messageQueue
 
 import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/utils"
 )
 
+// DefaultFunctionTimeout is the timeout of the functions of triggers
+// that can't be looked up, it matches the FunctionSpec default.
+const DefaultFunctionTimeout = 60 * time.Second
+
 type (
 	Subscription interface{}
 
@@ -27,6 +41,8 @@ type (
 		MQType  string
 		Url     string
 		Secrets map[string][]byte
+		// KubeClient reads the ConfigMaps of message schemas
+		KubeClient kubernetes.Interface
 	}
 
 	MessageQueue interface {
@@ -34,3 +50,69 @@ type (
 		Unsubscribe(triggerSub Subscription) error
 	}
 )
+
+// FunctionURL returns the URL invoking the function of a trigger through
+// the router. The function of a function-weights reference is picked by
+// weight on every call, so that canary rollouts cover the trigger.
+func FunctionURL(routerURL string, trigger *fv1.MessageQueueTrigger) (string, error) {
+	// with the addition of multi-tenancy, the users can create functions in any namespace. however,
+	// the triggers can only be created in the same namespace as the function.
+	// so essentially, function namespace = trigger namespace.
+	url, err := utils.UrlForFunctionReference(trigger.Spec.FunctionReference, trigger.ObjectMeta.Namespace)
+	if err != nil {
+		return "", errors.Wrapf(err, "error resolving function reference of trigger %q", trigger.ObjectMeta.Name)
+	}
+	return routerURL + "/" + strings.TrimPrefix(url, "/"), nil
+}
+
+// Functions returns the functions of a trigger, all the ones of a
+// function-weights reference. It returns nil without a fission client.
+func Functions(ctx context.Context, fissionClient *crd.FissionClient, trigger *fv1.MessageQueueTrigger) ([]*fv1.Function, error) {
+	if fissionClient == nil {
+		return nil, nil
+	}
+	var fns []*fv1.Function
+	for _, name := range utils.FunctionNamesForReference(trigger.Spec.FunctionReference) {
+		fn, err := fissionClient.CoreV1().Functions(trigger.ObjectMeta.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting function %q of trigger %q", name, trigger.ObjectMeta.Name)
+		}
+		fns = append(fns, fn)
+	}
+	return fns, nil
+}
+
+// FunctionTimeout returns the longest timeout of functions, the ones of
+// a trigger, or DefaultFunctionTimeout without functions.
+func FunctionTimeout(fns []*fv1.Function) time.Duration {
+	if len(fns) == 0 {
+		return DefaultFunctionTimeout
+	}
+	var timeout time.Duration
+	for _, fn := range fns {
+		fnTimeout := DefaultFunctionTimeout
+		if fn.Spec.FunctionTimeout > 0 {
+			fnTimeout = time.Duration(fn.Spec.FunctionTimeout) * time.Second
+		}
+		if fnTimeout > timeout {
+			timeout = fnTimeout
+		}
+	}
+	return timeout
+}
+
+// ConsumerGroupMetadataKey is the trigger metadata key setting the
+// consumer group of a redis streams trigger, it matches the KEDA
+// redis-streams scaler.
+const ConsumerGroupMetadataKey = "consumerGroup"
+
+// ConsumerGroup returns the redis streams consumer group of a trigger.
+// Both the consumer and the KEDA scaler use it, so that the scaler
+// watches the pending entries of the group the trigger reads with.
+// It's kept across trigger updates.
+func ConsumerGroup(trigger *fv1.MessageQueueTrigger) string {
+	if group := trigger.Spec.Metadata[ConsumerGroupMetadataKey]; len(group) > 0 {
+		return group
+	}
+	return "fission-" + string(trigger.ObjectMeta.UID)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/generated/clientset/versioned/fake"
+)
+
+func TestFunctionURL(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+		},
+	}
+	url, err := FunctionURL("http://router", trigger)
+	require.NoError(t, err)
+	require.Equal(t, "http://router/fission-function/hello", url)
+
+	trigger.Spec.FunctionReference = fv1.FunctionReference{
+		Type:            fv1.FunctionReferenceTypeFunctionWeights,
+		FunctionWeights: map[string]int{"v1": 50, "v2": 50},
+	}
+	picked := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		url, err = FunctionURL("http://router", trigger)
+		require.NoError(t, err)
+		picked[url] = true
+	}
+	require.Equal(t, map[string]bool{
+		"http://router/fission-function/v1": true,
+		"http://router/fission-function/v2": true,
+	}, picked)
+
+	trigger.Spec.FunctionReference = fv1.FunctionReference{Type: "unknown"}
+	_, err = FunctionURL("http://router", trigger)
+	require.Error(t, err)
+}
+
+func TestFunctionTimeout(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{
+				Type:            fv1.FunctionReferenceTypeFunctionWeights,
+				FunctionWeights: map[string]int{"v1": 50, "v2": 50},
+			},
+		},
+	}
+
+	// without fission client the functions aren't looked up
+	fns, err := Functions(context.Background(), nil, trigger)
+	require.NoError(t, err)
+	require.Empty(t, fns)
+	require.Equal(t, DefaultFunctionTimeout, FunctionTimeout(fns))
+
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(&fv1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "v1", Namespace: "default"},
+		Spec:       fv1.FunctionSpec{FunctionTimeout: 30},
+	})}
+	_, err = Functions(context.Background(), fissionClient, trigger)
+	require.Error(t, err)
+
+	_, err = fissionClient.CoreV1().Functions("default").Create(context.Background(), &fv1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "v2", Namespace: "default"},
+		Spec:       fv1.FunctionSpec{FunctionTimeout: 120},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	fns, err = Functions(context.Background(), fissionClient, trigger)
+	require.NoError(t, err)
+	require.Len(t, fns, 2)
+	require.Equal(t, 120*time.Second, FunctionTimeout(fns))
+
+	// functions without timeout have the default one
+	for _, fn := range fns {
+		if fn.ObjectMeta.Name == "v2" {
+			fn.Spec.FunctionTimeout = 0
+		}
+	}
+	require.Equal(t, DefaultFunctionTimeout, FunctionTimeout(fns))
+}
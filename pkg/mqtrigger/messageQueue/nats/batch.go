@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	ns "github.com/nats-io/stan.go"
+	"go.uber.org/zap"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
+)
+
+// batchSubscription is a subscription delivering messages to the
+// function in batches. Messages are acked once their batch is handled.
+type batchSubscription struct {
+	messageQueue.StatusRecorder
+	schema *schema.Schema
+	sub    ns.Subscription
+	msgs   chan *ns.Msg
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func (nats Nats) subscribeBatch(trigger *fv1.MessageQueueTrigger, msgSchema *schema.Schema, config *messageQueue.BatchConfig, opts []ns.SubscriptionOption) (messageQueue.Subscription, error) {
+	s := &batchSubscription{
+		schema: msgSchema,
+		msgs:   make(chan *ns.Msg),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	// the messages of a batch are in flight until it's handled
+	if config.MaxSize > ns.DefaultMaxInflight {
+		opts = append(opts, ns.MaxInflight(config.MaxSize))
+	}
+	sub, err := nats.nsConn.Subscribe(trigger.Spec.Topic, func(msg *ns.Msg) {
+		select {
+		case s.msgs <- msg:
+		case <-s.stop:
+		}
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.sub = sub
+
+	go nats.handleBatches(trigger, config, s)
+	return s, nil
+}
+
+// close closes the subscription and waits for the batch being handled.
+// The messages of the batch being collected are redelivered.
+func (s *batchSubscription) close() error {
+	err := s.sub.Close()
+	close(s.stop)
+	<-s.done
+	return err
+}
+
+// handleBatches collects and handles batches until the subscription is
+// closed.
+func (nats Nats) handleBatches(trigger *fv1.MessageQueueTrigger, config *messageQueue.BatchConfig, s *batchSubscription) {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case msg := <-s.msgs:
+			msgs := s.collect(msg, config)
+			if msgs == nil {
+				return
+			}
+			nats.handleBatch(trigger, config, s.schema, &s.StatusRecorder, msgs)
+		}
+	}
+}
+
+// collect returns a batch starting with first, and the messages
+// delivered until the batch is full or the max wait is over. It returns
+// nil if the subscription is closed meanwhile.
+func (s *batchSubscription) collect(first *ns.Msg, config *messageQueue.BatchConfig) []*ns.Msg {
+	msgs := []*ns.Msg{first}
+
+	// without a max wait, only the messages delivered already are added
+	var timeout <-chan time.Time
+	if config.MaxWait > 0 {
+		timer := time.NewTimer(config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for len(msgs) < config.MaxSize {
+		if timeout == nil {
+			select {
+			case <-s.stop:
+				return nil
+			case msg := <-s.msgs:
+				msgs = append(msgs, msg)
+			default:
+				return msgs
+			}
+			continue
+		}
+
+		select {
+		case <-s.stop:
+			return nil
+		case msg := <-s.msgs:
+			msgs = append(msgs, msg)
+		case <-timeout:
+			return msgs
+		}
+	}
+	return msgs
+}
+
+// handleBatch invokes the function with a batch of messages. The
+// messages that succeeded are acked, the others are handled like a
+// message whose retries all failed.
+func (nats Nats) handleBatch(trigger *fv1.MessageQueueTrigger, config *messageQueue.BatchConfig, msgSchema *schema.Schema, status *messageQueue.StatusRecorder, msgs []*ns.Msg) {
+	url, err := messageQueue.FunctionURL(nats.routerUrl, trigger)
+	if err != nil {
+		nats.logger.Error("failed to get function URL, leaving batch to be redelivered",
+			zap.Error(err),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+		status.Error(err)
+		return
+	}
+	nats.logger.Debug("making HTTP request with batch", zap.String("url", url), zap.Int("size", len(msgs)))
+
+	batch := make([]messageQueue.Message, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = messageQueue.Message{
+			ID:      strconv.FormatUint(msg.Sequence, 10),
+			Headers: map[string]string{"Content-Type": trigger.Spec.ContentType},
+			Body:    msg.Data,
+			Time:    time.Unix(0, msg.Timestamp),
+		}
+	}
+
+	result, err := msgSchema.InvokeBatch(context.Background(), messageQueue.MakeRetryPolicy(trigger), batch, func(ctx context.Context, attempt int, batch []messageQueue.Message) (int, []byte, error) {
+		batch = messageQueue.CloudEventBatch(trigger, "", batch)
+		req, err := config.NewBatchRequest(ctx, url, batch)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("X-Fission-MQTrigger-Topic", trigger.Spec.Topic)
+		req.Header.Set("X-Fission-MQTrigger-RespTopic", trigger.Spec.ResponseTopic)
+		req.Header.Set("X-Fission-MQTrigger-ErrorTopic", trigger.Spec.ErrorTopic)
+		if attempt > 0 {
+			req.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(attempt))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			nats.logger.Error("sending function invocation request failed",
+				zap.Error(err),
+				zap.String("function_url", url),
+				zap.String("trigger", trigger.ObjectMeta.Name))
+			return 0, nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, body, err
+	})
+	if err != nil {
+		nats.logger.Error("failed to read the schema of message, leaving batch to be redelivered",
+			zap.Error(err),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+		status.Error(err)
+		return
+	}
+	status.InvokedBatch(result)
+
+	for i, msgResult := range result.Results {
+		if !msgResult.Succeeded() {
+			nats.handleFailure(trigger, url, msgs[i], msgResult)
+			continue
+		}
+		err := msgs[i].Ack()
+		if err != nil {
+			nats.logger.Error("failed to ack message after successful function invocation from trigger",
+				zap.Error(err),
+				zap.String("function_url", url),
+				zap.String("trigger", trigger.ObjectMeta.Name))
+		}
+	}
+
+	if len(trigger.Spec.ResponseTopic) > 0 {
+		for _, body := range result.Responses {
+			err := nats.nsConn.Publish(trigger.Spec.ResponseTopic, body)
+			if err != nil {
+				nats.logger.Error("failed to publish message with function invocation response to topic",
+					zap.Error(err),
+					zap.String("topic", trigger.Spec.ResponseTopic),
+					zap.String("trigger", trigger.ObjectMeta.Name))
+				status.Error(err)
+			}
+		}
+	}
+}
@@ -18,21 +18,24 @@ package nats
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"strings"
+	"strconv"
+	"time"
 
 	nsUtil "github.com/nats-io/nats-streaming-server/util"
 	ns "github.com/nats-io/stan.go"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
 	"github.com/fission/fission/pkg/mqtrigger/validator"
-	"github.com/fission/fission/pkg/utils"
 )
 
 var natsClusterID string
@@ -67,6 +70,14 @@ type (
 		logger    *zap.Logger
 		nsConn    ns.Conn
 		routerUrl string
+		schemas   *schema.Loader
+	}
+
+	// subscription is a subscription delivering messages to the
+	// function one at a time.
+	subscription struct {
+		messageQueue.StatusRecorder
+		sub ns.Subscription
 	}
 
 	Factory struct{}
@@ -94,6 +105,7 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 		logger:    logger.Named("nats"),
 		nsConn:    conn,
 		routerUrl: routerUrl,
+		schemas:   schema.MakeLoader(mqCfg.KubeClient),
 	}
 	return nats, nil
 }
@@ -104,6 +116,13 @@ func (nats Nats) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Subsc
 	if !IsTopicValid(subj) {
 		return nil, fmt.Errorf("not a valid topic: %q", trigger.Spec.Topic)
 	}
+	if _, err := messageQueue.FunctionURL(nats.routerUrl, trigger); err != nil {
+		return nil, err
+	}
+	msgSchema, err := nats.schemas.Load(context.TODO(), trigger)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := []ns.SubscriptionOption{
 		// Create a durable subscription to nats, so that triggers could retrieve last unack message.
@@ -115,106 +134,105 @@ func (nats Nats) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Subsc
 		// trigger could choose to ack message or simply drop it depend on the response of function pod.
 		ns.SetManualAckMode(),
 	}
-	sub, err := nats.nsConn.Subscribe(subj, msgHandler(&nats, trigger), opts...)
+	if batch := messageQueue.MakeBatchConfig(trigger); batch != nil {
+		return nats.subscribeBatch(trigger, msgSchema, batch, opts)
+	}
+
+	s := &subscription{}
+	sub, err := nats.nsConn.Subscribe(subj, msgHandler(&nats, trigger, msgSchema, &s.StatusRecorder), opts...)
 	if err != nil {
 		return nil, err
 	}
-	return sub, nil
+	s.sub = sub
+	return s, nil
 }
 
-func (nats Nats) Unsubscribe(subscription messageQueue.Subscription) error {
-	return subscription.(ns.Subscription).Close()
+func (nats Nats) Unsubscribe(triggerSub messageQueue.Subscription) error {
+	if sub, ok := triggerSub.(*batchSubscription); ok {
+		return sub.close()
+	}
+	return triggerSub.(*subscription).sub.Close()
 }
 
-func msgHandler(nats *Nats, trigger *fv1.MessageQueueTrigger) func(*ns.Msg) {
+func msgHandler(nats *Nats, trigger *fv1.MessageQueueTrigger, msgSchema *schema.Schema, status *messageQueue.StatusRecorder) func(*ns.Msg) {
 	return func(msg *ns.Msg) {
 
-		// Support other function ref types
-		if trigger.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
-			nats.logger.Fatal("unsupported function reference type for trigger",
-				zap.Any("function_reference_type", trigger.Spec.FunctionReference.Type),
+		url, err := messageQueue.FunctionURL(nats.routerUrl, trigger)
+		if err != nil {
+			nats.logger.Error("failed to get function URL, leaving message to be redelivered",
+				zap.Error(err),
 				zap.String("trigger", trigger.ObjectMeta.Name))
+			status.Error(err)
+			return
 		}
-
-		// with the addition of multi-tenancy, the users can create functions in any namespace. however,
-		// the triggers can only be created in the same namespace as the function.
-		// so essentially, function namespace = trigger namespace.
-		url := nats.routerUrl + "/" + strings.TrimPrefix(utils.UrlForFunction(trigger.Spec.FunctionReference.Name, trigger.ObjectMeta.Namespace), "/")
 		nats.logger.Debug("making HTTP request", zap.String("url", url))
 
 		headers := map[string]string{
 			"X-Fission-MQTrigger-Topic":      trigger.Spec.Topic,
 			"X-Fission-MQTrigger-RespTopic":  trigger.Spec.ResponseTopic,
 			"X-Fission-MQTrigger-ErrorTopic": trigger.Spec.ErrorTopic,
-			"Content-Type":                   trigger.Spec.ContentType,
+			"Content-Type":                   msgSchema.ContentType(trigger.Spec.ContentType),
 		}
 
-		// Create request
-		req, err := http.NewRequest("POST", url, bytes.NewReader(msg.Data))
-
-		if err != nil {
-			nats.logger.Error("failed to create HTTP request to invoke function",
+		data, err := msgSchema.Check(context.Background(), msg.Data)
+		if schema.IsUnavailable(err) {
+			nats.logger.Error("failed to read the schema of message, leaving message to be redelivered",
 				zap.Error(err),
-				zap.String("function_url", url))
+				zap.String("trigger", trigger.ObjectMeta.Name))
+			status.Error(err)
 			return
 		}
-
-		for k, v := range headers {
-			req.Header.Set(k, v)
+		if err != nil {
+			result := schema.InvalidMessageResult(err)
+			status.Invoked(result)
+			nats.handleFailure(trigger, url, msg, result)
+			return
+		}
+		data, ceHeaders := messageQueue.CloudEvent(trigger, strconv.FormatUint(msg.Sequence, 10), time.Unix(0, msg.Timestamp),
+			msgSchema.ContentType(trigger.Spec.ContentType), data)
+		for k, v := range ceHeaders {
+			headers[k] = v
 		}
 
-		var resp *http.Response
-		for attempt := 0; attempt <= trigger.Spec.MaxRetries; attempt++ {
+		result := messageQueue.MakeRetryPolicy(trigger).Invoke(context.Background(), func(ctx context.Context, attempt int) (int, []byte, error) {
+			// Create request
+			req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+			if err != nil {
+				return 0, nil, errors.Wrap(err, "failed to create HTTP request to invoke function")
+			}
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+			if attempt > 0 {
+				req.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(attempt))
+			}
+
 			// Make the request
-			resp, err = http.DefaultClient.Do(req)
+			resp, err := http.DefaultClient.Do(req)
 			if err != nil {
 				nats.logger.Error("sending function invocation request failed",
 					zap.Error(err),
 					zap.String("function_url", url),
 					zap.String("trigger", trigger.ObjectMeta.Name))
-				continue
-			}
-			if resp == nil {
-				continue
-			}
-			if err == nil && resp.StatusCode == http.StatusOK {
-				// Success, quit retrying
-				break
+				return 0, nil, err
 			}
-		}
-
-		if resp == nil {
-			nats.logger.Warn("every function invocation retry failed; final retry gave empty response",
-				zap.String("function_url", url),
-				zap.String("trigger", trigger.ObjectMeta.Name))
-			return
-		}
-
-		defer resp.Body.Close()
+			defer resp.Body.Close()
 
-		body, bodyErr := ioutil.ReadAll(resp.Body)
-		if bodyErr != nil {
-			nats.logger.Error("error reading function invocation response",
-				zap.Error(err),
-				zap.String("function_url", url),
-				zap.String("trigger", trigger.ObjectMeta.Name))
-			return
-		}
-
-		// Only the latest error response will be published to error topic
-		if err != nil || resp.StatusCode != 200 {
-			if len(trigger.Spec.ErrorTopic) > 0 && len(body) > 0 {
-				publishErr := nats.nsConn.Publish(trigger.Spec.ErrorTopic, body)
-				if publishErr != nil {
-					nats.logger.Error("failed to publish function invocation error to error topic",
-						zap.Error(publishErr),
-						zap.String("topic", trigger.Spec.ErrorTopic),
-						zap.String("function_url", url),
-						zap.String("trigger", trigger.ObjectMeta.Name))
-					// TODO: We will ack this message after max retries to prevent re-processing but
-					// this may cause message loss
-				}
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				nats.logger.Error("error reading function invocation response",
+					zap.Error(err),
+					zap.String("function_url", url),
+					zap.String("trigger", trigger.ObjectMeta.Name))
+				return resp.StatusCode, nil, err
 			}
+			return resp.StatusCode, body, nil
+		})
+		body := result.Body
+		status.Invoked(result)
+
+		if !result.Succeeded() {
+			nats.handleFailure(trigger, url, msg, result)
 			return
 		}
 
@@ -234,11 +252,55 @@ func msgHandler(nats *Nats, trigger *fv1.MessageQueueTrigger) func(*ns.Msg) {
 					zap.Error(err),
 					zap.String("topic", trigger.Spec.ResponseTopic),
 					zap.String("trigger", trigger.ObjectMeta.Name))
+				status.Error(err)
 			}
 		}
 	}
 }
 
+// handleFailure publishes the error response of the last attempt to
+// invoke the function with a message to the error topic, along with the
+// failure metadata, and acks the message. The message is left to be
+// redelivered if there's no error topic.
+func (nats Nats) handleFailure(trigger *fv1.MessageQueueTrigger, url string, msg *ns.Msg, result messageQueue.InvocationResult) {
+	nats.logger.Warn("every function invocation retry failed",
+		zap.String("error", result.Error()),
+		zap.Int("attempts", result.Attempts),
+		zap.Uint64("sequence", msg.Sequence),
+		zap.String("function_url", url),
+		zap.String("trigger", trigger.ObjectMeta.Name))
+
+	// Only the latest error response will be published to error topic
+	if len(trigger.Spec.ErrorTopic) == 0 {
+		return
+	}
+	// NATS streaming messages have no headers
+	envelope, err := result.FailureEnvelope(result.Body)
+	if err != nil {
+		nats.logger.Error("failed to encode function invocation error",
+			zap.Error(err),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+		return
+	}
+	err = nats.nsConn.Publish(trigger.Spec.ErrorTopic, envelope)
+	if err != nil {
+		nats.logger.Error("failed to publish function invocation error to error topic",
+			zap.Error(err),
+			zap.String("topic", trigger.Spec.ErrorTopic),
+			zap.String("function_url", url),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+		return
+	}
+	// the message is dead-lettered to the error topic,
+	// it's acked so that it isn't redelivered
+	err = msg.Ack()
+	if err != nil {
+		nats.logger.Error("failed to ack message published to error topic",
+			zap.Error(err),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+	}
+}
+
 func IsTopicValid(topic string) bool {
 	// nats-streaming does not support wildcard channel.
 	return nsUtil.IsChannelNameValid(topic, false)
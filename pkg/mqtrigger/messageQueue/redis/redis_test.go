@@ -0,0 +1,268 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/schema"
+)
+
+func TestIsTopicValid(t *testing.T) {
+	require.True(t, IsTopicValid("orders"))
+	require.True(t, IsTopicValid("orders:created"))
+	require.False(t, IsTopicValid(""))
+	require.False(t, IsTopicValid("orders created"))
+}
+
+func TestRedisStreams(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	// the function fails the first attempt of every message
+	var calls int32
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1)%2 == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("failed")) // nolint: errcheck
+			return
+		}
+		require.Equal(t, "1", r.Header.Get("X-Fission-MQTrigger-RetryCount"))
+		w.Write(append([]byte("re: "), body...)) // nolint: errcheck
+	}))
+	defer router.Close()
+
+	mq := newRedis(zap.NewNop(), client, "test-consumer", router.URL, nil)
+
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "test-uid"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			MessageQueueType:  fv1.MessageQueueTypeRedisStreams,
+			Topic:             "requests",
+			ResponseTopic:     "responses",
+			ErrorTopic:        "errors",
+			MaxRetries:        1,
+		},
+	}
+	sub, err := mq.Subscribe(trigger)
+	require.NoError(t, err)
+
+	id, err := client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "requests",
+		Values: map[string]interface{}{"body": "hello"},
+	}).Result()
+	require.NoError(t, err)
+
+	var responses []redis.XMessage
+	require.Eventually(t, func() bool {
+		responses, err = client.XRange(ctx, "responses", "-", "+").Result()
+		return err == nil && len(responses) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	require.Equal(t, "re: hello", responses[0].Values["body"])
+	require.Equal(t, id, responses[0].Values["source_id"])
+
+	// the message is acked
+	require.Eventually(t, func() bool {
+		pending, err := client.XPending(ctx, "requests", "fission-test-uid").Result()
+		return err == nil && pending.Count == 0
+	}, 10*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, mq.Unsubscribe(sub))
+
+	// without retries, the failed attempt is the last one
+	trigger.Spec.MaxRetries = 0
+	sub, err = mq.Subscribe(trigger)
+	require.NoError(t, err)
+	defer mq.Unsubscribe(sub) // nolint: errcheck
+
+	_, err = client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "requests",
+		Values: map[string]interface{}{"body": "again"},
+	}).Result()
+	require.NoError(t, err)
+
+	var errs []redis.XMessage
+	require.Eventually(t, func() bool {
+		errs, err = client.XRange(ctx, "errors", "-", "+").Result()
+		return err == nil && len(errs) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	require.Equal(t, "failed", errs[0].Values["body"])
+	require.Equal(t, "request returned failure: 500", errs[0].Values["error"])
+}
+
+func TestClaimAbandoned(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok")) // nolint: errcheck
+	}))
+	defer router.Close()
+
+	// a consumer read a message long ago and died before acking it
+	server.SetTime(time.Now().Add(-time.Hour))
+	require.NoError(t, client.XGroupCreateMkStream(ctx, "requests", "fission-test-uid", "$").Err())
+	require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "requests",
+		Values: map[string]interface{}{"body": "abandoned"},
+	}).Err())
+	require.NoError(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "fission-test-uid",
+		Consumer: "dead-consumer",
+		Streams:  []string{"requests", ">"},
+	}).Err())
+	server.SetTime(time.Now())
+
+	mq := newRedis(zap.NewNop(), client, "test-consumer", router.URL, nil)
+	sub, err := mq.Subscribe(&fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "test-uid"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			MessageQueueType:  fv1.MessageQueueTypeRedisStreams,
+			Topic:             "requests",
+			ResponseTopic:     "responses",
+		},
+	})
+	require.NoError(t, err)
+	defer mq.Unsubscribe(sub) // nolint: errcheck
+
+	require.Eventually(t, func() bool {
+		n, err := client.XLen(ctx, "responses").Result()
+		return err == nil && n == 1
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestResponseNotAdded(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	var calls int32
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("ok")) // nolint: errcheck
+	}))
+	defer router.Close()
+
+	// the response stream key holds a string, adding to it fails
+	require.NoError(t, client.Set(ctx, "responses", "not a stream", 0).Err())
+
+	mq := newRedis(zap.NewNop(), client, "test-consumer", router.URL, nil)
+	sub, err := mq.Subscribe(&fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "test-uid"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			MessageQueueType:  fv1.MessageQueueTypeRedisStreams,
+			Topic:             "requests",
+			ResponseTopic:     "responses",
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "requests",
+		Values: map[string]interface{}{"body": "hello"},
+	}).Err())
+	require.Eventually(t, func() bool {
+		return sub.(*subscription).SubscriptionStatus().LastError != ""
+	}, 10*time.Second, 50*time.Millisecond)
+	require.NoError(t, mq.Unsubscribe(sub))
+
+	// the message is left pending, to be handled again
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	pending, err := client.XPending(ctx, "requests", "fission-test-uid").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pending.Count)
+}
+
+func TestDecodedContentType(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	type request struct {
+		contentType string
+		body        string
+	}
+	requests := make(chan request, 1)
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{contentType: r.Header.Get("Content-Type"), body: string(body)}
+	}))
+	defer router.Close()
+
+	mq := newRedis(zap.NewNop(), client, "test-consumer", router.URL, nil)
+	mq.schemas = schema.MakeLoader(fake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "schemas", Namespace: "default"},
+		Data: map[string]string{
+			"greeting.avsc": `{"type": "record", "name": "Greeting", "fields": [{"name": "text", "type": "string"}]}`,
+		},
+	}))
+	sub, err := mq.Subscribe(&fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "test-uid"},
+		Spec: fv1.MessageQueueTriggerSpec{
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "hello"},
+			MessageQueueType:  fv1.MessageQueueTypeRedisStreams,
+			Topic:             "requests",
+			ContentType:       "avro/binary",
+			Schema: &fv1.MessageSchema{
+				Type:      fv1.MessageSchemaTypeAvro,
+				ConfigMap: &fv1.MessageSchemaConfigMap{Name: "schemas", Key: "greeting.avsc"},
+				Decode:    true,
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer mq.Unsubscribe(sub) // nolint: errcheck
+
+	require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "requests",
+		Values: map[string]interface{}{"body": string([]byte{4, 'h', 'i'})},
+	}).Err())
+
+	// the function gets the decoded message labelled as JSON
+	select {
+	case req := <-requests:
+		require.Equal(t, "application/json", req.contentType)
+		require.JSONEq(t, `{"text": "hi"}`, req.body)
+	case <-time.After(10 * time.Second):
+		t.Fatal("function not invoked")
+	}
+}
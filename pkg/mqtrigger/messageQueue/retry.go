@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// Headers carrying the failure metadata of messages published to the
+// error topic, for the message queues supporting headers.
+const (
+	FailureAttemptsHeader   = "X-Fission-MQTrigger-Attempts"
+	FailureStatusCodeHeader = "X-Fission-MQTrigger-StatusCode"
+	FailureErrorHeader      = "X-Fission-MQTrigger-Error"
+)
+
+type (
+	// RetryPolicy is the retry policy of a trigger, see
+	// fv1.MessageQueueRetryPolicy.
+	RetryPolicy struct {
+		MaxRetries           int
+		InitialBackoff       time.Duration
+		MaxBackoff           time.Duration
+		MaxAge               time.Duration
+		RetryableStatusCodes []int
+	}
+
+	// InvokeFunc makes an attempt to invoke a function, attempt is 0
+	// for the first one. It returns the status code and body of the
+	// response, or an error if there's no response.
+	InvokeFunc func(ctx context.Context, attempt int) (statusCode int, body []byte, err error)
+
+	// FailureEnvelope is the message published to the error topic by
+	// the message queues without headers. It carries the failure
+	// metadata of FailureHeaders along with the body, which is base64
+	// encoded in JSON.
+	FailureEnvelope struct {
+		Headers map[string]string `json:"headers"`
+		Body    []byte            `json:"body,omitempty"`
+	}
+
+	// InvocationResult is the outcome of the last attempt to invoke
+	// a function.
+	InvocationResult struct {
+		Attempts   int
+		StatusCode int
+		Body       []byte
+		Err        error
+	}
+)
+
+// MakeRetryPolicy returns the retry policy of a trigger.
+func MakeRetryPolicy(trigger *fv1.MessageQueueTrigger) RetryPolicy {
+	policy := RetryPolicy{MaxRetries: trigger.Spec.MaxRetries}
+	if policy.MaxRetries < 0 {
+		policy.MaxRetries = 0
+	}
+	if p := trigger.Spec.RetryPolicy; p != nil {
+		policy.InitialBackoff = time.Duration(p.InitialBackoff) * time.Millisecond
+		policy.MaxBackoff = time.Duration(p.MaxBackoff) * time.Millisecond
+		policy.MaxAge = time.Duration(p.MaxAge) * time.Second
+		policy.RetryableStatusCodes = p.RetryableStatusCodes
+	}
+	return policy
+}
+
+// Invoke calls invoke until it succeeds or the failure isn't to be
+// retried, and returns the result of the last attempt. Retries are
+// given up when ctx is done.
+func (policy RetryPolicy) Invoke(ctx context.Context, invoke InvokeFunc) InvocationResult {
+	start := time.Now()
+	backoff := policy.InitialBackoff
+
+	var result InvocationResult
+	for attempt := 0; ; attempt++ {
+		result.StatusCode, result.Body, result.Err = invoke(ctx, attempt)
+		result.Attempts = attempt + 1
+		if !policy.retry(result, start, backoff) || ctx.Err() != nil {
+			return result
+		}
+
+		if backoff > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return result
+			case <-timer.C:
+			}
+			backoff *= 2
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+// LongestBackoff returns the longest backoff between two attempts.
+func (policy RetryPolicy) LongestBackoff() time.Duration {
+	if policy.MaxRetries == 0 {
+		return 0
+	}
+	backoff := policy.InitialBackoff
+	for i := 1; i < policy.MaxRetries && backoff > 0; i++ {
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff >= policy.MaxBackoff {
+			return policy.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// retry returns true if the result of an attempt is to be retried
+// after the given backoff.
+func (policy RetryPolicy) retry(result InvocationResult, start time.Time, backoff time.Duration) bool {
+	if result.Succeeded() || result.Attempts > policy.MaxRetries {
+		return false
+	}
+	if policy.MaxAge > 0 && time.Since(start)+backoff > policy.MaxAge {
+		return false
+	}
+	return result.Err != nil || policy.Retryable(result.StatusCode)
+}
+
+// Retryable returns true if a function response with the given status
+// code is to be retried.
+func (policy RetryPolicy) Retryable(statusCode int) bool {
+	if len(policy.RetryableStatusCodes) == 0 {
+		return true
+	}
+	for _, code := range policy.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Succeeded returns true if the function responded with 200 OK.
+func (result InvocationResult) Succeeded() bool {
+	return result.Err == nil && result.StatusCode == http.StatusOK
+}
+
+// Error describes the failure of the last attempt.
+func (result InvocationResult) Error() string {
+	if result.Err != nil {
+		return result.Err.Error()
+	}
+	return fmt.Sprintf("request returned failure: %v", result.StatusCode)
+}
+
+// FailureHeaders returns the failure metadata of a failed invocation.
+func (result InvocationResult) FailureHeaders() map[string]string {
+	headers := map[string]string{
+		FailureAttemptsHeader: strconv.Itoa(result.Attempts),
+		FailureErrorHeader:    result.Error(),
+	}
+	if result.StatusCode > 0 {
+		headers[FailureStatusCodeHeader] = strconv.Itoa(result.StatusCode)
+	}
+	return headers
+}
+
+// FailureEnvelope returns the JSON encoded FailureEnvelope of a failed
+// invocation with the given body.
+func (result InvocationResult) FailureEnvelope(body []byte) ([]byte, error) {
+	return json.Marshal(FailureEnvelope{Headers: result.FailureHeaders(), Body: body})
+}
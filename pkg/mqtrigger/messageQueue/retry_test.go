@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestMakeRetryPolicy(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{}
+	trigger.Spec.MaxRetries = -1
+	require.Equal(t, RetryPolicy{}, MakeRetryPolicy(trigger))
+
+	trigger.Spec.MaxRetries = 3
+	trigger.Spec.RetryPolicy = &fv1.MessageQueueRetryPolicy{
+		InitialBackoff:       100,
+		MaxBackoff:           1000,
+		RetryableStatusCodes: []int{503},
+		MaxAge:               60,
+	}
+	require.Equal(t, RetryPolicy{
+		MaxRetries:           3,
+		InitialBackoff:       100 * time.Millisecond,
+		MaxBackoff:           time.Second,
+		MaxAge:               time.Minute,
+		RetryableStatusCodes: []int{503},
+	}, MakeRetryPolicy(trigger))
+}
+
+func TestInvoke(t *testing.T) {
+	statusCodes := func(codes ...int) InvokeFunc {
+		return func(ctx context.Context, attempt int) (int, []byte, error) {
+			if attempt >= len(codes) {
+				t.Fatalf("unexpected attempt %v", attempt)
+			}
+			return codes[attempt], []byte("body"), nil
+		}
+	}
+
+	// succeeds after retrying
+	policy := RetryPolicy{MaxRetries: 3}
+	result := policy.Invoke(context.Background(), statusCodes(500, http.StatusOK))
+	require.True(t, result.Succeeded())
+	require.Equal(t, 2, result.Attempts)
+
+	// gives up after the last retry
+	result = policy.Invoke(context.Background(), statusCodes(500, 500, 500, 502))
+	require.False(t, result.Succeeded())
+	require.Equal(t, 4, result.Attempts)
+	require.Equal(t, 502, result.StatusCode)
+	require.Equal(t, []byte("body"), result.Body)
+	require.Equal(t, map[string]string{
+		FailureAttemptsHeader:   "4",
+		FailureStatusCodeHeader: "502",
+		FailureErrorHeader:      "request returned failure: 502",
+	}, result.FailureHeaders())
+	envelope, err := result.FailureEnvelope([]byte("message"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"headers":{
+		"X-Fission-MQTrigger-Attempts":"4",
+		"X-Fission-MQTrigger-StatusCode":"502",
+		"X-Fission-MQTrigger-Error":"request returned failure: 502"
+	},"body":"bWVzc2FnZQ=="}`, string(envelope))
+
+	// only retryable status codes are retried, errors always are
+	policy.RetryableStatusCodes = []int{503}
+	result = policy.Invoke(context.Background(), statusCodes(503, 400))
+	require.Equal(t, 2, result.Attempts)
+	require.Equal(t, 400, result.StatusCode)
+
+	result = policy.Invoke(context.Background(), func(ctx context.Context, attempt int) (int, []byte, error) {
+		if attempt == 0 {
+			return 0, nil, errors.New("connection refused")
+		}
+		return http.StatusOK, nil, nil
+	})
+	require.True(t, result.Succeeded())
+	require.Equal(t, 2, result.Attempts)
+}
+
+func TestInvokeBackoff(t *testing.T) {
+	var attempts []time.Time
+	failing := func(ctx context.Context, attempt int) (int, []byte, error) {
+		attempts = append(attempts, time.Now())
+		return http.StatusInternalServerError, nil, nil
+	}
+
+	policy := RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     30 * time.Millisecond,
+	}
+	require.Equal(t, 30*time.Millisecond, policy.LongestBackoff())
+	result := policy.Invoke(context.Background(), failing)
+	require.Equal(t, 4, result.Attempts)
+	for i, backoff := range []time.Duration{20, 30, 30} {
+		require.GreaterOrEqual(t, int64(attempts[i+1].Sub(attempts[i])), int64(backoff*time.Millisecond))
+	}
+
+	// retries stop once the next one would exceed the max age
+	attempts = nil
+	policy.MaxAge = 40 * time.Millisecond
+	result = policy.Invoke(context.Background(), failing)
+	require.Equal(t, 2, result.Attempts)
+
+	// and once the context is done
+	attempts = nil
+	policy.MaxAge = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = policy.Invoke(ctx, failing)
+	require.Equal(t, 1, result.Attempts)
+}
+
+func TestLongestBackoff(t *testing.T) {
+	require.Equal(t, time.Duration(0), RetryPolicy{InitialBackoff: time.Second}.LongestBackoff())
+	require.Equal(t, 4*time.Second, RetryPolicy{MaxRetries: 3, InitialBackoff: time.Second}.LongestBackoff())
+	require.Equal(t, 3*time.Second, RetryPolicy{MaxRetries: 3, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}.LongestBackoff())
+}
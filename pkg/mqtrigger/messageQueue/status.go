@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// SubscriptionStatus is the activity of a subscription.
+	SubscriptionStatus struct {
+		// LastMessageAt is the time a message was last handled, zero if
+		// none was.
+		LastMessageAt time.Time
+		// ConsumerLag is the number of messages not consumed yet, nil if
+		// the message queue doesn't report it.
+		ConsumerLag *int64
+		// LastError is the last error handling a message, and
+		// LastErrorAt its time.
+		LastError   string
+		LastErrorAt time.Time
+	}
+
+	// StatusReporter is implemented by the subscriptions reporting their
+	// activity.
+	StatusReporter interface {
+		SubscriptionStatus() SubscriptionStatus
+	}
+
+	// StatusRecorder records the activity of a subscription. It's safe
+	// for concurrent use, and the zero value is ready to use.
+	StatusRecorder struct {
+		lock   sync.Mutex
+		status SubscriptionStatus
+		// lags are the consumer lags of the partitions of the topic
+		lags map[string]int64
+	}
+)
+
+// Invoked records an invocation of the function with a message.
+func (recorder *StatusRecorder) Invoked(result InvocationResult) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	now := time.Now()
+	recorder.status.LastMessageAt = now
+	if !result.Succeeded() {
+		recorder.status.LastError = result.Error()
+		recorder.status.LastErrorAt = now
+	}
+}
+
+// InvokedBatch records an invocation of the function with a batch, the
+// error of the last failed message of the batch is recorded.
+func (recorder *StatusRecorder) InvokedBatch(result BatchResult) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	now := time.Now()
+	recorder.status.LastMessageAt = now
+	for _, msgResult := range result.Results {
+		if !msgResult.Succeeded() {
+			recorder.status.LastError = msgResult.Error()
+			recorder.status.LastErrorAt = now
+		}
+	}
+}
+
+// Error records an error of the subscription that isn't the error of
+// an invocation, such as failing to publish a response.
+func (recorder *StatusRecorder) Error(err error) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.status.LastError = err.Error()
+	recorder.status.LastErrorAt = time.Now()
+}
+
+// SetConsumerLag records the consumer lag of a partition of the topic,
+// the lag of the subscription is the sum of the lags of its partitions.
+// Message queues without partitions use a single empty partition.
+func (recorder *StatusRecorder) SetConsumerLag(partition string, lag int64) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	if recorder.lags == nil {
+		recorder.lags = make(map[string]int64)
+	}
+	recorder.lags[partition] = lag
+}
+
+// RemoveConsumerLag forgets the consumer lag of a partition that's no
+// longer consumed by the subscription.
+func (recorder *StatusRecorder) RemoveConsumerLag(partition string) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	delete(recorder.lags, partition)
+}
+
+// SubscriptionStatus returns the recorded activity.
+func (recorder *StatusRecorder) SubscriptionStatus() SubscriptionStatus {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	status := recorder.status
+	if recorder.lags != nil {
+		var lag int64
+		for _, partitionLag := range recorder.lags {
+			lag += partitionLag
+		}
+		status.ConsumerLag = &lag
+	}
+	return status
+}
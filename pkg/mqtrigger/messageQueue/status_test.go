@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package messageQueue
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRecorder(t *testing.T) {
+	var recorder StatusRecorder
+	status := recorder.SubscriptionStatus()
+	require.True(t, status.LastMessageAt.IsZero())
+	require.Nil(t, status.ConsumerLag)
+
+	recorder.Invoked(InvocationResult{StatusCode: http.StatusOK})
+	status = recorder.SubscriptionStatus()
+	require.False(t, status.LastMessageAt.IsZero())
+	require.Empty(t, status.LastError)
+
+	recorder.Invoked(InvocationResult{StatusCode: http.StatusBadGateway})
+	status = recorder.SubscriptionStatus()
+	require.Equal(t, "request returned failure: 502", status.LastError)
+	require.False(t, status.LastErrorAt.IsZero())
+
+	// errors are kept after later messages succeed
+	recorder.InvokedBatch(BatchResult{Results: []InvocationResult{
+		{StatusCode: http.StatusOK},
+		{Err: errors.New("timeout")},
+	}})
+	recorder.Invoked(InvocationResult{StatusCode: http.StatusOK})
+	require.Equal(t, "timeout", recorder.SubscriptionStatus().LastError)
+
+	recorder.Error(errors.New("connection lost"))
+	require.Equal(t, "connection lost", recorder.SubscriptionStatus().LastError)
+
+	recorder.SetConsumerLag("0", 3)
+	recorder.SetConsumerLag("1", 4)
+	require.Equal(t, int64(7), *recorder.SubscriptionStatus().ConsumerLag)
+	recorder.SetConsumerLag("1", 1)
+	recorder.RemoveConsumerLag("0")
+	require.Equal(t, int64(1), *recorder.SubscriptionStatus().ConsumerLag)
+}
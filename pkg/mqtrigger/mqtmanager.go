@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
@@ -36,6 +37,11 @@ const (
 	GET_ALL_TRIGGERS
 )
 
+// statusUpdateInterval is the shortest interval between updates of the
+// status of a trigger, unless it's subscribed, unsubscribed or fails
+// with another error.
+const statusUpdateInterval = 30 * time.Second
+
 type (
 	requestType int
 
@@ -88,7 +94,7 @@ func (mqt *MessageQueueTriggerManager) service() {
 		switch req.requestType {
 		case ADD_TRIGGER:
 			var err error
-			k := crd.CacheKey(&req.triggerSub.trigger.ObjectMeta)
+			k := crd.CacheKeyGeneration(&req.triggerSub.trigger.ObjectMeta)
 			if _, ok := mqt.triggers[k]; ok {
 				err = errors.New("trigger already exists")
 			} else {
@@ -102,7 +108,7 @@ func (mqt *MessageQueueTriggerManager) service() {
 			}
 			req.respChan <- response{triggers: &copyTriggers}
 		case DELETE_TRIGGER:
-			delete(mqt.triggers, crd.CacheKey(&req.triggerSub.trigger.ObjectMeta))
+			delete(mqt.triggers, crd.CacheKeyGeneration(&req.triggerSub.trigger.ObjectMeta))
 		}
 	}
 }
@@ -139,7 +145,12 @@ func (mqt *MessageQueueTriggerManager) delTrigger(m *metav1.ObjectMeta) {
 	}
 }
 
+// syncTriggers subscribes to the triggers of the message queue and
+// unsubscribes from the deleted ones. Triggers are subscribed again when
+// their spec changes, status updates are ignored.
 func (mqt *MessageQueueTriggerManager) syncTriggers() {
+	// the last status update of each trigger
+	statusUpdates := make(map[string]time.Time)
 	for {
 		// get new set of triggers
 		newTriggers, err := mqt.fissionClient.CoreV1().MessageQueueTriggers(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
@@ -155,7 +166,7 @@ func (mqt *MessageQueueTriggerManager) syncTriggers() {
 		for index := range newTriggers.Items {
 			newTrigger := &newTriggers.Items[index]
 			if newTrigger.Spec.MessageQueueType == mqt.messageQueueType {
-				newTriggerMap[crd.CacheKey(&newTrigger.ObjectMeta)] = newTrigger
+				newTriggerMap[crd.CacheKeyGeneration(&newTrigger.ObjectMeta)] = newTrigger
 			}
 		}
 
@@ -164,7 +175,9 @@ func (mqt *MessageQueueTriggerManager) syncTriggers() {
 
 		// register new triggers
 		for key, trigger := range newTriggerMap {
-			if _, ok := (*currentTriggers)[key]; ok {
+			if triggerSub, ok := (*currentTriggers)[key]; ok {
+				status := makeTriggerStatus(trigger.Status, triggerSub.subscription)
+				mqt.updateStatus(trigger, status, statusUpdates)
 				continue
 			}
 
@@ -172,6 +185,11 @@ func (mqt *MessageQueueTriggerManager) syncTriggers() {
 			sub, err := mqt.messageQueue.Subscribe(trigger)
 			if err != nil {
 				mqt.logger.Warn("failed to subscribe to message queue trigger", zap.Error(err), zap.String("trigger_name", trigger.ObjectMeta.Name))
+				status := *trigger.Status.DeepCopy()
+				status.Subscribed = false
+				status.LastError = err.Error()
+				status.LastErrorAt = &metav1.Time{Time: time.Now().Truncate(time.Second)}
+				mqt.updateStatus(trigger, status, statusUpdates)
 				continue
 			}
 
@@ -187,6 +205,17 @@ func (mqt *MessageQueueTriggerManager) syncTriggers() {
 			}
 
 			mqt.logger.Info("message queue trigger created", zap.String("trigger_name", trigger.ObjectMeta.Name))
+
+			status := *trigger.Status.DeepCopy()
+			status.Subscribed = true
+			status.LastError = ""
+			status.LastErrorAt = nil
+			mqt.updateStatus(trigger, status, statusUpdates)
+		}
+		for key := range statusUpdates {
+			if _, ok := newTriggerMap[key]; !ok {
+				delete(statusUpdates, key)
+			}
 		}
 
 		// remove old triggers
@@ -207,3 +236,50 @@ func (mqt *MessageQueueTriggerManager) syncTriggers() {
 		time.Sleep(3 * time.Second)
 	}
 }
+
+// makeTriggerStatus returns the status of a subscribed trigger, given its
+// current status and the activity of the subscription if it reports it.
+func makeTriggerStatus(current fv1.MessageQueueTriggerStatus, sub messageQueue.Subscription) fv1.MessageQueueTriggerStatus {
+	status := *current.DeepCopy()
+	status.Subscribed = true
+
+	reporter, ok := sub.(messageQueue.StatusReporter)
+	if !ok {
+		return status
+	}
+	subStatus := reporter.SubscriptionStatus()
+	if !subStatus.LastMessageAt.IsZero() {
+		status.LastMessageAt = &metav1.Time{Time: subStatus.LastMessageAt.Truncate(time.Second)}
+	}
+	status.ConsumerLag = subStatus.ConsumerLag
+	if len(subStatus.LastError) > 0 {
+		status.LastError = subStatus.LastError
+		status.LastErrorAt = &metav1.Time{Time: subStatus.LastErrorAt.Truncate(time.Second)}
+	}
+	return status
+}
+
+// updateStatus updates the status of a trigger if it changed. Updates
+// that only change the activity of the trigger are made once every
+// statusUpdateInterval at most, updates is the time of the last update
+// of each trigger.
+func (mqt *MessageQueueTriggerManager) updateStatus(trigger *fv1.MessageQueueTrigger, status fv1.MessageQueueTriggerStatus, updates map[string]time.Time) {
+	if equality.Semantic.DeepEqual(trigger.Status, status) {
+		return
+	}
+	key := crd.CacheKeyGeneration(&trigger.ObjectMeta)
+	if status.Subscribed == trigger.Status.Subscribed && status.LastError == trigger.Status.LastError &&
+		time.Since(updates[key]) < statusUpdateInterval {
+		return
+	}
+
+	updated := trigger.DeepCopy()
+	updated.Status = status
+	_, err := mqt.fissionClient.CoreV1().MessageQueueTriggers(trigger.ObjectMeta.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		// updated again on the next sync
+		mqt.logger.Warn("failed to update status of message queue trigger", zap.Error(err), zap.String("trigger_name", trigger.ObjectMeta.Name))
+		return
+	}
+	updates[key] = time.Now()
+}
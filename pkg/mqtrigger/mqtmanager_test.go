@@ -0,0 +1,79 @@
+package mqtrigger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/generated/clientset/versioned/fake"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+type reportingSubscription struct {
+	status messageQueue.SubscriptionStatus
+}
+
+func (sub reportingSubscription) SubscriptionStatus() messageQueue.SubscriptionStatus {
+	return sub.status
+}
+
+func Test_makeTriggerStatus(t *testing.T) {
+	current := fv1.MessageQueueTriggerStatus{LastError: "old error"}
+
+	// subscriptions not reporting their activity are only subscribed
+	status := makeTriggerStatus(current, struct{}{})
+	assert.Equal(t, fv1.MessageQueueTriggerStatus{Subscribed: true, LastError: "old error"}, status)
+
+	lag := int64(5)
+	now := time.Now()
+	status = makeTriggerStatus(current, reportingSubscription{status: messageQueue.SubscriptionStatus{
+		LastMessageAt: now,
+		ConsumerLag:   &lag,
+		LastError:     "new error",
+		LastErrorAt:   now,
+	}})
+	assert.True(t, status.Subscribed)
+	assert.Equal(t, now.Truncate(time.Second), status.LastMessageAt.Time)
+	assert.Equal(t, int64(5), *status.ConsumerLag)
+	assert.Equal(t, "new error", status.LastError)
+	assert.Equal(t, now.Truncate(time.Second), status.LastErrorAt.Time)
+}
+
+func Test_updateStatus(t *testing.T) {
+	trigger := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "uid", Generation: 1},
+	}
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(trigger)}
+	mqt := MakeMessageQueueTriggerManager(zap.NewNop(), fissionClient, fv1.MessageQueueTypeKafka, nil)
+	updates := make(map[string]time.Time)
+
+	getStatus := func() fv1.MessageQueueTriggerStatus {
+		updated, err := fissionClient.CoreV1().MessageQueueTriggers("default").Get(context.TODO(), "test", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return updated.Status
+	}
+
+	mqt.updateStatus(trigger, fv1.MessageQueueTriggerStatus{Subscribed: true}, updates)
+	assert.True(t, getStatus().Subscribed)
+	trigger.Status.Subscribed = true
+
+	// activity updates are throttled
+	lastMessageAt := metav1.Now()
+	mqt.updateStatus(trigger, fv1.MessageQueueTriggerStatus{Subscribed: true, LastMessageAt: &lastMessageAt}, updates)
+	assert.Nil(t, getStatus().LastMessageAt)
+
+	// errors aren't
+	mqt.updateStatus(trigger, fv1.MessageQueueTriggerStatus{Subscribed: true, LastError: "failed"}, updates)
+	assert.Equal(t, "failed", getStatus().LastError)
+	trigger.Status.LastError = "failed"
+
+	updates[crd.CacheKeyGeneration(&trigger.ObjectMeta)] = time.Now().Add(-statusUpdateInterval)
+	mqt.updateStatus(trigger, fv1.MessageQueueTriggerStatus{Subscribed: true, LastError: "failed", LastMessageAt: &lastMessageAt}, updates)
+	assert.NotNil(t, getStatus().LastMessageAt)
+}
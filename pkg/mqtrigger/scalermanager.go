@@ -20,11 +20,13 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	k8sCache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/crd"
 	"github.com/fission/fission/pkg/executor/util"
 	genInformer "github.com/fission/fission/pkg/generated/informers/externalversions"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
 	"github.com/fission/fission/pkg/utils"
 )
 
@@ -66,7 +68,33 @@ func getAuthTriggerClient(namespace string) (dynamic.ResourceInterface, error) {
 	return dynamicClient.Resource(authTriggerGVR).Namespace(namespace), nil
 }
 
-func mqTriggerEventHandlers(logger *zap.Logger, kubeClient *kubernetes.Clientset, routerURL string) k8sCache.ResourceEventHandlerFuncs {
+// updateStatus updates the status of a trigger after its scaler objects
+// were created or updated. The scaler objects are in place if subscribed
+// is true, scalerErr is the error creating or updating them if any.
+func updateStatus(logger *zap.Logger, fissionClient *crd.FissionClient, mqt *fv1.MessageQueueTrigger, subscribed bool, scalerErr error) {
+	triggers := fissionClient.CoreV1().MessageQueueTriggers(mqt.ObjectMeta.Namespace)
+	updateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := triggers.Get(context.Background(), mqt.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Status.Subscribed = subscribed
+		current.Status.LastError = ""
+		current.Status.LastErrorAt = nil
+		if scalerErr != nil {
+			now := metav1.Now()
+			current.Status.LastError = scalerErr.Error()
+			current.Status.LastErrorAt = &now
+		}
+		_, err = triggers.UpdateStatus(context.Background(), current, metav1.UpdateOptions{})
+		return err
+	})
+	if updateErr != nil {
+		logger.Warn("Failed to update status of message queue trigger", zap.Error(updateErr), zap.String("trigger", mqt.ObjectMeta.Name))
+	}
+}
+
+func mqTriggerEventHandlers(logger *zap.Logger, fissionClient *crd.FissionClient, kubeClient *kubernetes.Clientset, routerURL string) k8sCache.ResourceEventHandlerFuncs {
 	return k8sCache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			go func() {
@@ -82,12 +110,14 @@ func mqTriggerEventHandlers(logger *zap.Logger, kubeClient *kubernetes.Clientset
 					err := createAuthTrigger(mqt, authenticationRef, kubeClient)
 					if err != nil {
 						logger.Error("Failed to create Authentication Trigger", zap.Error(err))
+						updateStatus(logger, fissionClient, mqt, false, err)
 						return
 					}
 				}
 
 				if err := createDeployment(mqt, routerURL, kubeClient); err != nil {
 					logger.Error("Failed to create Deployment", zap.Error(err))
+					updateStatus(logger, fissionClient, mqt, false, err)
 					if len(authenticationRef) > 0 {
 						err = deleteAuthTrigger(authenticationRef, mqt.ObjectMeta.Namespace)
 						if err != nil {
@@ -99,6 +129,7 @@ func mqTriggerEventHandlers(logger *zap.Logger, kubeClient *kubernetes.Clientset
 
 				if err := createScaledObject(mqt, authenticationRef); err != nil {
 					logger.Error("Failed to create ScaledObject", zap.Error(err))
+					updateStatus(logger, fissionClient, mqt, false, err)
 					if len(authenticationRef) > 0 {
 						if err = deleteAuthTrigger(authenticationRef, mqt.ObjectMeta.Namespace); err != nil {
 							logger.Error("Failed to delete Authentication Trigger", zap.Error(err))
@@ -107,13 +138,19 @@ func mqTriggerEventHandlers(logger *zap.Logger, kubeClient *kubernetes.Clientset
 					if err = deleteDeployment(mqt.ObjectMeta.Name, kubeClient); err != nil {
 						logger.Error("Failed to delete Deployment", zap.Error(err))
 					}
+					return
 				}
+				updateStatus(logger, fissionClient, mqt, true, nil)
 			}()
 		},
 		UpdateFunc: func(obj interface{}, newObj interface{}) {
 			go func() {
 				mqt := obj.(*fv1.MessageQueueTrigger)
 				newMqt := newObj.(*fv1.MessageQueueTrigger)
+				// status updates and resyncs don't change the spec
+				if mqt.ObjectMeta.Generation == newMqt.ObjectMeta.Generation {
+					return
+				}
 				updated := checkAndUpdateTriggerFields(mqt, newMqt)
 				if mqt.Spec.MqtKind == "fission" {
 					return
@@ -128,19 +165,23 @@ func mqTriggerEventHandlers(logger *zap.Logger, kubeClient *kubernetes.Clientset
 					authenticationRef = fmt.Sprintf("%s-auth-trigger", mqt.ObjectMeta.Name)
 					if err := updateAuthTrigger(mqt, authenticationRef, kubeClient); err != nil {
 						logger.Error("Failed to update Authentication Trigger", zap.Error(err))
+						updateStatus(logger, fissionClient, mqt, true, err)
 						return
 					}
 				}
 
 				if err := updateDeployment(mqt, routerURL, kubeClient); err != nil {
 					logger.Error("Failed to Update Deployment", zap.Error(err))
+					updateStatus(logger, fissionClient, mqt, true, err)
 					return
 				}
 
 				if err := updateScaledObject(mqt, authenticationRef); err != nil {
 					logger.Error("Failed to Update ScaledObject", zap.Error(err))
+					updateStatus(logger, fissionClient, mqt, true, err)
 					return
 				}
+				updateStatus(logger, fissionClient, mqt, true, nil)
 			}()
 		},
 	}
@@ -160,7 +201,7 @@ func StartScalerManager(logger *zap.Logger, routerURL string) error {
 	}
 	informerFactory := genInformer.NewSharedInformerFactory(fissionClient, time.Minute*30)
 	mqTriggerInformer := informerFactory.Core().V1().MessageQueueTriggers().Informer()
-	mqTriggerInformer.AddEventHandler(mqTriggerEventHandlers(logger, kubeClient, routerURL))
+	mqTriggerInformer.AddEventHandler(mqTriggerEventHandlers(logger, fissionClient, kubeClient, routerURL))
 	mqTriggerInformer.Run(context.Background().Done())
 	return nil
 }
@@ -172,6 +213,10 @@ func toEnvVar(str string) string {
 }
 
 func getEnvVarlist(mqt *fv1.MessageQueueTrigger, routerURL string, kubeClient kubernetes.Interface) ([]apiv1.EnvVar, error) {
+	// the connector invokes a single function URL
+	if mqt.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
+		return nil, fmt.Errorf("unsupported function reference type (%v) for keda trigger %q", mqt.Spec.FunctionReference.Type, mqt.ObjectMeta.Name)
+	}
 	url := routerURL + "/" + strings.TrimPrefix(utils.UrlForFunction(mqt.Spec.FunctionReference.Name, mqt.ObjectMeta.Namespace), "/")
 	envVars := []apiv1.EnvVar{
 		{
@@ -204,7 +249,7 @@ func getEnvVarlist(mqt *fv1.MessageQueueTrigger, routerURL string, kubeClient ku
 		},
 	}
 	// Metadata Fields
-	for key, value := range mqt.Spec.Metadata {
+	for key, value := range scalerMetadata(mqt) {
 		envVars = append(envVars, apiv1.EnvVar{
 			Name:  toEnvVar(key),
 			Value: value,
@@ -482,6 +527,29 @@ func deleteDeployment(name string, kubeClient *kubernetes.Clientset) error {
 	return nil
 }
 
+// scalerMetadata returns the metadata of the KEDA scaler of a trigger,
+// with the fields that can be derived from the trigger filled in.
+func scalerMetadata(mqt *fv1.MessageQueueTrigger) map[string]string {
+	if mqt.Spec.MessageQueueType != fv1.MessageQueueTypeRedisStreams {
+		return mqt.Spec.Metadata
+	}
+
+	metadata := make(map[string]string, len(mqt.Spec.Metadata))
+	for key, value := range mqt.Spec.Metadata {
+		metadata[key] = value
+	}
+	// the redis-streams scaler scales on the pending entries
+	// of a consumer group of a stream
+	if len(metadata["stream"]) == 0 {
+		metadata["stream"] = mqt.Spec.Topic
+	}
+	metadata[messageQueue.ConsumerGroupMetadataKey] = messageQueue.ConsumerGroup(mqt)
+	if len(metadata["pendingEntriesCount"]) == 0 {
+		metadata["pendingEntriesCount"] = "5"
+	}
+	return metadata
+}
+
 func getScaledObject(mqt *fv1.MessageQueueTrigger, authenticationRef string) *unstructured.Unstructured {
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -511,7 +579,7 @@ func getScaledObject(mqt *fv1.MessageQueueTrigger, authenticationRef string) *un
 				"triggers": []interface{}{
 					map[string]interface{}{
 						"type":     mqt.Spec.MessageQueueType,
-						"metadata": mqt.Spec.Metadata,
+						"metadata": scalerMetadata(mqt),
 						"authenticationRef": map[string]interface{}{
 							"name": authenticationRef,
 						},
@@ -568,3 +568,41 @@ func Test_getAuthTriggerSpec(t *testing.T) {
 		})
 	}
 }
+
+func Test_getScaledObjectRedisStreams(t *testing.T) {
+	mqt := &fv1.MessageQueueTrigger{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+			UID:       "test-uid",
+		},
+		Spec: fv1.MessageQueueTriggerSpec{
+			MessageQueueType: fv1.MessageQueueTypeRedisStreams,
+			Topic:            "orders",
+			Metadata: map[string]string{
+				"address": "redis.default.svc:6379",
+			},
+			MqtKind: "keda",
+		},
+	}
+
+	scaledObject := getScaledObject(mqt, "")
+	triggers := scaledObject.Object["spec"].(map[string]interface{})["triggers"].([]interface{})
+	assert.Len(t, triggers, 1)
+	trigger := triggers[0].(map[string]interface{})
+	assert.Equal(t, fv1.MessageQueueType(fv1.MessageQueueTypeRedisStreams), trigger["type"])
+	assert.Equal(t, map[string]string{
+		"address":             "redis.default.svc:6379",
+		"stream":              "orders",
+		"consumerGroup":       "fission-test-uid",
+		"pendingEntriesCount": "5",
+	}, trigger["metadata"])
+	// the trigger metadata is left as is
+	assert.Len(t, mqt.Spec.Metadata, 1)
+
+	mqt.Spec.Metadata["consumerGroup"] = "my-group"
+	mqt.Spec.Metadata["pendingEntriesCount"] = "10"
+	metadata := scalerMetadata(mqt)
+	assert.Equal(t, "my-group", metadata["consumerGroup"])
+	assert.Equal(t, "10", metadata["pendingEntriesCount"])
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+// avroSchema is a parsed Avro schema, decoding Avro binary encoded
+// messages. Decoded messages are converted to the JSON encoding of the
+// Avro specification, where union values are wrapped in an object keyed
+// on their type, e.g. {"string": "a"}.
+type avroSchema struct {
+	codec  *goavro.Codec
+	decode bool
+}
+
+// parseAvroSchema parses an Avro schema. Messages are converted to JSON
+// by check if decode is set.
+func parseAvroSchema(data []byte, decode bool) (*avroSchema, error) {
+	codec, err := goavro.NewCodec(string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Avro schema")
+	}
+	return &avroSchema{codec: codec, decode: decode}, nil
+}
+
+// check decodes an Avro binary encoded message. It returns the message
+// as JSON if the schema decodes messages, or as is otherwise.
+func (schema *avroSchema) check(data []byte) ([]byte, error) {
+	value, rest, err := schema.codec.NativeFromBinary(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "message doesn't match Avro schema")
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("message doesn't match Avro schema: %v bytes left after decoding", len(rest))
+	}
+	if !schema.decode {
+		return data, nil
+	}
+	decoded, err := schema.codec.TextualFromNative(nil, value)
+	if err != nil {
+		return nil, errors.Wrap(err, "error converting Avro message to JSON")
+	}
+	return decoded, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// jsonSchemaURL is the URL the schema document is compiled under, $ref
+// can point within it.
+const jsonSchemaURL = "fission:///schema.json"
+
+// jsonSchema is a compiled JSON Schema. Schemas are draft 7 unless
+// $schema says otherwise, and can't refer to other schema documents.
+type jsonSchema struct {
+	schema *jsonschema.Schema
+}
+
+// compileJSONSchema compiles a JSON Schema document.
+func compileJSONSchema(data []byte) (*jsonSchema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	// schemas come from ConfigMaps and registries, they must not read
+	// files or URLs from the trigger
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("schema can't refer to other schema document %q", s)
+	}
+	err := compiler.AddResource(jsonSchemaURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing JSON schema")
+	}
+	schema, err := compiler.Compile(jsonSchemaURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid JSON schema")
+	}
+	return &jsonSchema{schema: schema}, nil
+}
+
+// check validates a JSON document, which is returned as is.
+func (schema *jsonSchema) check(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// numbers are validated as written, e.g. large integers
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err == nil && decoder.More() {
+		err = errors.New("trailing data after JSON document")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "message is not valid JSON")
+	}
+	err = schema.schema.Validate(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "message doesn't match JSON schema")
+	}
+	return data, nil
+}
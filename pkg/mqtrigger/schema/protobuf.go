@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufSchema decodes Protocol Buffers binary encoded messages of a
+// message type.
+type protobufSchema struct {
+	message protoreflect.MessageDescriptor
+	decode  bool
+}
+
+// parseProtobufSchema finds a message type in a serialized
+// FileDescriptorSet. Messages are converted to JSON by check if decode
+// is set.
+func parseProtobufSchema(data []byte, messageType string, decode bool) (*protobufSchema, error) {
+	var set descriptorpb.FileDescriptorSet
+	err := proto.Unmarshal(data, &set)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing protobuf file descriptor set")
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid protobuf file descriptor set")
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(messageType))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error finding protobuf message type %q", messageType)
+	}
+	message, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a protobuf message type", messageType)
+	}
+	return &protobufSchema{message: message, decode: decode}, nil
+}
+
+// check decodes a Protocol Buffers binary encoded message. It returns
+// the message as JSON if the schema decodes messages, or as is
+// otherwise.
+func (schema *protobufSchema) check(data []byte) ([]byte, error) {
+	message := dynamicpb.NewMessage(schema.message)
+	err := proto.Unmarshal(data, message)
+	if err != nil {
+		return nil, errors.Wrap(err, "message doesn't match protobuf message type")
+	}
+	if !schema.decode {
+		return data, nil
+	}
+	decoded, err := protojson.Marshal(message)
+	if err != nil {
+		return nil, errors.Wrap(err, "error converting protobuf message to JSON")
+	}
+	return decoded, nil
+}
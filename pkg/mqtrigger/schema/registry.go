@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// registrySchema is a schema of a Confluent compatible schema registry.
+// SchemaType is empty for Avro schemas.
+type registrySchema struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+// unavailableError is the error of a registry schema that can't be read
+// for now, e.g. because the registry is down.
+type unavailableError struct {
+	error
+}
+
+func (err unavailableError) Unwrap() error {
+	return err.error
+}
+
+// IsUnavailable returns true if a message couldn't be checked because
+// its schema can't be read for now. The message isn't invalid then, it
+// should be processed again later rather than failed.
+func IsUnavailable(err error) bool {
+	var unavailable unavailableError
+	return errors.As(err, &unavailable)
+}
+
+// subjectSchema reads the latest schema of the registry subject.
+func (loader *Loader) subjectSchema(ctx context.Context, spec *fv1.MessageSchema) (codec, error) {
+	return loader.fetch(ctx, spec, "subjects/"+url.PathEscape(spec.Registry.Subject)+"/versions/latest")
+}
+
+// idSchema returns the registry schema with an ID, it's only read once.
+func (loader *Loader) idSchema(ctx context.Context, spec *fv1.MessageSchema, id uint32) (codec, error) {
+	key := fmt.Sprintf("%v|%v|%v|%v", spec.Registry.URL, id, spec.Type, spec.Decode)
+	loader.lock.Lock()
+	c, ok := loader.registrySchemas[key]
+	loader.lock.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	c, err := loader.fetch(ctx, spec, fmt.Sprintf("schemas/ids/%v", id))
+	if err != nil {
+		return nil, err
+	}
+	loader.lock.Lock()
+	loader.registrySchemas[key] = c
+	loader.lock.Unlock()
+	return c, nil
+}
+
+// fetch reads a schema from the registry, path is relative to the
+// registry URL.
+func (loader *Loader) fetch(ctx context.Context, spec *fv1.MessageSchema, path string) (codec, error) {
+	u := strings.TrimSuffix(spec.Registry.URL, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating schema registry request")
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := loader.httpClient.Do(req)
+	if err != nil {
+		return nil, unavailableError{errors.Wrap(err, "error reading schema from registry")}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, unavailableError{errors.Wrap(err, "error reading schema registry response")}
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("schema registry returned %v for %v: %v", resp.StatusCode, path, string(body))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, unavailableError{err}
+		}
+		return nil, err
+	}
+
+	var schema registrySchema
+	err = json.Unmarshal(body, &schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing schema registry response")
+	}
+
+	var schemaType fv1.MessageSchemaType
+	switch schema.SchemaType {
+	case "", "AVRO":
+		schemaType = fv1.MessageSchemaTypeAvro
+	case "JSON":
+		schemaType = fv1.MessageSchemaTypeJSON
+	default:
+		return nil, fmt.Errorf("unsupported registry schema type %q", schema.SchemaType)
+	}
+	if schemaType != spec.Type {
+		return nil, fmt.Errorf("registry schema %v is a %v schema, not a %v schema", path, schemaType, spec.Type)
+	}
+	return makeCodec(schemaType, []byte(schema.Schema), spec)
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schema checks the messages of message queue triggers against
+// their schema, and decodes Avro and Protocol Buffers messages to JSON.
+package schema
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+// registryTimeout is the timeout of the requests to schema registries.
+const registryTimeout = 10 * time.Second
+
+type (
+	// codec checks messages against a schema.
+	codec interface {
+		// check returns the message, converted to JSON if the schema
+		// decodes messages, or an error if it doesn't match the schema.
+		check(data []byte) ([]byte, error)
+	}
+
+	// Loader reads the schemas of triggers, from ConfigMaps or schema
+	// registries. It's safe for concurrent use.
+	Loader struct {
+		kubeClient kubernetes.Interface
+		httpClient *http.Client
+
+		lock sync.Mutex
+		// registrySchemas are the schemas read from registries by ID,
+		// they never change
+		registrySchemas map[string]codec
+	}
+
+	// Schema checks the messages of a trigger. A nil Schema accepts
+	// any message as is.
+	Schema struct {
+		loader *Loader
+		spec   fv1.MessageSchema
+		// codec is the schema of the ConfigMap, or the latest schema
+		// of the registry subject if any
+		codec codec
+	}
+)
+
+// MakeLoader returns a loader reading ConfigMaps with kubeClient.
+func MakeLoader(kubeClient kubernetes.Interface) *Loader {
+	return &Loader{
+		kubeClient:      kubeClient,
+		httpClient:      &http.Client{Timeout: registryTimeout},
+		registrySchemas: make(map[string]codec),
+	}
+}
+
+// Load reads the schema of a trigger, it returns nil if the trigger
+// has no schema.
+func (loader *Loader) Load(ctx context.Context, trigger *fv1.MessageQueueTrigger) (*Schema, error) {
+	spec := trigger.Spec.Schema
+	if spec == nil {
+		return nil, nil
+	}
+	if loader == nil {
+		return nil, errors.New("message schemas are not supported by this message queue")
+	}
+
+	schema := &Schema{loader: loader, spec: *spec.DeepCopy()}
+	var err error
+	switch {
+	case spec.ConfigMap != nil:
+		schema.codec, err = loader.configMapSchema(ctx, trigger.ObjectMeta.Namespace, spec)
+	case spec.Registry != nil && len(spec.Registry.Subject) > 0:
+		schema.codec, err = loader.subjectSchema(ctx, spec)
+	case spec.Registry == nil:
+		err = errors.New("schema has neither a ConfigMap nor a registry")
+	}
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "error loading schema of trigger %q", trigger.ObjectMeta.Name)
+	}
+	return schema, nil
+}
+
+// configMapSchema reads a schema from a ConfigMap.
+func (loader *Loader) configMapSchema(ctx context.Context, namespace string, spec *fv1.MessageSchema) (codec, error) {
+	if loader.kubeClient == nil {
+		return nil, errors.New("no kubernetes client to read the schema ConfigMap")
+	}
+	cm, err := loader.kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, spec.ConfigMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "error getting ConfigMap %q", spec.ConfigMap.Name)
+	}
+	data, ok := cm.BinaryData[spec.ConfigMap.Key]
+	if !ok {
+		value, ok := cm.Data[spec.ConfigMap.Key]
+		if !ok {
+			return nil, fmt.Errorf("ConfigMap %q has no key %q", spec.ConfigMap.Name, spec.ConfigMap.Key)
+		}
+		data = []byte(value)
+	}
+	return makeCodec(spec.Type, data, spec)
+}
+
+// makeCodec parses a schema of a type.
+func makeCodec(schemaType fv1.MessageSchemaType, data []byte, spec *fv1.MessageSchema) (codec, error) {
+	switch schemaType {
+	case fv1.MessageSchemaTypeJSON:
+		return compileJSONSchema(data)
+	case fv1.MessageSchemaTypeAvro:
+		return parseAvroSchema(data, spec.Decode)
+	case fv1.MessageSchemaTypeProtobuf:
+		return parseProtobufSchema(data, spec.MessageType, spec.Decode)
+	}
+	return nil, fmt.Errorf("unsupported schema type %q", schemaType)
+}
+
+// Check checks a message against the schema. It returns the message,
+// converted to JSON if the schema decodes messages, or an error if the
+// message doesn't match the schema. Messages in the registry wire format
+// are returned as is unless decoded, wire format header included.
+// Messages whose schema can't be read from the registry fail as well,
+// with an error for which IsUnavailable returns true if the registry
+// can't be read for now.
+func (schema *Schema) Check(ctx context.Context, data []byte) ([]byte, error) {
+	if schema == nil {
+		return data, nil
+	}
+
+	c := schema.codec
+	payload := data
+	if schema.spec.Registry != nil && len(data) >= 5 && data[0] == 0 {
+		var err error
+		c, err = schema.loader.idSchema(ctx, &schema.spec, binary.BigEndian.Uint32(data[1:5]))
+		if err != nil {
+			return nil, err
+		}
+		payload = data[5:]
+	}
+	if c == nil {
+		return nil, errors.New("message has no schema ID and the schema has no registry subject")
+	}
+	checked, err := c.check(payload)
+	if err != nil || schema.spec.Decode {
+		return checked, err
+	}
+	return data, nil
+}
+
+// ContentType returns the content type of the messages returned by
+// Check, given the one of the messages as sent.
+func (schema *Schema) ContentType(contentType string) string {
+	if schema != nil && schema.spec.Decode {
+		return "application/json"
+	}
+	return contentType
+}
+
+// InvalidMessageResult returns the result of a message that doesn't
+// match the schema of the trigger, the function isn't invoked with it.
+// The error is the body of the result, for the message queues that
+// only publish error responses to the error topic.
+func InvalidMessageResult(err error) messageQueue.InvocationResult {
+	return messageQueue.InvocationResult{Err: err, Body: []byte(err.Error())}
+}
+
+// InvokeBatch invokes the function with the messages of a batch that
+// match the schema, converted by Check along with their Content-Type
+// header. The messages that don't match fail without invoking the
+// function. If the schema of a message can't be read for now, the
+// function isn't invoked and the error is returned, the batch should
+// be processed again later.
+func (schema *Schema) InvokeBatch(ctx context.Context, policy messageQueue.RetryPolicy, batch []messageQueue.Message, invoke messageQueue.BatchInvokeFunc) (messageQueue.BatchResult, error) {
+	if schema == nil {
+		return policy.InvokeBatch(ctx, batch, invoke), nil
+	}
+
+	result := messageQueue.BatchResult{Results: make([]messageQueue.InvocationResult, len(batch))}
+	var (
+		valid   []messageQueue.Message
+		indexes []int
+	)
+	for i, msg := range batch {
+		body, err := schema.Check(ctx, msg.Body)
+		if IsUnavailable(err) {
+			return messageQueue.BatchResult{}, err
+		}
+		if err != nil {
+			result.Results[i] = InvalidMessageResult(err)
+			continue
+		}
+		msg.Body = body
+		if contentType, ok := msg.Headers["Content-Type"]; ok && schema.ContentType(contentType) != contentType {
+			headers := make(map[string]string, len(msg.Headers))
+			for k, v := range msg.Headers {
+				headers[k] = v
+			}
+			headers["Content-Type"] = schema.ContentType(contentType)
+			msg.Headers = headers
+		}
+		valid = append(valid, msg)
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	validResult := policy.InvokeBatch(ctx, valid, invoke)
+	for i, msgResult := range validResult.Results {
+		result.Results[indexes[i]] = msgResult
+	}
+	result.Responses = validResult.Responses
+	return result, nil
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+const (
+	orderJSONSchema = `{
+		"type": "object",
+		"required": ["id", "items"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"items": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/item"}},
+			"note": {"type": ["string", "null"], "maxLength": 5}
+		},
+		"additionalProperties": false,
+		"definitions": {
+			"item": {"type": "object", "required": ["sku"], "properties": {"sku": {"type": "string", "pattern": "^[A-Z]+$"}}}
+		}
+	}`
+
+	userAvroSchema = `{
+		"type": "record", "name": "User", "namespace": "test",
+		"fields": [
+			{"name": "name", "type": "string"},
+			{"name": "age", "type": "int"},
+			{"name": "email", "type": ["null", "string"]},
+			{"name": "tags", "type": {"type": "array", "items": "string"}},
+			{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}}
+		]
+	}`
+)
+
+// userAvro is a User of userAvroSchema, Avro binary encoded.
+var userAvro = []byte{
+	0x06, 'b', 'o', 'b', // name
+	0x54,                      // age 42
+	0x02, 0x06, 'b', '@', 'x', // email, string branch
+	0x04, 0x02, 'a', 0x02, 'b', 0, // tags, one block of 2 items
+	0x02, // role USER
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema, err := compileJSONSchema([]byte(orderJSONSchema))
+	require.NoError(t, err)
+
+	for _, valid := range []string{
+		`{"id": 1, "items": [{"sku": "ABC"}]}`,
+		`{"id": 2, "items": [{"sku": "A"}, {"sku": "B", "qty": 3}], "note": null}`,
+	} {
+		data, err := schema.check([]byte(valid))
+		require.NoError(t, err, valid)
+		require.Equal(t, valid, string(data))
+	}
+
+	for invalid, reason := range map[string]string{
+		`not json`:                                             "not valid JSON",
+		`{"items": [{"sku": "A"}]}`:                            "missing properties: 'id'",
+		`{"id": 1.5, "items": [{"sku": "A"}]}`:                 "'/id' does not validate",
+		`{"id": 0, "items": [{"sku": "A"}]}`:                   "must be >= 1",
+		`{"id": 1, "items": []}`:                               "minimum 1 items required",
+		`{"id": 1, "items": [{"sku": "a"}]}`:                   "'/items/0/sku' does not validate",
+		`{"id": 1, "items": [{"sku": "A"}], "x": 1}`:           "additionalProperties 'x' not allowed",
+		`{"id": 1, "items": [{"sku": "A"}], "note": "x1234"}`:  "",
+		`{"id": 1, "items": [{"sku": "A"}], "note": "123456"}`: "length must be <= 5",
+	} {
+		_, err := schema.check([]byte(invalid))
+		if len(reason) == 0 {
+			require.NoError(t, err, invalid)
+			continue
+		}
+		require.Error(t, err, invalid)
+		require.Contains(t, err.Error(), reason)
+	}
+
+	_, err = compileJSONSchema([]byte(`{"$ref": "#/definitions/missing"}`))
+	require.Error(t, err)
+	// schemas can't read other documents
+	_, err = compileJSONSchema([]byte(`{"$ref": "file:///etc/passwd"}`))
+	require.Error(t, err)
+}
+
+func TestJSONSchemaCombinators(t *testing.T) {
+	schema, err := compileJSONSchema([]byte(`{
+		"oneOf": [{"type": "string"}, {"type": "integer", "multipleOf": 2}],
+		"not": {"const": "forbidden"}
+	}`))
+	require.NoError(t, err)
+
+	_, err = schema.check([]byte(`"ok"`))
+	require.NoError(t, err)
+	_, err = schema.check([]byte(`4`))
+	require.NoError(t, err)
+	_, err = schema.check([]byte(`3`))
+	require.Error(t, err)
+	_, err = schema.check([]byte(`"forbidden"`))
+	require.Error(t, err)
+}
+
+func TestAvroSchema(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(userAvroSchema), true)
+	require.NoError(t, err)
+
+	data, err := schema.check(userAvro)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "bob", "age": 42, "email": {"string": "b@x"}, "tags": ["a", "b"], "role": "USER"}`, string(data))
+
+	_, err = schema.check(userAvro[:len(userAvro)-1])
+	require.Error(t, err)
+	_, err = schema.check(append(append([]byte{}, userAvro...), 0))
+	require.Error(t, err)
+
+	// messages are kept as is without decoding
+	schema.decode = false
+	data, err = schema.check(userAvro)
+	require.NoError(t, err)
+	require.Equal(t, userAvro, data)
+
+	// records can refer to themselves
+	_, err = parseAvroSchema([]byte(`{"type": "record", "name": "Node", "fields": [{"name": "next", "type": ["null", "Node"]}]}`), true)
+	require.NoError(t, err)
+	_, err = parseAvroSchema([]byte(`{"type": "record", "name": "R", "fields": [{"name": "x", "type": "Unknown"}]}`), true)
+	require.Error(t, err)
+}
+
+func TestProtobufSchema(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("user.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1),
+					Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("age"), JsonName: proto.String("age"), Number: proto.Int32(2),
+					Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}}}
+	descriptors, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	schema, err := parseProtobufSchema(descriptors, "test.User", true)
+	require.NoError(t, err)
+
+	// name "bob", age 42
+	user := []byte{0x0a, 0x03, 'b', 'o', 'b', 0x10, 0x2a}
+	data, err := schema.check(user)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "bob", "age": 42}`, string(data))
+
+	_, err = schema.check([]byte{0x0a, 0x05, 'b'})
+	require.Error(t, err)
+
+	_, err = parseProtobufSchema(descriptors, "test.Missing", true)
+	require.Error(t, err)
+}
+
+func TestLoadConfigMap(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "schemas", Namespace: "default"},
+		Data:       map[string]string{"order.json": orderJSONSchema},
+	})
+	loader := MakeLoader(kubeClient)
+
+	trigger := &fv1.MessageQueueTrigger{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"}}
+	schema, err := loader.Load(context.Background(), trigger)
+	require.NoError(t, err)
+	require.Nil(t, schema)
+	data, err := schema.Check(context.Background(), []byte("anything"))
+	require.NoError(t, err)
+	require.Equal(t, "anything", string(data))
+
+	trigger.Spec.Schema = &fv1.MessageSchema{
+		Type:      fv1.MessageSchemaTypeJSON,
+		ConfigMap: &fv1.MessageSchemaConfigMap{Name: "schemas", Key: "order.json"},
+	}
+	schema, err = loader.Load(context.Background(), trigger)
+	require.NoError(t, err)
+	_, err = schema.Check(context.Background(), []byte(`{"id": 1, "items": [{"sku": "A"}]}`))
+	require.NoError(t, err)
+	_, err = schema.Check(context.Background(), []byte(`{"id": 1}`))
+	require.Error(t, err)
+
+	trigger.Spec.Schema.ConfigMap.Key = "missing.json"
+	_, err = loader.Load(context.Background(), trigger)
+	require.Error(t, err)
+}
+
+func TestLoadRegistry(t *testing.T) {
+	var requests int32
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/subjects/users-value/versions/latest", "/schemas/ids/7":
+			json.NewEncoder(w).Encode(registrySchema{Schema: userAvroSchema}) // nolint: errcheck
+		case "/schemas/ids/10":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case "/schemas/ids/8":
+			json.NewEncoder(w).Encode(registrySchema{Schema: `{}`, SchemaType: "JSON"}) // nolint: errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer registry.Close()
+
+	trigger := &fv1.MessageQueueTrigger{ObjectMeta: metav1.ObjectMeta{Name: "users", Namespace: "default"}}
+	trigger.Spec.Schema = &fv1.MessageSchema{
+		Type:     fv1.MessageSchemaTypeAvro,
+		Registry: &fv1.MessageSchemaRegistry{URL: registry.URL, Subject: "users-value"},
+		Decode:   true,
+	}
+	schema, err := MakeLoader(nil).Load(context.Background(), trigger)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// messages without the wire format header use the subject schema
+	data, err := schema.Check(context.Background(), userAvro)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"name":"bob"`)
+
+	// the schema of an ID is only read once
+	wire := append([]byte{0, 0, 0, 0, 7}, userAvro...)
+	for i := 0; i < 2; i++ {
+		data, err = schema.Check(context.Background(), wire)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"name":"bob"`)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// messages are passed on as is, header included, if not decoded
+	schema.spec.Decode = false
+	data, err = schema.Check(context.Background(), wire)
+	require.NoError(t, err)
+	require.Equal(t, wire, data)
+	schema.spec.Decode = true
+
+	_, err = schema.Check(context.Background(), append([]byte{0, 0, 0, 0, 8}, userAvro...))
+	require.Error(t, err, "JSON schema of an avro trigger")
+	_, err = schema.Check(context.Background(), append([]byte{0, 0, 0, 0, 9}, userAvro...))
+	require.Error(t, err, "unknown schema ID")
+	require.False(t, IsUnavailable(err))
+
+	// the message isn't invalid if the registry can't be read
+	_, err = schema.Check(context.Background(), append([]byte{0, 0, 0, 0, 10}, userAvro...))
+	require.True(t, IsUnavailable(err))
+	_, err = schema.InvokeBatch(context.Background(), messageQueue.RetryPolicy{}, []messageQueue.Message{{Body: wire}, {Body: append([]byte{0, 0, 0, 0, 10}, userAvro...)}},
+		func(ctx context.Context, attempt int, batch []messageQueue.Message) (int, []byte, error) {
+			return 0, nil, fmt.Errorf("unexpected invocation")
+		})
+	require.True(t, IsUnavailable(err))
+}
+
+func TestInvokeBatch(t *testing.T) {
+	schema := &Schema{codec: mustCompileJSONSchema(t, `{"type": "integer"}`)}
+	batch := []messageQueue.Message{{Body: []byte("1")}, {Body: []byte(`"a"`)}, {Body: []byte("3")}}
+
+	var invoked []messageQueue.Message
+	result, err := schema.InvokeBatch(context.Background(), messageQueue.RetryPolicy{}, batch, func(ctx context.Context, attempt int, batch []messageQueue.Message) (int, []byte, error) {
+		invoked = batch
+		// the function fails the second message it got
+		return http.StatusMultiStatus, []byte(`{"failed": [1]}`), nil
+	})
+	require.NoError(t, err)
+	require.Len(t, invoked, 2)
+	require.Len(t, result.Results, 3)
+	require.True(t, result.Results[0].Succeeded())
+	require.False(t, result.Results[1].Succeeded())
+	require.Equal(t, 0, result.Results[1].Attempts)
+	require.NotEmpty(t, result.Results[1].Body)
+	require.False(t, result.Results[2].Succeeded())
+	require.Equal(t, 1, result.Results[2].Attempts)
+
+	// nothing is invoked if no message is valid
+	result, err = schema.InvokeBatch(context.Background(), messageQueue.RetryPolicy{}, batch[1:2], func(ctx context.Context, attempt int, batch []messageQueue.Message) (int, []byte, error) {
+		return 0, nil, fmt.Errorf("unexpected invocation")
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Contains(t, result.Results[0].Error(), "expected integer")
+}
+
+func mustCompileJSONSchema(t *testing.T, schema string) *jsonSchema {
+	s, err := compileJSONSchema([]byte(schema))
+	require.NoError(t, err)
+	return s
+}
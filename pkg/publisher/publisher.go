@@ -16,6 +16,8 @@ limitations under the License.
 
 package publisher
 
+import "context"
+
 type (
 	// Publisher interface wraps the Publish method that publishes an request
 	// with given "body" and "headers" to given "target"
@@ -25,4 +27,28 @@ type (
 		// name in a queue-based publisher such as NATS.
 		Publish(body string, headers map[string]string, target string)
 	}
+
+	// Invoker is a Publisher that can also wait for the response of the
+	// target.
+	Invoker interface {
+		Publisher
+
+		// Invoke sends a request with the given HTTP method to a
+		// "target" and waits for its response, it returns the status code
+		// of the response. The request is abandoned once ctx is done.
+		Invoke(ctx context.Context, method string, body string, headers map[string]string, target string) (int, error)
+	}
+
+	// OrderedPublisher is a Publisher that can deliver the requests about
+	// the same thing, like the events of an object, in order, and tells
+	// once they are delivered.
+	OrderedPublisher interface {
+		Publisher
+
+		// PublishOrdered publishes a request like Publish, after the
+		// requests published before with the same key if ordering is
+		// enabled. done is called once the request is delivered, or
+		// given up on.
+		PublishOrdered(key string, body string, headers map[string]string, target string, done func())
+	}
 )
@@ -18,69 +18,407 @@ package publisher
 
 import (
 	"bytes"
+	"context"
+	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
+const (
+	// EnvConcurrency sets the number of requests in flight to each
+	// target, EnvOrdered enables the ordered delivery of the requests
+	// published with the same key, and EnvTimeout bounds each published
+	// request, e.g. 60s.
+	EnvConcurrency = "PUBLISHER_CONCURRENCY"
+	EnvOrdered     = "PUBLISHER_ORDERED"
+	EnvTimeout     = "PUBLISHER_TIMEOUT"
+)
+
+var (
+	labelsStrings = []string{"target"}
+
+	queuedRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fission_publisher_queued_requests",
+			Help: "The number of requests waiting to be delivered to the target",
+		},
+		labelsStrings,
+	)
+	inflightRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fission_publisher_inflight_requests",
+			Help: "The number of requests being delivered to the target",
+		},
+		labelsStrings,
+	)
+	blockedPublishes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fission_publisher_blocked_publishes_total",
+			Help: "The number of requests published while the queue of the target was full, blocking the publisher",
+		},
+		labelsStrings,
+	)
+	droppedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fission_publisher_dropped_requests_total",
+			Help: "The number of requests given up on after failing all retries",
+		},
+		labelsStrings,
+	)
+	queueWait = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       "fission_publisher_queue_wait_seconds",
+			Help:       "The time requests waited in the queue of the target before being delivered",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		},
+		labelsStrings,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(queuedRequests, inflightRequests, blockedPublishes, droppedRequests, queueWait)
+}
+
 type (
+	// WebhookPublisherConfig is the delivery config of a WebhookPublisher.
+	WebhookPublisherConfig struct {
+		// Concurrency is the number of requests in flight to each target.
+		Concurrency int
+		// QueueSize is the number of requests waiting for each target,
+		// publishing blocks once it is reached.
+		QueueSize int
+		// Ordered delivers the requests published with the same key one
+		// after the other, in the order they were published.
+		Ordered bool
+		// Timeout bounds each published request, including reading the
+		// response. Invoked requests are bounded by their context.
+		Timeout time.Duration
+		// IdleTimeout is how long the workers of a target are kept once
+		// it has no requests left, zero keeps them forever.
+		IdleTimeout time.Duration
+
+		MaxRetries int
+		RetryDelay time.Duration
+	}
+
 	// WebhookPublisher for a single URL. Satisfies the Publisher interface.
 	WebhookPublisher struct {
 		logger *zap.Logger
 
-		requestChannel chan *publishRequest
+		config WebhookPublisherConfig
+		// client makes the published requests, invokeClient the
+		// invoked ones, without timeout
+		client       *http.Client
+		invokeClient *http.Client
+		baseURL      string
 
-		maxRetries int
-		retryDelay time.Duration
+		lock    sync.Mutex
+		targets map[string]*targetWorkers
+		// running is the number of running workers, of all targets
+		running int32
+	}
+
+	// targetWorkers deliver the requests to a target. Requests without
+	// a key go to the shared queue and are picked by any worker, the
+	// ones with a key go to the queue of a single worker, chosen by the
+	// key, so they are delivered in order.
+	// The workers are stopped once the target is idle for a while, as
+	// targets come and go with the functions.
+	targetWorkers struct {
+		target   string
+		requests chan *publishRequest
+		keyed    []chan *publishRequest
+		// stop is closed once the target is evicted
+		stop chan struct{}
 
-		baseURL string
+		// pending is the number of requests queued, in flight or
+		// waiting for their retry, idle counts the times it dropped to
+		// zero, both are guarded by the lock of the publisher
+		pending int
+		idle    uint64
 	}
+
 	publishRequest struct {
 		body       string
 		headers    map[string]string
 		target     string
 		retries    int
 		retryDelay time.Duration
+		queued     time.Time
+		// done is called once the request is delivered or dropped
+		done func()
 	}
 )
 
-// MakeWebhookPublisher creates a WebhookPublisher object for the given baseURL
+// DefaultWebhookPublisherConfig returns the config publishers use when
+// none is given.
+func DefaultWebhookPublisherConfig() WebhookPublisherConfig {
+	return WebhookPublisherConfig{
+		Concurrency: 8,
+		QueueSize:   32,
+		Timeout:     60 * time.Second,
+		IdleTimeout: 5 * time.Minute,
+		MaxRetries:  10,
+		RetryDelay:  500 * time.Millisecond,
+	}
+}
+
+// WebhookPublisherConfigFromEnv returns the default config, overridden by
+// the environment.
+func WebhookPublisherConfigFromEnv() (WebhookPublisherConfig, error) {
+	config := DefaultWebhookPublisherConfig()
+
+	if v := os.Getenv(EnvConcurrency); len(v) > 0 {
+		concurrency, err := strconv.Atoi(v)
+		if err != nil {
+			return config, errors.Wrapf(err, "error parsing %v", EnvConcurrency)
+		}
+		if concurrency < 1 {
+			return config, errors.Errorf("%v must be at least 1", EnvConcurrency)
+		}
+		config.Concurrency = concurrency
+	}
+	if v := os.Getenv(EnvOrdered); len(v) > 0 {
+		ordered, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, errors.Wrapf(err, "error parsing %v", EnvOrdered)
+		}
+		config.Ordered = ordered
+	}
+	if v := os.Getenv(EnvTimeout); len(v) > 0 {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return config, errors.Wrapf(err, "error parsing %v", EnvTimeout)
+		}
+		if timeout <= 0 {
+			return config, errors.Errorf("%v must be positive", EnvTimeout)
+		}
+		config.Timeout = timeout
+	}
+	return config, nil
+}
+
+// MakeWebhookPublisher creates a WebhookPublisher object for the given
+// baseURL, with the default config.
 func MakeWebhookPublisher(logger *zap.Logger, baseURL string) *WebhookPublisher {
-	p := &WebhookPublisher{
-		logger:         logger.Named("webhook_publisher"),
-		baseURL:        baseURL,
-		requestChannel: make(chan *publishRequest, 32), // buffered channel
-		// TODO make this configurable
-		maxRetries: 10,
-		retryDelay: 500 * time.Millisecond,
-	}
-	go p.svc()
-	return p
+	return MakeWebhookPublisherWithConfig(logger, baseURL, DefaultWebhookPublisherConfig())
 }
 
-// Publish sends a request to the target with payload having given body and headers
+// MakeWebhookPublisherWithConfig creates a WebhookPublisher object for the
+// given baseURL and config.
+func MakeWebhookPublisherWithConfig(logger *zap.Logger, baseURL string, config WebhookPublisherConfig) *WebhookPublisher {
+	// every target is reached through the router, keep enough idle
+	// connections to it for all the workers
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 100
+
+	return &WebhookPublisher{
+		logger:       logger.Named("webhook_publisher"),
+		config:       config,
+		client:       &http.Client{Transport: transport, Timeout: config.Timeout},
+		invokeClient: &http.Client{Transport: transport},
+		baseURL:      baseURL,
+		targets:      make(map[string]*targetWorkers),
+	}
+}
+
+// Publish sends a request to the target with payload having given body and headers.
+// It blocks while the queue of the target is full.
 func (p *WebhookPublisher) Publish(body string, headers map[string]string, target string) {
-	// serializing the request gives user a guarantee that the request is sent in sequence order
-	p.requestChannel <- &publishRequest{
+	p.enqueue(p.acquire(target).requests, p.makeRequest(body, headers, target))
+}
+
+// PublishOrdered sends a request like Publish. With ordering enabled, the
+// request is delivered after the ones published before with the same key
+// to the same target, retries included. done is called once the request
+// is delivered, or dropped once its retries failed.
+func (p *WebhookPublisher) PublishOrdered(key string, body string, headers map[string]string, target string, done func()) {
+	r := p.makeRequest(body, headers, target)
+	r.done = done
+	w := p.acquire(target)
+	if !p.config.Ordered {
+		p.enqueue(w.requests, r)
+		return
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	p.enqueue(w.keyed[h.Sum32()%uint32(len(w.keyed))], r)
+}
+
+// Invoke sends a request to the target and waits for the response, as
+// long as ctx allows. Requests failing to reach the target are retried
+// like published ones, until ctx is done. Requests failing once sent
+// aren't, the function may be running already.
+func (p *WebhookPublisher) Invoke(ctx context.Context, method string, body string, headers map[string]string, target string) (int, error) {
+	url := p.baseURL + "/" + strings.TrimPrefix(target, "/")
+	retryDelay := p.config.RetryDelay
+	for retries := p.config.MaxRetries; ; retries-- {
+		var sent int32
+		trace := &httptrace.ClientTrace{
+			WroteHeaders: func() { atomic.StoreInt32(&sent, 1) },
+		}
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, url, strings.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := p.invokeClient.Do(req)
+		if err == nil {
+			// drain the body to reuse the connection
+			_, err = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			return resp.StatusCode, err
+		}
+		if retries <= 1 || ctx.Err() != nil || atomic.LoadInt32(&sent) == 1 {
+			return 0, err
+		}
+
+		retryDelay *= time.Duration(2)
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (p *WebhookPublisher) makeRequest(body string, headers map[string]string, target string) *publishRequest {
+	return &publishRequest{
 		body:       body,
 		headers:    headers,
 		target:     target,
-		retries:    p.maxRetries,
-		retryDelay: p.retryDelay,
+		retries:    p.config.MaxRetries,
+		retryDelay: p.config.RetryDelay,
+	}
+}
+
+// acquire returns the workers of the target, starting them if needed,
+// for a new request. The request is pending until released.
+func (p *WebhookPublisher) acquire(target string) *targetWorkers {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	w, ok := p.targets[target]
+	if !ok {
+		w = &targetWorkers{
+			target:   target,
+			requests: make(chan *publishRequest, p.config.QueueSize),
+			keyed:    make([]chan *publishRequest, p.config.Concurrency),
+			stop:     make(chan struct{}),
+		}
+		for i := range w.keyed {
+			w.keyed[i] = make(chan *publishRequest, p.config.QueueSize)
+			atomic.AddInt32(&p.running, 1)
+			go p.work(w, w.keyed[i])
+		}
+		p.targets[target] = w
+	}
+	w.pending++
+	return w
+}
+
+// release marks a request of the target as delivered or dropped. The
+// target is evicted once it stays without requests for the idle timeout.
+func (p *WebhookPublisher) release(w *targetWorkers) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	w.pending--
+	if w.pending > 0 || p.config.IdleTimeout <= 0 {
+		return
+	}
+	w.idle++
+	idle := w.idle
+	time.AfterFunc(p.config.IdleTimeout, func() { p.evict(w, idle) })
+}
+
+// evict stops the workers of the target, unless it got requests since it
+// became idle.
+func (p *WebhookPublisher) evict(w *targetWorkers, idle uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if w.pending > 0 || w.idle != idle {
+		return
+	}
+	delete(p.targets, w.target)
+	close(w.stop)
+	queuedRequests.DeleteLabelValues(w.target)
+	inflightRequests.DeleteLabelValues(w.target)
+	blockedPublishes.DeleteLabelValues(w.target)
+	droppedRequests.DeleteLabelValues(w.target)
+	queueWait.DeleteLabelValues(w.target)
+}
+
+func (p *WebhookPublisher) enqueue(queue chan *publishRequest, r *publishRequest) {
+	r.queued = time.Now()
+	queuedRequests.WithLabelValues(r.target).Inc()
+	select {
+	case queue <- r:
+	default:
+		// the target can't keep up, hold the publisher back
+		blockedPublishes.WithLabelValues(r.target).Inc()
+		queue <- r
 	}
 }
 
-func (p *WebhookPublisher) svc() {
+// work delivers the requests of a target, one at a time. Failed keyed
+// requests are retried before picking the next one, so a worker never
+// reorders them, the others are queued again once their retry is due.
+// It returns once the target is evicted.
+func (p *WebhookPublisher) work(w *targetWorkers, keyed chan *publishRequest) {
+	defer atomic.AddInt32(&p.running, -1)
 	for {
-		r := <-p.requestChannel
-		p.makeHTTPRequest(r)
+		var r *publishRequest
+		ordered := false
+		select {
+		case <-w.stop:
+			return
+		case r = <-keyed:
+			ordered = true
+		case r = <-w.requests:
+		}
+		queuedRequests.WithLabelValues(r.target).Dec()
+		queueWait.WithLabelValues(r.target).Observe(time.Since(r.queued).Seconds())
+
+		inflightRequests.WithLabelValues(r.target).Inc()
+		done := p.makeHTTPRequest(r)
+		for ordered && !done {
+			time.Sleep(r.retryDelay)
+			done = p.makeHTTPRequest(r)
+		}
+		inflightRequests.WithLabelValues(r.target).Dec()
+
+		if !done {
+			time.AfterFunc(r.retryDelay, func() {
+				p.enqueue(w.requests, r)
+			})
+			continue
+		}
+		if r.done != nil {
+			r.done()
+		}
+		p.release(w)
 	}
 }
 
-func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) {
+// makeHTTPRequest makes a request, it returns false if it should be
+// retried after r.retryDelay.
+func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) bool {
 	url := p.baseURL + "/" + strings.TrimPrefix(r.target, "/")
 
 	msg := "making HTTP request"
@@ -101,18 +439,19 @@ func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) {
 	req, err := http.NewRequest(http.MethodPost, url, &buf)
 	if err != nil {
 		fields = append(fields, zap.Error(err))
-		return
+		return true
 	}
 	for k, v := range r.headers {
 		req.Header.Set(k, v)
 	}
 	// Make the request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
 		fields = append(fields, zap.Error(err), zap.Any("request", r))
 	} else {
 		var body []byte
 		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			fields = append(fields, zap.Error(err), zap.Any("request", r))
 			msg = "read response body error"
@@ -126,19 +465,18 @@ func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) {
 			} else {
 				msg = "request returned failure status code"
 			}
-			return
+			return true
 		}
 	}
 
-	// Schedule a retry, or give up if out of retries
+	// Retry, or give up if out of retries
 	r.retries--
 	if r.retries > 0 {
 		r.retryDelay *= time.Duration(2)
-		time.AfterFunc(r.retryDelay, func() {
-			p.requestChannel <- r
-		})
-	} else {
-		msg = "final retry failed, giving up"
-		// Event dropped
+		return false
 	}
+	msg = "final retry failed, giving up"
+	// Event dropped
+	droppedRequests.WithLabelValues(r.target).Inc()
+	return true
 }
@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testConfig() WebhookPublisherConfig {
+	config := DefaultWebhookPublisherConfig()
+	config.RetryDelay = time.Millisecond
+	return config
+}
+
+func TestPublishOrdered(t *testing.T) {
+	var lock sync.Mutex
+	received := make(map[string][]int)
+	failed := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n, _ := strconv.Atoi(string(body))
+		// fail the first attempt of some requests, to check the
+		// retries keep the order too
+		lock.Lock()
+		fail := n%5 == 0 && !failed[r.URL.Path+string(body)]
+		failed[r.URL.Path+string(body)] = true
+		lock.Unlock()
+		if fail {
+			hijackAndClose(w)
+			return
+		}
+		// later requests would overtake slow ones without ordering
+		time.Sleep(time.Duration(10-n%10) * time.Millisecond)
+		lock.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], n)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	config := testConfig()
+	config.Concurrency = 4
+	config.Ordered = true
+	p := MakeWebhookPublisherWithConfig(zap.NewNop(), server.URL, config)
+
+	var (
+		expected  []int
+		delivered int32
+	)
+	for i := 1; i <= 20; i++ {
+		expected = append(expected, i)
+		for _, key := range []string{"a", "b"} {
+			p.PublishOrdered(key, strconv.Itoa(i), nil, key, func() { atomic.AddInt32(&delivered, 1) })
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received["/a"]) == 20 && len(received["/b"]) == 20
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, expected, received["/a"])
+	require.Equal(t, expected, received["/b"])
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&delivered) == 40
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+// hijackAndClose closes the connection without responding, making the
+// request fail.
+func hijackAndClose(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func TestPublishConcurrency(t *testing.T) {
+	var lock sync.Mutex
+	inflight, maxInflight, count := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		inflight--
+		count++
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	config := testConfig()
+	config.Concurrency = 3
+	config.QueueSize = 2
+	p := MakeWebhookPublisherWithConfig(zap.NewNop(), server.URL, config)
+
+	// more requests than the queue holds, publishing blocks until the
+	// workers catch up
+	for i := 0; i < 12; i++ {
+		p.Publish("", nil, "fn")
+	}
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return count == 12
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 3, maxInflight)
+}
+
+func TestIdleTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	config := testConfig()
+	config.Concurrency = 2
+	config.IdleTimeout = 100 * time.Millisecond
+	p := MakeWebhookPublisherWithConfig(zap.NewNop(), server.URL, config)
+
+	var delivered int32
+	done := func() { atomic.AddInt32(&delivered, 1) }
+	p.PublishOrdered("", "", nil, "a", done)
+	p.PublishOrdered("", "", nil, "b", done)
+	require.Equal(t, int32(4), atomic.LoadInt32(&p.running))
+
+	// the workers of the targets stop once they're idle
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&p.running) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(&delivered))
+	p.lock.Lock()
+	require.Empty(t, p.targets)
+	p.lock.Unlock()
+
+	// and start again on the next request
+	p.PublishOrdered("", "", nil, "a", done)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&delivered) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&p.running) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestInvoke(t *testing.T) {
+	var requests int32
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+			return
+		}
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	config := testConfig()
+	config.Timeout = 20 * time.Millisecond
+	p := MakeWebhookPublisherWithConfig(zap.NewNop(), server.URL, config)
+
+	// invocations aren't bounded by the timeout of published requests
+	statusCode, err := p.Invoke(context.Background(), http.MethodPost, "", nil, "slow")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// nor retried once sent
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.Invoke(ctx, http.MethodPost, "", nil, "hang")
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// but published requests are, after the timeout
+	config.MaxRetries = 2
+	p = MakeWebhookPublisherWithConfig(zap.NewNop(), server.URL, config)
+	p.Publish("", nil, "hang")
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWebhookPublisherConfigFromEnv(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		env      map[string]string
+		expected func(*WebhookPublisherConfig)
+		err      bool
+	}{
+		{
+			name:     "defaults",
+			expected: func(*WebhookPublisherConfig) {},
+		},
+		{
+			name: "overridden",
+			env:  map[string]string{EnvConcurrency: "2", EnvOrdered: "true", EnvTimeout: "5s"},
+			expected: func(config *WebhookPublisherConfig) {
+				config.Concurrency = 2
+				config.Ordered = true
+				config.Timeout = 5 * time.Second
+			},
+		},
+		{
+			name: "no concurrency",
+			env:  map[string]string{EnvConcurrency: "0"},
+			err:  true,
+		},
+		{
+			name: "bad timeout",
+			env:  map[string]string{EnvTimeout: "5"},
+			err:  true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, k := range []string{EnvConcurrency, EnvOrdered, EnvTimeout} {
+				os.Unsetenv(k)
+			}
+			for k, v := range test.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+			config, err := WebhookPublisherConfigFromEnv()
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expected := DefaultWebhookPublisherConfig()
+			test.expected(&expected)
+			require.Equal(t, expected, config)
+		})
+	}
+}
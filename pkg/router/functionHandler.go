@@ -64,7 +64,7 @@ type (
 		function                 *fv1.Function
 		httpTrigger              *fv1.HTTPTrigger
 		functionMap              map[string]*fv1.Function
-		fnWeightDistributionList []functionWeightDistribution
+		fnWeightDistributionList []utils.FunctionWeightDistribution
 		tsRoundTripperParams     *tsRoundTripperParams
 		isDebugEnv               bool
 		svcAddrUpdateThrottler   *throttler.Throttler
@@ -522,36 +522,9 @@ func (fh functionHandler) handler(responseWriter http.ResponseWriter, request *h
 	proxy.ServeHTTP(responseWriter, request)
 }
 
-// findCeil picks a function from the functionWeightDistribution list based on the
-// random number generated. It uses the prefix calculated for the function weights.
-func findCeil(randomNumber int, wtDistrList []functionWeightDistribution) string {
-	low := 0
-	high := len(wtDistrList) - 1
-
-	for {
-		if low >= high {
-			break
-		}
-
-		mid := low + high/2
-		if randomNumber >= wtDistrList[mid].sumPrefix {
-			low = mid + 1
-		} else {
-			high = mid
-		}
-	}
-
-	if wtDistrList[low].sumPrefix >= randomNumber {
-		return wtDistrList[low].name
-	}
-	return ""
-}
-
 // picks a function to route to based on a random number generated
-func getCanaryBackend(fnMap map[string]*fv1.Function, fnWtDistributionList []functionWeightDistribution) *fv1.Function {
-	randomNumber := rand.Intn(fnWtDistributionList[len(fnWtDistributionList)-1].sumPrefix + 1)
-	fnName := findCeil(randomNumber, fnWtDistributionList)
-	return fnMap[fnName]
+func getCanaryBackend(fnMap map[string]*fv1.Function, fnWtDistributionList []utils.FunctionWeightDistribution) *fv1.Function {
+	return fnMap[utils.PickWeightedFunction(fnWtDistributionList)]
 }
 
 // addForwardedHostHeader add "forwarded host" to request header
@@ -26,6 +26,7 @@ import (
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/cache"
+	"github.com/fission/fission/pkg/utils"
 )
 
 type (
@@ -40,19 +41,13 @@ type (
 
 	resolveResultType int
 
-	functionWeightDistribution struct {
-		name      string
-		weight    int
-		sumPrefix int
-	}
-
 	// resolveResult is the result of resolving a function reference;
 	// it could be the metadata of one function or
 	// a distribution of requests across two functions.
 	resolveResult struct {
 		resolveResultType
 		functionMap                map[string]*fv1.Function
-		functionWtDistributionList []functionWeightDistribution
+		functionWtDistributionList []utils.FunctionWeightDistribution
 	}
 
 	// namespacedTriggerReference is just a trigger reference plus a
@@ -150,10 +145,8 @@ func (frr *functionReferenceResolver) resolveByName(namespace, name string) (*re
 func (frr *functionReferenceResolver) resolveByFunctionWeights(namespace string, fr *fv1.FunctionReference) (*resolveResult, error) {
 
 	functionMap := make(map[string]*fv1.Function)
-	fnWtDistrList := make([]functionWeightDistribution, 0)
-	sumPrefix := 0
 
-	for functionName, functionWeight := range fr.FunctionWeights {
+	for functionName := range fr.FunctionWeights {
 		// get function from cache
 		obj, isExist, err := (*frr.funcInformer).GetStore().Get(&fv1.Function{
 			ObjectMeta: metav1.ObjectMeta{
@@ -170,13 +163,8 @@ func (frr *functionReferenceResolver) resolveByFunctionWeights(namespace string,
 
 		f := obj.(*fv1.Function)
 		functionMap[f.ObjectMeta.Name] = f
-		sumPrefix = sumPrefix + functionWeight
-		fnWtDistrList = append(fnWtDistrList, functionWeightDistribution{
-			name:      functionName,
-			weight:    functionWeight,
-			sumPrefix: sumPrefix,
-		})
 	}
+	fnWtDistrList := utils.MakeFunctionWeightDistribution(fr.FunctionWeights)
 
 	rr := resolveResult{
 		resolveResultType:          resolveResultMultipleFunctions,
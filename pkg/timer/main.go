@@ -17,15 +17,22 @@ limitations under the License.
 package timer
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/fission/fission/pkg/crd"
 	"github.com/fission/fission/pkg/publisher"
+	"github.com/fission/fission/pkg/utils/leader"
 )
 
+const metricAddr = ":8080"
+
 func Start(logger *zap.Logger, routerUrl string) error {
-	fissionClient, _, _, _, err := crd.MakeFissionClient()
+	fissionClient, kubeClient, _, _, err := crd.MakeFissionClient()
 	if err != nil {
 		return errors.Wrap(err, "failed to get fission or kubernetes client")
 	}
@@ -35,8 +42,37 @@ func Start(logger *zap.Logger, routerUrl string) error {
 		return errors.Wrap(err, "error waiting for CRDs")
 	}
 
-	poster := publisher.MakeWebhookPublisher(logger, routerUrl)
-	MakeTimerSync(logger, fissionClient, MakeTimer(logger, poster))
+	config, err := leader.ConfigFromEnv("timer")
+	if err != nil {
+		return errors.Wrap(err, "error reading leader election config")
+	}
+
+	publisherConfig, err := publisher.WebhookPublisherConfigFromEnv()
+	if err != nil {
+		return errors.Wrap(err, "error reading publisher config")
+	}
+
+	go serveMetric(logger)
+
+	// with several replicas, only the leader runs the timer
+	go func() {
+		err := leader.Run(context.Background(), logger, kubeClient, "timer", config, func(ctx context.Context) {
+			poster := publisher.MakeWebhookPublisherWithConfig(logger, routerUrl, publisherConfig)
+			MakeTimerSync(logger, fissionClient, MakeTimer(logger, fissionClient, poster))
+		})
+		if err != nil {
+			logger.Fatal("error running leader election", zap.Error(err))
+		}
+		logger.Fatal("stopped leading, exiting to stand by")
+	}()
 
 	return nil
 }
+
+func serveMetric(logger *zap.Logger) {
+	// Expose the registered metrics via HTTP.
+	http.Handle("/metrics", promhttp.Handler())
+	err := http.ListenAndServe(metricAddr, nil)
+
+	logger.Fatal("done listening on metrics endpoint", zap.Error(err))
+}
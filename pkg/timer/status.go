@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// runRecorder records the runs of a trigger in its status. It's shared
+// by the successive jobs of the trigger, as its spec changes.
+type runRecorder struct {
+	lock   sync.Mutex
+	status fv1.TimeTriggerStatus
+}
+
+func makeRunRecorder(t fv1.TimeTrigger) *runRecorder {
+	return &runRecorder{status: *t.Status.DeepCopy()}
+}
+
+// lastScheduleTime returns the time of the tick of the last run of the
+// trigger, or its creation time if it never ran.
+func (recorder *runRecorder) lastScheduleTime(t fv1.TimeTrigger) time.Time {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	if recorder.status.LastScheduleTime != nil {
+		return recorder.status.LastScheduleTime.Time
+	}
+	return t.ObjectMeta.CreationTimestamp.Time
+}
+
+// completionTime returns the completion time of the run of the tick at,
+// or false if there is no such run.
+func (recorder *runRecorder) completionTime(at time.Time) (time.Time, bool) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	for _, run := range recorder.status.Runs {
+		if run.ScheduleTime.Equal(&metav1.Time{Time: at}) {
+			return run.CompletionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// record adds a run to the status of the trigger and updates it.
+func (timer *Timer) record(recorder *runRecorder, t fv1.TimeTrigger, run fv1.TimeTriggerRun) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	addRun(&recorder.status, run)
+	status := *recorder.status.DeepCopy()
+
+	triggers := timer.fissionClient.CoreV1().TimeTriggers(t.ObjectMeta.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := triggers.Get(context.Background(), t.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Status = status
+		_, err = triggers.UpdateStatus(context.Background(), current, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		timer.logger.Warn("failed to update status of time trigger", zap.Error(err), zap.String("trigger", t.ObjectMeta.Name))
+	}
+}
+
+// addRun adds a run to a status, keeping the most recent
+// TimeTriggerRunHistoryLimit runs.
+func addRun(status *fv1.TimeTriggerStatus, run fv1.TimeTriggerRun) {
+	// runs may finish out of order with the Allow concurrency policy
+	if status.LastScheduleTime == nil || run.ScheduleTime.After(status.LastScheduleTime.Time) {
+		scheduleTime := run.ScheduleTime
+		status.LastScheduleTime = &scheduleTime
+	}
+	if succeeded(run) && (status.LastSuccessfulTime == nil || run.ScheduleTime.After(status.LastSuccessfulTime.Time)) {
+		scheduleTime := run.ScheduleTime
+		status.LastSuccessfulTime = &scheduleTime
+	}
+
+	runs := make([]fv1.TimeTriggerRun, 0, len(status.Runs)+1)
+	runs = append(runs, run)
+	runs = append(runs, status.Runs...)
+	if len(runs) > fv1.TimeTriggerRunHistoryLimit {
+		runs = runs[:fv1.TimeTriggerRunHistoryLimit]
+	}
+	status.Runs = runs
+}
+
+// succeeded returns true if the function of the run responded with a
+// success status code.
+func succeeded(run fv1.TimeTriggerRun) bool {
+	return len(run.Error) == 0 && run.StatusCode > 0 && run.StatusCode < 400
+}
+
+// missedTick returns the last tick of a schedule after last and at or
+// before now, ignoring the ticks more than deadline before now. It
+// returns false if there is no such tick.
+func missedTick(schedule cron.Schedule, last time.Time, now time.Time, deadline time.Duration) (time.Time, bool) {
+	if from := now.Add(-deadline); from.After(last) {
+		last = from
+	}
+	var missed time.Time
+	for next := schedule.Next(last); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		missed = next
+	}
+	return missed, !missed.IsZero()
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestAddRun(t *testing.T) {
+	tick := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	status := fv1.TimeTriggerStatus{}
+
+	addRun(&status, fv1.TimeTriggerRun{ScheduleTime: metav1.NewTime(tick), StatusCode: 200})
+	require.Equal(t, tick, status.LastScheduleTime.Time)
+	require.Equal(t, tick, status.LastSuccessfulTime.Time)
+
+	// a failed run doesn't change the last successful time
+	addRun(&status, fv1.TimeTriggerRun{ScheduleTime: metav1.NewTime(tick.Add(time.Minute)), StatusCode: 500})
+	require.Equal(t, tick.Add(time.Minute), status.LastScheduleTime.Time)
+	require.Equal(t, tick, status.LastSuccessfulTime.Time)
+
+	// a run finishing out of order doesn't move the times back
+	addRun(&status, fv1.TimeTriggerRun{ScheduleTime: metav1.NewTime(tick.Add(-time.Minute)), StatusCode: 200})
+	require.Equal(t, tick.Add(time.Minute), status.LastScheduleTime.Time)
+	require.Equal(t, tick, status.LastSuccessfulTime.Time)
+	require.Len(t, status.Runs, 3)
+	require.Equal(t, tick.Add(-time.Minute), status.Runs[0].ScheduleTime.Time)
+
+	for i := 0; i < 2*fv1.TimeTriggerRunHistoryLimit; i++ {
+		addRun(&status, fv1.TimeTriggerRun{ScheduleTime: metav1.NewTime(tick), Error: "connection refused"})
+	}
+	require.Len(t, status.Runs, fv1.TimeTriggerRunHistoryLimit)
+}
+
+func TestMissedTick(t *testing.T) {
+	schedule, err := cron.Parse("0 0 * * * *")
+	require.NoError(t, err)
+	last := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// no tick missed
+	_, ok := missedTick(schedule, last, last.Add(30*time.Minute), time.Hour)
+	require.False(t, ok)
+
+	// the last of the missed ticks is run
+	tick, ok := missedTick(schedule, last, last.Add(3*time.Hour+30*time.Minute), time.Hour)
+	require.True(t, ok)
+	require.Equal(t, last.Add(3*time.Hour), tick)
+
+	// the missed ticks are past the deadline
+	_, ok = missedTick(schedule, last, last.Add(3*time.Hour+30*time.Minute), 10*time.Minute)
+	require.False(t, ok)
+}
+
+func TestCompletionTime(t *testing.T) {
+	at := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	recorder := makeRunRecorder(fv1.TimeTrigger{})
+
+	_, ok := recorder.completionTime(at)
+	require.False(t, ok)
+
+	completion := at.Add(3 * time.Second)
+	addRun(&recorder.status, fv1.TimeTriggerRun{
+		ScheduleTime:   metav1.NewTime(at),
+		CompletionTime: metav1.NewTime(completion),
+		StatusCode:     200,
+	})
+	got, ok := recorder.completionTime(at)
+	require.True(t, ok)
+	require.Equal(t, completion, got)
+
+	// runs of other ticks don't count
+	_, ok = recorder.completionTime(at.Add(time.Minute))
+	require.False(t, ok)
+}
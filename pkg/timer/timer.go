@@ -17,10 +17,21 @@ limitations under the License.
 package timer
 
 import (
+	"context"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
 	"github.com/robfig/cron"
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/cloudevents"
 	"github.com/fission/fission/pkg/crd"
 	"github.com/fission/fission/pkg/publisher"
 	"github.com/fission/fission/pkg/utils"
@@ -37,7 +48,8 @@ type (
 		logger         *zap.Logger
 		triggers       map[string]*timerTriggerWithCron
 		requestChannel chan *timerRequest
-		publisher      *publisher.Publisher
+		fissionClient  *crd.FissionClient
+		invoker        publisher.Invoker
 	}
 
 	timerRequest struct {
@@ -51,15 +63,42 @@ type (
 	timerTriggerWithCron struct {
 		trigger fv1.TimeTrigger
 		cron    *cron.Cron
+		// oneShot fires the run of a one-shot trigger, nil once the
+		// trigger ran.
+		oneShot  *time.Timer
+		job      *timerJob
+		recorder *runRecorder
+		// deleted is true once a finished one-shot trigger is deleted.
+		deleted bool
+	}
+
+	// timerJob invokes the function of a trigger on the ticks of its
+	// cron, honoring the schedule window, jitter and concurrency policy
+	// of the trigger.
+	timerJob struct {
+		timer    *Timer
+		trigger  fv1.TimeTrigger
+		location *time.Location
+		recorder *runRecorder
+
+		// ctx is canceled when the cron is stopped, abandoning the ticks
+		// waiting for their jitter.
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		lock    sync.Mutex
+		nextID  uint64
+		running map[uint64]context.CancelFunc
 	}
 )
 
-func MakeTimer(logger *zap.Logger, publisher publisher.Publisher) *Timer {
+func MakeTimer(logger *zap.Logger, fissionClient *crd.FissionClient, invoker publisher.Invoker) *Timer {
 	timer := &Timer{
 		logger:         logger.Named("timer"),
 		triggers:       make(map[string]*timerTriggerWithCron),
 		requestChannel: make(chan *timerRequest),
-		publisher:      &publisher,
+		fissionClient:  fissionClient,
+		invoker:        invoker,
 	}
 	go timer.svc()
 	return timer
@@ -88,25 +127,31 @@ func (timer *Timer) svc() {
 }
 
 func (timer *Timer) syncCron(triggers []fv1.TimeTrigger) error {
-	// add new triggers or update existing ones
+	// add new triggers or update existing ones, keyed on their UID since
+	// recording their runs in their status changes their resourceVersion
 	triggerMap := make(map[string]bool)
 	for _, t := range triggers {
-		triggerMap[crd.CacheKey(&t.ObjectMeta)] = true
-		if item, ok := timer.triggers[crd.CacheKey(&t.ObjectMeta)]; ok {
-			// update cron if the cron spec changed
-			if item.trigger.Spec.Cron != t.Spec.Cron {
+		triggerMap[crd.CacheKeyUID(&t.ObjectMeta)] = true
+		if item, ok := timer.triggers[crd.CacheKeyUID(&t.ObjectMeta)]; ok {
+			// update cron if the spec changed
+			if !reflect.DeepEqual(item.trigger.Spec, t.Spec) {
 				// if there is an cron running, stop it
-				if item.cron != nil {
-					item.cron.Stop()
-				}
-				item.cron = timer.newCron(t)
+				item.stop()
+				timer.schedule(item, t)
 			}
 
 			item.trigger = t
 		} else {
-			timer.triggers[crd.CacheKey(&t.ObjectMeta)] = &timerTriggerWithCron{
-				trigger: t,
-				cron:    timer.newCron(t),
+			item := &timerTriggerWithCron{
+				trigger:  t,
+				recorder: makeRunRecorder(t),
+			}
+			timer.schedule(item, t)
+			timer.triggers[crd.CacheKeyUID(&t.ObjectMeta)] = item
+			// the ticks of new triggers may have been missed while the
+			// timer wasn't running
+			if item.cron != nil && t.Spec.StartingDeadlineSeconds != nil {
+				go item.job.catchUp()
 			}
 		}
 	}
@@ -115,29 +160,295 @@ func (timer *Timer) syncCron(triggers []fv1.TimeTrigger) error {
 	for k, v := range timer.triggers {
 		if _, found := triggerMap[k]; !found {
 			if v.cron != nil {
-				v.cron.Stop()
 				timer.logger.Info("cron for time trigger stopped", zap.String("trigger", v.trigger.ObjectMeta.Name))
 			}
+			v.stop()
 			delete(timer.triggers, k)
 		}
 	}
 
+	// delete the one-shot triggers that ran
+	for _, item := range timer.triggers {
+		timer.collect(item)
+	}
+
 	return nil
 }
 
-func (timer *Timer) newCron(t fv1.TimeTrigger) *cron.Cron {
-	c := cron.New()
-	c.AddFunc(t.Spec.Cron, func() { //nolint: errCheck
-		headers := map[string]string{
-			"X-Fission-Timer-Name": t.ObjectMeta.Name,
+// schedule starts the cron of a trigger, or the timer of a one-shot
+// trigger.
+func (timer *Timer) schedule(item *timerTriggerWithCron, t fv1.TimeTrigger) {
+	item.cron, item.oneShot = nil, nil
+	if t.Spec.At != nil {
+		item.oneShot, item.job = timer.newOneShot(t, item.recorder)
+		return
+	}
+	item.cron, item.job = timer.newCron(t, item.recorder)
+}
+
+func (timer *Timer) newCron(t fv1.TimeTrigger, recorder *runRecorder) (*cron.Cron, *timerJob) {
+	location := time.Local
+	if len(t.Spec.TimeZone) > 0 {
+		loc, err := time.LoadLocation(t.Spec.TimeZone)
+		if err != nil {
+			timer.logger.Error("failed to load time zone of time trigger, using the local time zone",
+				zap.Error(err), zap.String("trigger", t.ObjectMeta.Name), zap.String("time_zone", t.Spec.TimeZone))
+		} else {
+			location = loc
 		}
+	}
 
-		// with the addition of multi-tenancy, the users can create functions in any namespace. however,
-		// the triggers can only be created in the same namespace as the function.
-		// so essentially, function namespace = trigger namespace.
-		(*timer.publisher).Publish("", headers, utils.UrlForFunction(t.Spec.FunctionReference.Name, t.ObjectMeta.Namespace))
-	})
+	job := timer.newJob(t, recorder, location)
+	c := cron.NewWithLocation(location)
+	c.AddJob(t.Spec.Cron, job) //nolint: errCheck
 	c.Start()
 	timer.logger.Info("added new cron for time trigger", zap.String("trigger", t.ObjectMeta.Name))
-	return c
+	return c, job
+}
+
+// newOneShot starts the timer of a one-shot trigger, unless it ran
+// already. It's run right away if its time passed, within its starting
+// deadline if any.
+func (timer *Timer) newOneShot(t fv1.TimeTrigger, recorder *runRecorder) (*time.Timer, *timerJob) {
+	job := timer.newJob(t, recorder, time.Local)
+	at := t.Spec.At.Time
+	if _, ran := recorder.completionTime(at); ran {
+		return nil, job
+	}
+
+	delay := time.Until(at)
+	if delay < 0 && t.Spec.StartingDeadlineSeconds != nil &&
+		-delay > time.Duration(*t.Spec.StartingDeadlineSeconds)*time.Second {
+		timer.logger.Warn("one-shot time trigger missed its starting deadline",
+			zap.String("trigger", t.ObjectMeta.Name), zap.Time("at", at))
+		go timer.record(recorder, t, fv1.TimeTriggerRun{
+			ScheduleTime:   metav1.NewTime(at),
+			CompletionTime: metav1.Now(),
+			Error:          "missed the starting deadline",
+		})
+		return nil, job
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	timer.logger.Info("added new one-shot time trigger", zap.String("trigger", t.ObjectMeta.Name), zap.Time("at", at))
+	return time.AfterFunc(delay, func() { job.run(at) }), job
+}
+
+func (timer *Timer) newJob(t fv1.TimeTrigger, recorder *runRecorder, location *time.Location) *timerJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &timerJob{
+		timer:    timer,
+		trigger:  t,
+		location: location,
+		recorder: recorder,
+		ctx:      ctx,
+		cancel:   cancel,
+		running:  make(map[uint64]context.CancelFunc),
+	}
+}
+
+// collect deletes a one-shot trigger once it ran and its time to live
+// expired.
+func (timer *Timer) collect(item *timerTriggerWithCron) {
+	t := item.trigger
+	if t.Spec.At == nil || item.deleted {
+		return
+	}
+	completed, ran := item.recorder.completionTime(t.Spec.At.Time)
+	if !ran {
+		return
+	}
+	ttl := int64(fv1.DefaultTTLSecondsAfterFinished)
+	if t.Spec.TTLSecondsAfterFinished != nil {
+		ttl = *t.Spec.TTLSecondsAfterFinished
+	}
+	if time.Since(completed) < time.Duration(ttl)*time.Second {
+		return
+	}
+
+	err := timer.fissionClient.CoreV1().TimeTriggers(t.ObjectMeta.Namespace).Delete(context.Background(), t.ObjectMeta.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &t.ObjectMeta.UID},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		// deleted on the next sync
+		timer.logger.Warn("failed to delete finished one-shot time trigger", zap.Error(err), zap.String("trigger", t.ObjectMeta.Name))
+		return
+	}
+	item.deleted = true
+	timer.logger.Info("deleted finished one-shot time trigger", zap.String("trigger", t.ObjectMeta.Name))
+}
+
+func (item *timerTriggerWithCron) stop() {
+	if item.cron != nil {
+		item.cron.Stop()
+	}
+	if item.oneShot != nil {
+		item.oneShot.Stop()
+	}
+	if item.job != nil {
+		item.job.cancel()
+	}
+}
+
+// Run handles a tick of the cron of the trigger.
+func (job *timerJob) Run() {
+	// the cron fires on the second of the tick
+	job.run(time.Now().Truncate(time.Second))
+}
+
+// catchUp runs the last tick missed since the last run of the trigger, if
+// it's within the starting deadline of the trigger.
+func (job *timerJob) catchUp() {
+	t := job.trigger
+	schedule, err := cron.Parse(t.Spec.Cron)
+	if err != nil {
+		return
+	}
+	last := job.recorder.lastScheduleTime(t).In(job.location)
+	deadline := time.Duration(*t.Spec.StartingDeadlineSeconds) * time.Second
+	tick, ok := missedTick(schedule, last, time.Now().In(job.location), deadline)
+	if !ok {
+		return
+	}
+	job.timer.logger.Info("catching up on missed tick of time trigger",
+		zap.String("trigger", t.ObjectMeta.Name), zap.Time("tick", tick))
+	job.run(tick)
+}
+
+// run handles a tick of the trigger.
+func (job *timerJob) run(tick time.Time) {
+	t := job.trigger
+	logger := job.timer.logger.With(zap.String("trigger", t.ObjectMeta.Name))
+
+	if t.Spec.StartTime != nil && tick.Before(t.Spec.StartTime.Time) {
+		logger.Debug("skipping tick before the start time of time trigger")
+		return
+	}
+	if t.Spec.EndTime != nil && tick.After(t.Spec.EndTime.Time) {
+		logger.Debug("skipping tick after the end time of time trigger")
+		return
+	}
+
+	if t.Spec.JitterSeconds > 0 {
+		jitter := time.Duration(rand.Int63n(int64(t.Spec.JitterSeconds) * int64(time.Second)))
+		select {
+		case <-job.ctx.Done():
+			return
+		case <-time.After(jitter):
+		}
+	}
+
+	ctx, id, ok := job.start()
+	if !ok {
+		logger.Info("skipping tick of time trigger, the previous invocation is still running")
+		return
+	}
+	defer job.finish(id)
+
+	method, body, headers := makeRequest(t, tick)
+
+	// with the addition of multi-tenancy, the users can create functions in any namespace. however,
+	// the triggers can only be created in the same namespace as the function.
+	// so essentially, function namespace = trigger namespace.
+	url, err := utils.UrlForFunctionReference(t.Spec.FunctionReference, t.ObjectMeta.Namespace)
+	if err != nil {
+		logger.Error("failed to resolve function reference of time trigger", zap.Error(err))
+		job.timer.record(job.recorder, t, fv1.TimeTriggerRun{
+			ScheduleTime:   metav1.NewTime(tick),
+			CompletionTime: metav1.Now(),
+			Error:          err.Error(),
+		})
+		return
+	}
+
+	started := time.Now()
+	statusCode, err := job.timer.invoker.Invoke(ctx, method, string(body), headers, url)
+	run := fv1.TimeTriggerRun{
+		ScheduleTime:         metav1.NewTime(tick),
+		StatusCode:           statusCode,
+		DurationMilliseconds: time.Since(started).Milliseconds(),
+		CompletionTime:       metav1.Now(),
+	}
+	switch {
+	case err != nil:
+		run.Error = err.Error()
+		logger.Error("failed to invoke function of time trigger", zap.Error(err))
+	case statusCode >= 400:
+		logger.Warn("function of time trigger returned failure status code", zap.Int("status_code", statusCode))
+	default:
+		logger.Debug("invoked function of time trigger", zap.Int("status_code", statusCode))
+	}
+	job.timer.record(job.recorder, t, run)
+}
+
+// makeRequest returns the method, body and headers of the invocation of
+// the function of a trigger for a tick.
+func makeRequest(t fv1.TimeTrigger, tick time.Time) (string, []byte, map[string]string) {
+	method := t.Spec.Method
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+
+	headers := make(map[string]string, len(t.Spec.Headers)+2)
+	for k, v := range t.Spec.Headers {
+		// canonical keys for the headers below to override them
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	if len(t.Spec.ContentType) > 0 {
+		headers["Content-Type"] = t.Spec.ContentType
+	}
+	headers["X-Fission-Timer-Name"] = t.ObjectMeta.Name
+
+	body := []byte(t.Spec.Body)
+	if len(t.Spec.CloudEvents) > 0 {
+		var ceHeaders map[string]string
+		body, ceHeaders = cloudevents.Encode(t.Spec.CloudEvents, cloudevents.Event{
+			ID:              uuid.NewV4().String(),
+			Source:          cloudevents.Source("timetriggers", t.ObjectMeta),
+			Type:            cloudevents.TypeTimeTrigger,
+			Time:            tick,
+			DataContentType: t.Spec.ContentType,
+			Data:            body,
+		})
+		for k, v := range ceHeaders {
+			headers[k] = v
+		}
+	}
+	return method, body, headers
+}
+
+// start registers a new invocation as per the concurrency policy of the
+// trigger. It returns false if the invocation must be skipped.
+func (job *timerJob) start() (context.Context, uint64, bool) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	if len(job.running) > 0 {
+		switch job.trigger.Spec.ConcurrencyPolicy {
+		case fv1.ConcurrencyPolicyForbid:
+			return nil, 0, false
+		case fv1.ConcurrencyPolicyReplace:
+			for _, cancel := range job.running {
+				cancel()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job.nextID++
+	job.running[job.nextID] = cancel
+	return ctx, job.nextID, true
+}
+
+// finish unregisters an invocation.
+func (job *timerJob) finish(id uint64) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	if cancel, ok := job.running[id]; ok {
+		cancel()
+		delete(job.running, id)
+	}
 }
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timer
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/generated/clientset/versioned/fake"
+)
+
+// fakeInvoker records the ticks of the invocations of time triggers.
+type fakeInvoker struct {
+	lock  sync.Mutex
+	ticks []string
+}
+
+func (invoker *fakeInvoker) Publish(body string, headers map[string]string, target string) {}
+
+func (invoker *fakeInvoker) Invoke(ctx context.Context, method string, body string, headers map[string]string, target string) (int, error) {
+	invoker.lock.Lock()
+	defer invoker.lock.Unlock()
+	invoker.ticks = append(invoker.ticks, headers["ce-time"])
+	return http.StatusOK, nil
+}
+
+func TestMakeRequest(t *testing.T) {
+	trigger := fv1.TimeTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "default"},
+	}
+	tick := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	method, body, headers := makeRequest(trigger, tick)
+	require.Equal(t, http.MethodPost, method)
+	require.Empty(t, body)
+	require.Equal(t, map[string]string{"X-Fission-Timer-Name": "daily"}, headers)
+
+	trigger.Spec.Method = http.MethodPut
+	trigger.Spec.Body = `{"report":"sales"}`
+	trigger.Spec.ContentType = "application/json"
+	trigger.Spec.Headers = map[string]string{
+		"x-tenant":             "acme",
+		"content-type":         "text/plain",
+		"X-Fission-Timer-Name": "other",
+	}
+	method, body, headers = makeRequest(trigger, tick)
+	require.Equal(t, http.MethodPut, method)
+	require.Equal(t, trigger.Spec.Body, string(body))
+	require.Equal(t, map[string]string{
+		"X-Tenant":             "acme",
+		"Content-Type":         "application/json",
+		"X-Fission-Timer-Name": "daily",
+	}, headers)
+
+	trigger.Spec.CloudEvents = fv1.CloudEventsModeBinary
+	_, body, headers = makeRequest(trigger, tick)
+	require.Equal(t, trigger.Spec.Body, string(body))
+	require.Equal(t, "application/json", headers["Content-Type"])
+	require.Equal(t, "2021-06-01T12:00:00Z", headers["ce-time"])
+	require.Equal(t, "daily", headers["X-Fission-Timer-Name"])
+}
+
+func TestSyncCronStatusUpdate(t *testing.T) {
+	timer := &Timer{
+		logger:   zap.NewNop(),
+		triggers: make(map[string]*timerTriggerWithCron),
+	}
+	trigger := fv1.TimeTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "hourly", Namespace: "default", UID: "uid", ResourceVersion: "1"},
+		Spec:       fv1.TimeTriggerSpec{Cron: "@hourly"},
+	}
+	require.NoError(t, timer.syncCron([]fv1.TimeTrigger{trigger}))
+	item := timer.triggers["uid"]
+	defer item.stop()
+	job := item.job
+
+	// recording a run in the status doesn't restart the trigger
+	trigger.ResourceVersion = "2"
+	trigger.Status.Runs = []fv1.TimeTriggerRun{{StatusCode: http.StatusOK}}
+	require.NoError(t, timer.syncCron([]fv1.TimeTrigger{trigger}))
+	require.Len(t, timer.triggers, 1)
+	require.Same(t, item, timer.triggers["uid"])
+	require.Same(t, job, item.job)
+
+	// changing the spec does, keeping the recorder of the runs
+	recorder := item.recorder
+	trigger.ResourceVersion = "3"
+	trigger.Spec.Cron = "@daily"
+	require.NoError(t, timer.syncCron([]fv1.TimeTrigger{trigger}))
+	require.Same(t, item, timer.triggers["uid"])
+	require.NotSame(t, job, item.job)
+	require.Same(t, recorder, item.recorder)
+}
+
+func TestJobStart(t *testing.T) {
+	timer := &Timer{logger: zap.NewNop()}
+	newJob := func(policy fv1.ConcurrencyPolicy) *timerJob {
+		trigger := fv1.TimeTrigger{Spec: fv1.TimeTriggerSpec{ConcurrencyPolicy: policy}}
+		return timer.newJob(trigger, makeRunRecorder(trigger), time.UTC)
+	}
+
+	// Allow runs the invocations side by side
+	job := newJob(fv1.ConcurrencyPolicyAllow)
+	first, firstID, ok := job.start()
+	require.True(t, ok)
+	second, secondID, ok := job.start()
+	require.True(t, ok)
+	require.NotEqual(t, firstID, secondID)
+	require.Len(t, job.running, 2)
+	require.NoError(t, first.Err())
+	require.NoError(t, second.Err())
+	job.finish(firstID)
+	require.Error(t, first.Err())
+	require.NoError(t, second.Err())
+	job.finish(secondID)
+	require.Empty(t, job.running)
+
+	// Forbid skips the invocations while one is running
+	job = newJob(fv1.ConcurrencyPolicyForbid)
+	first, firstID, ok = job.start()
+	require.True(t, ok)
+	_, _, ok = job.start()
+	require.False(t, ok)
+	require.NoError(t, first.Err())
+	job.finish(firstID)
+	_, secondID, ok = job.start()
+	require.True(t, ok)
+	job.finish(secondID)
+	require.Empty(t, job.running)
+
+	// Replace cancels the running invocations
+	job = newJob(fv1.ConcurrencyPolicyReplace)
+	first, firstID, ok = job.start()
+	require.True(t, ok)
+	second, secondID, ok = job.start()
+	require.True(t, ok)
+	require.Error(t, first.Err())
+	require.NoError(t, second.Err())
+	job.finish(firstID)
+	require.Len(t, job.running, 1)
+	job.finish(secondID)
+	require.Empty(t, job.running)
+}
+
+func TestJobRunWindow(t *testing.T) {
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	trigger := fv1.TimeTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "hourly", Namespace: "default", UID: "uid"},
+		Spec: fv1.TimeTriggerSpec{
+			Cron:              "@every 30m",
+			CloudEvents:       fv1.CloudEventsModeBinary,
+			FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "report"},
+			StartTime:         &metav1.Time{Time: start},
+			EndTime:           &metav1.Time{Time: end},
+		},
+	}
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(&trigger)}
+	invoker := &fakeInvoker{}
+	timer := &Timer{
+		logger:        zap.NewNop(),
+		fissionClient: fissionClient,
+		invoker:       invoker,
+	}
+	recorder := makeRunRecorder(trigger)
+	job := timer.newJob(trigger, recorder, time.UTC)
+	defer job.cancel()
+
+	for _, tick := range []time.Time{
+		start.Add(-time.Second),
+		start,
+		start.Add(30 * time.Minute),
+		end,
+		end.Add(time.Second),
+	} {
+		job.run(tick)
+	}
+
+	// the ticks within the window, bounds included, are run and recorded
+	require.Equal(t, []string{
+		"2021-06-01T12:00:00Z",
+		"2021-06-01T12:30:00Z",
+		"2021-06-01T13:00:00Z",
+	}, invoker.ticks)
+	updated, err := fissionClient.CoreV1().TimeTriggers("default").Get(context.Background(), "hourly", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, updated.Status.Runs, 3)
+	require.Equal(t, end, updated.Status.LastScheduleTime.Time.UTC())
+}
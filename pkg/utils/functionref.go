@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// FunctionWeightDistribution is a function of a function-weights
+// reference, with the sum of its weight and the weights of the
+// functions before it.
+type FunctionWeightDistribution struct {
+	Name      string
+	Weight    int
+	SumPrefix int
+}
+
+// MakeFunctionWeightDistribution returns the weight distribution of
+// functions, in the order of their names.
+func MakeFunctionWeightDistribution(functionWeights map[string]int) []FunctionWeightDistribution {
+	names := make([]string, 0, len(functionWeights))
+	for name := range functionWeights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]FunctionWeightDistribution, 0, len(names))
+	sumPrefix := 0
+	for _, name := range names {
+		sumPrefix += functionWeights[name]
+		list = append(list, FunctionWeightDistribution{
+			Name:      name,
+			Weight:    functionWeights[name],
+			SumPrefix: sumPrefix,
+		})
+	}
+	return list
+}
+
+// PickWeightedFunction picks a function of a weight distribution at
+// random, in proportion to its weight. It returns an empty name if the
+// weights sum up to 0.
+func PickWeightedFunction(list []FunctionWeightDistribution) string {
+	if len(list) == 0 || list[len(list)-1].SumPrefix <= 0 {
+		return ""
+	}
+	randomNumber := rand.Intn(list[len(list)-1].SumPrefix)
+	return list[ceilFunctionWeight(randomNumber, list)].Name
+}
+
+// ceilFunctionWeight returns the index of the first function whose
+// sum prefix is greater than randomNumber.
+func ceilFunctionWeight(randomNumber int, list []FunctionWeightDistribution) int {
+	return sort.Search(len(list), func(i int) bool {
+		return list[i].SumPrefix > randomNumber
+	})
+}
+
+// FunctionNameForReference returns the name of the function to invoke
+// for a function reference. The function of a function-weights
+// reference is picked by weight on every call.
+func FunctionNameForReference(ref fv1.FunctionReference) (string, error) {
+	switch ref.Type {
+	case fv1.FunctionReferenceTypeFunctionName:
+		return ref.Name, nil
+	case fv1.FunctionReferenceTypeFunctionWeights:
+		name := PickWeightedFunction(MakeFunctionWeightDistribution(ref.FunctionWeights))
+		if len(name) == 0 {
+			return "", errors.New("function weights of function reference sum up to 0")
+		}
+		return name, nil
+	default:
+		return "", errors.Errorf("unsupported function reference type %q", ref.Type)
+	}
+}
+
+// FunctionNamesForReference returns the names of all the functions a
+// function reference may invoke.
+func FunctionNamesForReference(ref fv1.FunctionReference) []string {
+	if ref.Type != fv1.FunctionReferenceTypeFunctionWeights {
+		return []string{ref.Name}
+	}
+	var names []string
+	for _, fn := range MakeFunctionWeightDistribution(ref.FunctionWeights) {
+		if fn.Weight > 0 {
+			names = append(names, fn.Name)
+		}
+	}
+	return names
+}
+
+// UrlForFunctionReference returns the router path of the function to
+// invoke for a function reference of a trigger in namespace, see
+// FunctionNameForReference.
+func UrlForFunctionReference(ref fv1.FunctionReference, namespace string) (string, error) {
+	name, err := FunctionNameForReference(ref)
+	if err != nil {
+		return "", err
+	}
+	return UrlForFunction(name, namespace), nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestMakeFunctionWeightDistribution(t *testing.T) {
+	list := MakeFunctionWeightDistribution(map[string]int{"b": 30, "a": 70, "c": 0})
+	expected := []FunctionWeightDistribution{
+		{Name: "a", Weight: 70, SumPrefix: 70},
+		{Name: "b", Weight: 30, SumPrefix: 100},
+		{Name: "c", Weight: 0, SumPrefix: 100},
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Fatalf("expected %v, got %v", expected, list)
+	}
+
+	for randomNumber, index := range map[int]int{0: 0, 69: 0, 70: 1, 99: 1} {
+		if i := ceilFunctionWeight(randomNumber, list); i != index {
+			t.Errorf("expected function %v for %v, got %v", index, randomNumber, i)
+		}
+	}
+}
+
+func TestPickWeightedFunction(t *testing.T) {
+	list := MakeFunctionWeightDistribution(map[string]int{"old": 1, "new": 3, "none": 0})
+	picked := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		picked[PickWeightedFunction(list)]++
+	}
+	if picked["none"] != 0 {
+		t.Errorf("function with weight 0 picked %v times", picked["none"])
+	}
+	if picked["new"] < 2700 || picked["new"] > 3300 {
+		t.Errorf("expected function to be picked about 3000 times, got %v", picked["new"])
+	}
+
+	if name := PickWeightedFunction(MakeFunctionWeightDistribution(map[string]int{"a": 0})); name != "" {
+		t.Errorf("expected no function, got %v", name)
+	}
+}
+
+func TestUrlForFunctionReference(t *testing.T) {
+	url, err := UrlForFunctionReference(fv1.FunctionReference{
+		Type: fv1.FunctionReferenceTypeFunctionName,
+		Name: "hello",
+	}, "default")
+	if err != nil || url != "/fission-function/hello" {
+		t.Errorf("unexpected url %v, error %v", url, err)
+	}
+
+	url, err = UrlForFunctionReference(fv1.FunctionReference{
+		Type:            fv1.FunctionReferenceTypeFunctionWeights,
+		FunctionWeights: map[string]int{"v1": 0, "v2": 100},
+	}, "ns")
+	if err != nil || url != "/fission-function/ns/v2" {
+		t.Errorf("unexpected url %v, error %v", url, err)
+	}
+
+	_, err = UrlForFunctionReference(fv1.FunctionReference{
+		Type:            fv1.FunctionReferenceTypeFunctionWeights,
+		FunctionWeights: map[string]int{},
+	}, "default")
+	if err == nil {
+		t.Error("expected error for function weights summing up to 0")
+	}
+
+	_, err = UrlForFunctionReference(fv1.FunctionReference{Type: "unknown"}, "default")
+	if err == nil {
+		t.Error("expected error for unsupported function reference type")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leader runs a component on a single replica at a time, using
+// the leader election of client-go with a Kubernetes Lease lock. The
+// other replicas stand by and take over once the lease of the leader
+// expires.
+package leader
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// DefaultLeaseDuration is how long standbys wait since the last renewal
+	// of the lease before taking over.
+	DefaultLeaseDuration = 15 * time.Second
+
+	// EnvEnabled enables leader election, and EnvLeaseDuration sets the
+	// lease duration, e.g. 15s.
+	EnvEnabled       = "LEADER_ELECTION_ENABLED"
+	EnvLeaseDuration = "LEADER_ELECTION_LEASE_DURATION"
+)
+
+var isLeader = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "fission_leader_election_is_leader",
+		Help: "A binary value indicating whether the replica is the leader of the component",
+	},
+	[]string{"component"},
+)
+
+func init() {
+	prometheus.MustRegister(isLeader)
+}
+
+type (
+	// Config is the leader election config of a component.
+	Config struct {
+		// Enabled is false to run the component right away, on every
+		// replica.
+		Enabled bool
+		// Namespace and Name are those of the lease.
+		Namespace string
+		Name      string
+		// Identity is the unique name of the replica.
+		Identity string
+
+		LeaseDuration time.Duration
+		RenewDeadline time.Duration
+		RetryPeriod   time.Duration
+	}
+)
+
+// ConfigFromEnv returns the leader election config of a component, read
+// from the environment. The lease is named after the component, in the
+// namespace of the pod, and the identity of the replica is its pod name.
+func ConfigFromEnv(component string) (Config, error) {
+	config := Config{
+		Namespace:     os.Getenv("POD_NAMESPACE"),
+		Name:          "fission-" + component,
+		Identity:      os.Getenv("POD_NAME"),
+		LeaseDuration: DefaultLeaseDuration,
+	}
+
+	if v := os.Getenv(EnvEnabled); len(v) > 0 {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return config, errors.Wrapf(err, "error parsing %v", EnvEnabled)
+		}
+		config.Enabled = enabled
+	}
+	if !config.Enabled {
+		return config, nil
+	}
+
+	if v := os.Getenv(EnvLeaseDuration); len(v) > 0 {
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return config, errors.Wrapf(err, "error parsing %v", EnvLeaseDuration)
+		}
+		if duration < 2*time.Second {
+			return config, errors.Errorf("%v must be at least 2s", EnvLeaseDuration)
+		}
+		config.LeaseDuration = duration
+	}
+	// renew well before the lease expires, as kube-controller-manager does
+	config.RenewDeadline = config.LeaseDuration * 2 / 3
+	config.RetryPeriod = config.LeaseDuration / 7
+
+	if len(config.Namespace) == 0 {
+		return config, errors.New("POD_NAMESPACE must be set for leader election")
+	}
+	if len(config.Identity) == 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return config, errors.Wrap(err, "error getting the identity of the replica")
+		}
+		config.Identity = hostname
+	}
+	return config, nil
+}
+
+// Run runs start once the replica is elected leader of the component,
+// or right away if leader election isn't enabled. It returns once the
+// replica stops leading, the component must then exit since it can't
+// stop what start started.
+func Run(ctx context.Context, logger *zap.Logger, kubeClient kubernetes.Interface, component string, config Config, start func(ctx context.Context)) error {
+	logger = logger.Named("leader_election").With(zap.String("component", component))
+
+	if !config.Enabled {
+		isLeader.WithLabelValues(component).Set(1)
+		start(ctx)
+		<-ctx.Done()
+		return nil
+	}
+	isLeader.WithLabelValues(component).Set(0)
+
+	lock := &resourcelock.LeaseLock{
+		Client: kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: config.Identity,
+		},
+	}
+	lock.LeaseMeta.Namespace = config.Namespace
+	lock.LeaseMeta.Name = config.Name
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   config.LeaseDuration,
+		RenewDeadline:   config.RenewDeadline,
+		RetryPeriod:     config.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            component,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logger.Info("started leading", zap.String("identity", config.Identity))
+				isLeader.WithLabelValues(component).Set(1)
+				start(ctx)
+			},
+			OnStoppedLeading: func() {
+				logger.Info("stopped leading", zap.String("identity", config.Identity))
+				isLeader.WithLabelValues(component).Set(0)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != config.Identity {
+					logger.Info("new leader elected", zap.String("leader", identity))
+				}
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating leader elector")
+	}
+	elector.Run(ctx)
+	return nil
+}
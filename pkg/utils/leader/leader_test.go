@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		old, ok := os.LookupEnv(k)
+		require.NoError(t, os.Setenv(k, v))
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old) //nolint: errcheck
+			} else {
+				os.Unsetenv(k) //nolint: errcheck
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	setenv(t, map[string]string{EnvEnabled: "", EnvLeaseDuration: "", "POD_NAMESPACE": "fission", "POD_NAME": "timer-1"})
+	config, err := ConfigFromEnv("timer")
+	require.NoError(t, err)
+	require.False(t, config.Enabled)
+
+	setenv(t, map[string]string{EnvEnabled: "true"})
+	config, err = ConfigFromEnv("timer")
+	require.NoError(t, err)
+	require.True(t, config.Enabled)
+	require.Equal(t, "fission", config.Namespace)
+	require.Equal(t, "fission-timer", config.Name)
+	require.Equal(t, "timer-1", config.Identity)
+	require.Equal(t, DefaultLeaseDuration, config.LeaseDuration)
+	require.Less(t, int64(config.RenewDeadline), int64(config.LeaseDuration))
+	require.Less(t, int64(config.RetryPeriod), int64(config.RenewDeadline))
+
+	setenv(t, map[string]string{EnvLeaseDuration: "30s"})
+	config, err = ConfigFromEnv("timer")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, config.LeaseDuration)
+
+	setenv(t, map[string]string{EnvLeaseDuration: "1s"})
+	_, err = ConfigFromEnv("timer")
+	require.Error(t, err)
+
+	setenv(t, map[string]string{EnvLeaseDuration: "", "POD_NAMESPACE": ""})
+	_, err = ConfigFromEnv("timer")
+	require.Error(t, err)
+}
@@ -0,0 +1,439 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oci pulls package archives stored as single layer artifacts
+// in an OCI distribution registry, e.g. pushed with
+// "oras push registry/repo:tag archive.zip".
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Scheme is the URL scheme of archive references stored in a registry.
+	Scheme = "oci://"
+
+	MediaTypeImageManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	digestAlgorithm = "sha256"
+)
+
+var (
+	digestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	tagRegex    = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	repoRegex   = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+)
+
+type (
+	// Reference is a parsed oci://registry/repository[:tag][@digest] reference.
+	Reference struct {
+		Registry   string
+		Repository string
+		Tag        string
+		Digest     string
+	}
+
+	// Credentials returns the username and password for a registry host.
+	// An empty username means anonymous access.
+	Credentials func(registry string) (username, password string)
+
+	// Client pulls archives from OCI registries.
+	Client struct {
+		httpClient  *http.Client
+		credentials Credentials
+		plainHTTP   map[string]bool
+	}
+
+	// Descriptor describes the content of a manifest or layer.
+	Descriptor struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Size      int64  `json:"size"`
+	}
+
+	manifest struct {
+		MediaType string       `json:"mediaType,omitempty"`
+		Layers    []Descriptor `json:"layers"`
+	}
+
+	dockerConfig struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+)
+
+// IsReference returns true if s uses the oci:// scheme.
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, Scheme)
+}
+
+// ParseReference parses an oci://registry/repository[:tag][@digest] reference.
+// At least one of tag and digest must be present.
+func ParseReference(s string) (*Reference, error) {
+	if !IsReference(s) {
+		return nil, errors.Errorf("reference %q must start with %q", s, Scheme)
+	}
+	rest := strings.TrimPrefix(s, Scheme)
+
+	ref := &Reference{}
+	if i := strings.Index(rest, "@"); i >= 0 {
+		ref.Digest = rest[i+1:]
+		rest = rest[:i]
+		if !digestRegex.MatchString(ref.Digest) {
+			return nil, errors.Errorf("reference %q has an invalid digest, only sha256 digests are supported", s)
+		}
+	}
+
+	i := strings.Index(rest, "/")
+	if i <= 0 {
+		return nil, errors.Errorf("reference %q has no registry host", s)
+	}
+	ref.Registry = rest[:i]
+	rest = rest[i+1:]
+
+	// the tag separator is the last colon after the last slash,
+	// the registry host may contain a port.
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i:], "/") {
+		ref.Tag = rest[i+1:]
+		rest = rest[:i]
+		if !tagRegex.MatchString(ref.Tag) {
+			return nil, errors.Errorf("reference %q has an invalid tag", s)
+		}
+	}
+	ref.Repository = rest
+	if !repoRegex.MatchString(ref.Repository) {
+		return nil, errors.Errorf("reference %q has an invalid repository name", s)
+	}
+
+	if len(ref.Tag) == 0 && len(ref.Digest) == 0 {
+		return nil, errors.Errorf("reference %q needs a tag or a digest", s)
+	}
+	return ref, nil
+}
+
+// String returns the reference in oci:// form.
+func (ref Reference) String() string {
+	s := Scheme + ref.Registry + "/" + ref.Repository
+	if len(ref.Tag) > 0 {
+		s += ":" + ref.Tag
+	}
+	if len(ref.Digest) > 0 {
+		s += "@" + ref.Digest
+	}
+	return s
+}
+
+// Pinned returns a copy of the reference pinned to the manifest digest.
+func (ref Reference) Pinned(digest string) Reference {
+	ref.Digest = digest
+	return ref
+}
+
+// MakeClient returns a Client. Registries in plainHTTP are accessed without
+// TLS in addition to localhost, which is always accessed that way so that a
+// local development registry works out of the box.
+func MakeClient(httpClient *http.Client, credentials Credentials, plainHTTP ...string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	c := &Client{
+		httpClient:  httpClient,
+		credentials: credentials,
+		plainHTTP:   make(map[string]bool, len(plainHTTP)),
+	}
+	for _, r := range plainHTTP {
+		if r = strings.TrimSpace(r); len(r) > 0 {
+			c.plainHTTP[r] = true
+		}
+	}
+	return c
+}
+
+// CredentialsFromDockerConfig returns Credentials read from the content of
+// a kubernetes.io/dockerconfigjson secret, i.e. an image pull secret.
+func CredentialsFromDockerConfig(data []byte) (Credentials, error) {
+	config := dockerConfig{}
+	err := json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing docker config")
+	}
+
+	type userPass struct{ username, password string }
+	auths := make(map[string]userPass, len(config.Auths))
+	for host, auth := range config.Auths {
+		up := userPass{auth.Username, auth.Password}
+		if len(auth.Auth) > 0 {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error decoding auth of registry %q", host)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, errors.Errorf("auth of registry %q is not in username:password form", host)
+			}
+			up = userPass{parts[0], parts[1]}
+		}
+		// entries may be full URLs such as https://index.docker.io/v1/
+		if u, err := url.Parse(host); err == nil && len(u.Host) > 0 {
+			host = u.Host
+		}
+		auths[host] = up
+	}
+
+	return func(registry string) (string, string) {
+		up := auths[registry]
+		return up.username, up.password
+	}, nil
+}
+
+func isLocalhost(registry string) bool {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (c *Client) baseURL(ref *Reference) string {
+	scheme := "https"
+	if c.plainHTTP[ref.Registry] || isLocalhost(ref.Registry) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", scheme, ref.Registry, ref.Repository)
+}
+
+// Resolve returns the manifest digest of the reference and the descriptor
+// of the archive layer. If the reference is pinned to a digest, the manifest
+// is verified against it.
+func (c *Client) Resolve(ctx context.Context, ref *Reference) (string, *Descriptor, error) {
+	tagOrDigest := ref.Digest
+	if len(tagOrDigest) == 0 {
+		tagOrDigest = ref.Tag
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL(ref)+"/manifests/"+tagOrDigest, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Accept", MediaTypeImageManifest+", "+MediaTypeDockerManifest)
+	resp, err := c.do(ref, req)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "error getting manifest of %s", ref)
+	}
+	defer resp.Body.Close()
+
+	// manifests are small, 4MiB is the limit most registries enforce
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "error reading manifest of %s", ref)
+	}
+	sum := sha256.Sum256(body)
+	digest := digestAlgorithm + ":" + hex.EncodeToString(sum[:])
+	if len(ref.Digest) > 0 && digest != ref.Digest {
+		return "", nil, errors.Errorf("manifest digest %s of %s does not match the pinned digest", digest, ref)
+	}
+
+	m := manifest{}
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "error parsing manifest of %s", ref)
+	}
+	if len(m.Layers) != 1 {
+		return "", nil, errors.Errorf("manifest of %s has %d layers, a package archive must be stored as a single layer", ref, len(m.Layers))
+	}
+	layer := m.Layers[0]
+	if !digestRegex.MatchString(layer.Digest) {
+		return "", nil, errors.Errorf("archive layer of %s has unsupported digest %q", ref, layer.Digest)
+	}
+	return digest, &layer, nil
+}
+
+// Pull downloads the archive layer of the reference to localPath, verifying
+// the manifest against the pinned digest and the layer against the digest
+// in the manifest. It returns the archive layer descriptor, whose digest is
+// the sha256 checksum of the archive.
+func (c *Client) Pull(ctx context.Context, ref *Reference, localPath string) (*Descriptor, error) {
+	_, layer, err := c.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL(ref)+"/blobs/"+layer.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ref, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting archive layer of %s", ref)
+	}
+	defer resp.Body.Close()
+
+	w, err := os.OpenFile(localPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(w, hasher), resp.Body)
+	if err != nil {
+		os.Remove(localPath)
+		return nil, errors.Wrapf(err, "error downloading archive layer of %s", ref)
+	}
+	if digest := digestAlgorithm + ":" + hex.EncodeToString(hasher.Sum(nil)); digest != layer.Digest {
+		os.Remove(localPath)
+		return nil, errors.Errorf("archive layer digest %s of %s does not match the manifest", digest, ref)
+	}
+
+	err = w.Sync()
+	if err != nil {
+		return nil, err
+	}
+	return layer, nil
+}
+
+// do sends the request, authenticating against the registry if it
+// responds with a challenge, and returns the response if it succeeded.
+func (c *Client) do(ref *Reference, req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		err = c.authorize(ref, req, challenge)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("registry responded with status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// authorize sets the Authorization header of req according to the
+// challenge, fetching a bearer token from the token service if required.
+func (c *Client) authorize(ref *Reference, req *http.Request, challenge string) error {
+	var username, password string
+	if c.credentials != nil {
+		username, password = c.credentials(ref.Registry)
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if len(username) == 0 {
+			return errors.Errorf("registry %s requires credentials", ref.Registry)
+		}
+		req.SetBasicAuth(username, password)
+		return nil
+	case "bearer":
+	default:
+		return errors.Errorf("registry %s uses unsupported authentication scheme %q", ref.Registry, scheme)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || len(realm.Host) == 0 {
+		return errors.Errorf("registry %s returned an invalid token realm %q", ref.Registry, params["realm"])
+	}
+	query := realm.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+	realm.RawQuery = query.Encode()
+
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if len(username) > 0 {
+		tokenReq.SetBasicAuth(username, password)
+	}
+	resp, err := c.httpClient.Do(tokenReq)
+	if err != nil {
+		return errors.Wrapf(err, "error getting token for registry %s", ref.Registry)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("token service of registry %s responded with status %s", ref.Registry, resp.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing token of registry %s", ref.Registry)
+	}
+	if len(token.Token) == 0 {
+		token.Token = token.AccessToken
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	return nil
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry".
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	for _, param := range strings.Split(parts[1], ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return parts[0], params
+}
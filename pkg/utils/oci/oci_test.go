@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// testRegistry serves a single artifact the way a distribution registry
+// does, requiring a bearer token obtained with the given credentials.
+func testRegistry(t *testing.T, archive []byte) (*httptest.Server, string, string) {
+	layerDigest := sha256Digest(archive)
+	m, err := json.Marshal(manifest{
+		MediaType: MediaTypeImageManifest,
+		Layers: []Descriptor{
+			{MediaType: "application/zip", Digest: layerDigest, Size: int64(len(archive))},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDigest := sha256Digest(m)
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			if user != "user" || pass != "pass" || r.URL.Query().Get("scope") != "repository:fission/hello:pull" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+ts.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/fission/hello/manifests/v1", "/v2/fission/hello/manifests/" + manifestDigest:
+			w.Header().Set("Content-Type", MediaTypeImageManifest)
+			w.Write(m)
+		case "/v2/fission/hello/blobs/" + layerDigest:
+			w.Write(archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts, manifestDigest, layerDigest
+}
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{"oci://ghcr.io/fission/hello:v1", Reference{Registry: "ghcr.io", Repository: "fission/hello", Tag: "v1"}, false},
+		{"oci://localhost:5000/hello@" + digest, Reference{Registry: "localhost:5000", Repository: "hello", Digest: digest}, false},
+		{"oci://localhost:5000/a/b:v1@" + digest, Reference{Registry: "localhost:5000", Repository: "a/b", Tag: "v1", Digest: digest}, false},
+		{"oci://localhost:5000/hello", Reference{}, true},
+		{"oci://hello:v1", Reference{}, true},
+		{"oci://ghcr.io/Hello:v1", Reference{}, true},
+		{"oci://ghcr.io/hello@sha256:abc", Reference{}, true},
+		{"https://ghcr.io/hello:v1", Reference{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			ref, err := ParseReference(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if *ref != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", *ref, tt.want)
+			}
+			if ref.String() != tt.ref {
+				t.Errorf("String() = %v, want %v", ref.String(), tt.ref)
+			}
+		})
+	}
+}
+
+func TestPull(t *testing.T) {
+	archive := []byte("deploy archive")
+	ts, manifestDigest, layerDigest := testRegistry(t, archive)
+	defer ts.Close()
+
+	registry := strings.TrimPrefix(ts.URL, "http://")
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	credentials, err := CredentialsFromDockerConfig([]byte(`{"auths":{"` + registry + `":{"auth":"` + auth + `"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := MakeClient(ts.Client(), credentials)
+
+	dir, err := ioutil.TempDir("", "oci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		ref     Reference
+		client  *Client
+		wantErr bool
+	}{
+		{"tag", Reference{Registry: registry, Repository: "fission/hello", Tag: "v1"}, client, false},
+		{"pinned", Reference{Registry: registry, Repository: "fission/hello", Tag: "v1", Digest: manifestDigest}, client, false},
+		{"digest mismatch", Reference{Registry: registry, Repository: "fission/hello", Tag: "v1", Digest: layerDigest}, client, true},
+		{"unknown tag", Reference{Registry: registry, Repository: "fission/hello", Tag: "v2"}, client, true},
+		{"no credentials", Reference{Registry: registry, Repository: "fission/hello", Tag: "v1"}, MakeClient(ts.Client(), nil), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-"))
+			layer, err := tt.client.Pull(context.Background(), &tt.ref, path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Pull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if layer.Digest != layerDigest {
+				t.Errorf("Pull() layer digest = %v, want %v", layer.Digest, layerDigest)
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(archive) {
+				t.Errorf("Pull() wrote %q, want %q", data, archive)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// ParseEd25519PrivateKey parses a PEM encoded PKCS #8 ed25519 private key,
+// e.g. one generated with "openssl genpkey -algorithm ed25519".
+func ParseEd25519PrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing private key")
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key type %T, only ed25519 is supported", key)
+	}
+	return edKey, nil
+}
+
+// ParseEd25519PublicKey parses a PEM encoded PKIX ed25519 public key,
+// e.g. one generated with "openssl pkey -pubout".
+func ParseEd25519PublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing public key")
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported public key type %T, only ed25519 is supported", key)
+	}
+	return edKey, nil
+}
+
+// ParseTrustedKeys parses the data of a trusted keys secret into
+// a map from key ID to public key.
+func ParseTrustedKeys(data map[string][]byte) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey, len(data))
+	for id, val := range data {
+		key, err := ParseEd25519PublicKey(val)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing trusted key %q", id)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// ParseSigningKey parses the data of a signing key secret, which has a
+// single entry, into the key ID and private key.
+func ParseSigningKey(data map[string][]byte) (string, ed25519.PrivateKey, error) {
+	if len(data) != 1 {
+		return "", nil, errors.Errorf("signing key secret must have a single entry, found %d", len(data))
+	}
+	for id, val := range data {
+		key, err := ParseEd25519PrivateKey(val)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "error parsing signing key %q", id)
+		}
+		return id, key, nil
+	}
+	return "", nil, nil
+}
+
+func checksumDigest(checksum *fv1.Checksum) ([]byte, error) {
+	if checksum == nil || checksum.Type != fv1.ChecksumTypeSHA256 {
+		return nil, errors.New("a sha256 checksum is required for signing")
+	}
+	digest, err := hex.DecodeString(checksum.Sum)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding checksum")
+	}
+	return digest, nil
+}
+
+// SignChecksum signs the archive checksum with the given ed25519 private key.
+func SignChecksum(key ed25519.PrivateKey, keyID string, checksum *fv1.Checksum) (*fv1.ArchiveSignature, error) {
+	digest, err := checksumDigest(checksum)
+	if err != nil {
+		return nil, err
+	}
+	return &fv1.ArchiveSignature{
+		Type:  fv1.SignatureTypeEd25519,
+		KeyID: keyID,
+		Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest)),
+	}, nil
+}
+
+// VerifyChecksumSignature verifies that the signature of the given checksum
+// was made by one of the trusted keys. If the signature names a key ID only
+// that key is used.
+func VerifyChecksumSignature(keys map[string]ed25519.PublicKey, checksum *fv1.Checksum, signature *fv1.ArchiveSignature) error {
+	if signature == nil || len(signature.Sig) == 0 {
+		return errors.New("archive is not signed")
+	}
+	if signature.Type != fv1.SignatureTypeEd25519 {
+		return errors.Errorf("unsupported signature type %q", signature.Type)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+	if err != nil {
+		return errors.Wrap(err, "error decoding signature")
+	}
+	digest, err := checksumDigest(checksum)
+	if err != nil {
+		return err
+	}
+
+	if len(signature.KeyID) > 0 {
+		key, ok := keys[signature.KeyID]
+		if !ok {
+			return errors.Errorf("signing key %q is not trusted", signature.KeyID)
+		}
+		if !ed25519.Verify(key, digest, sig) {
+			return errors.Errorf("signature does not match key %q", signature.KeyID)
+		}
+		return nil
+	}
+
+	for _, key := range keys {
+		if ed25519.Verify(key, digest, sig) {
+			return nil
+		}
+	}
+	return errors.New("signature does not match any trusted key")
+}
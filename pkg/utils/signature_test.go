@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestSignChecksum(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := ParseTrustedKeys(map[string][]byte{
+		"release": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checksum, err := GetChecksum(bytes.NewReader([]byte("deploy archive")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered, err := GetChecksum(bytes.NewReader([]byte("tampered archive")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := SignChecksum(priv, "", checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigWithKeyID, err := SignChecksum(priv, "release", checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigWithUnknownKeyID, err := SignChecksum(priv, "dev", checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     map[string]ed25519.PublicKey
+		checksum *fv1.Checksum
+		sig      *fv1.ArchiveSignature
+		wantErr  bool
+	}{
+		{"valid signature", trusted, checksum, sig, false},
+		{"valid signature with key id", trusted, checksum, sigWithKeyID, false},
+		{"unknown key id", trusted, checksum, sigWithUnknownKeyID, true},
+		{"tampered archive", trusted, tampered, sig, true},
+		{"untrusted key", map[string]ed25519.PublicKey{"other": otherPub}, checksum, sig, true},
+		{"unsigned archive", trusted, checksum, &fv1.ArchiveSignature{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyChecksumSignature(tt.keys, tt.checksum, tt.sig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyChecksumSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	id, key, err := ParseSigningKey(map[string][]byte{"release": keyPEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "release" || !pub.Equal(key.Public()) {
+		t.Errorf("ParseSigningKey() = %q, %v, want release key", id, key.Public())
+	}
+
+	for name, data := range map[string]map[string][]byte{
+		"no key":       {},
+		"several keys": {"release": keyPEM, "dev": keyPEM},
+		"invalid key":  {"release": []byte("key")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := ParseSigningKey(data)
+			if err == nil {
+				t.Error("ParseSigningKey() succeeded, want error")
+			}
+		})
+	}
+}